@@ -2,18 +2,29 @@ package main
 
 import (
 	"context"
-	"database/sql"
+	"crypto/tls"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"idempot/internal/address"
+	"idempot/internal/auth"
 	"idempot/internal/config"
+	"idempot/internal/domain"
 	handlerhttp "idempot/internal/handler/http"
+	"idempot/internal/health"
+	"idempot/internal/logging"
+	"idempot/internal/metrics"
+	"idempot/internal/mtls"
 	"idempot/internal/repository/migration"
+	"idempot/internal/risk"
+	"idempot/internal/screening"
 	"idempot/internal/service"
+	"idempot/internal/tracing"
 
 	"idempot/internal/repository/postgresql"
 
@@ -28,7 +39,30 @@ func main() {
 		log.Fatal("failed to load configuration:", err)
 	}
 
-	db, err := sql.Open("postgres", config.DB.DatabaseURL)
+	// Handlers, services and repositories log through the default logger, and
+	// so does the standard log package from here on
+	logger, logLevel, err := logging.New(os.Stdout, logging.Options{
+		Format:  config.Logger.Format,
+		Level:   config.Logger.LoggerLevel,
+		PII:     config.Logger.PII,
+		PIISalt: config.Logger.PIISalt,
+	})
+	if err != nil {
+		log.Fatal("Invalid logger configuration:", err)
+	}
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := tracing.Setup(tracing.Options{
+		Exporter:    config.Tracing.Exporter,
+		File:        config.Tracing.File,
+		ServiceName: config.Tracing.ServiceName,
+		SampleRatio: config.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Fatal("Invalid tracing configuration:", err)
+	}
+
+	db, err := tracing.OpenDB("postgres", config.DB.DatabaseURL)
 	if err != nil {
 		log.Fatal("Failed to connect to DB:", err)
 	}
@@ -42,55 +76,226 @@ func main() {
 		log.Fatal("Failed to ping DB:", err)
 	}
 
-	log.Printf("Connected to DB with max open conns: %d", config.DB.MaxOpenConnection)
-	log.Printf("Running with log level: %s", config.Logger.LoggerLevel)
+	logger.Info("connected to DB", "max_open_conns", config.DB.MaxOpenConnection)
+	logger.Info("logger configured", "level", logLevel.Level(), "format", config.Logger.Format, "pii", config.Logger.PII)
 
 	if err := migration.RunMigrations(db); err != nil {
 		log.Fatal("Failed to run migrations:", err)
 	}
 
+	appMetrics := metrics.New(db)
+
 	withdrawalRepo := postgresql.NewWithdrawalRepository(db)
-	balanceRepo := postgresql.NewBalanceRepository(db)
+	balanceRepo := postgresql.NewBalanceRepository(db, postgresql.WithLockObserver(appMetrics))
+	feeLedger := postgresql.NewFeeLedger(db)
+	auditRepo := postgresql.NewAuditRepository(db)
+	actionRepo := postgresql.NewWithdrawalActionRepository(db)
 
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
-	r.Use(middleware.Logger)
+	r.Use(handlerhttp.RequestMeta)
+	r.Use(tracing.Middleware)
+	r.Use(logging.Middleware(logger))
+	r.Use(appMetrics.Middleware)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(30 * time.Second))
+
+	broadcaster := service.NewBroadcaster(config.Events.HistorySize)
+
+	defaultLimits := make([]domain.WithdrawalLimits, 0, len(config.Limits.Currencies))
+	for _, l := range config.Limits.Currencies {
+		defaultLimits = append(defaultLimits, domain.WithdrawalLimits{
+			Currency:          l.Currency,
+			MinPerTransaction: l.MinPerTransaction,
+			MaxPerTransaction: l.MaxPerTransaction,
+			Daily:             l.Daily,
+			Monthly:           l.Monthly,
+		})
+	}
+	limitService := service.NewLimitService(postgresql.NewWithdrawalLimitRepository(db), withdrawalRepo, defaultLimits,
+		service.WithLimitAudit(balanceRepo, auditRepo))
+
+	feeSchedules := make([]domain.FeeSchedule, 0, len(config.Fees.Schedules))
+	for _, f := range config.Fees.Schedules {
+		schedule := domain.FeeSchedule{
+			Currency: f.Currency,
+			Network:  f.Network,
+			Type:     domain.FeeType(f.Type),
+			Flat:     f.Flat,
+			Percent:  f.Percent,
+			Min:      f.Min,
+			Max:      f.Max,
+		}
+		for _, t := range f.Tiers {
+			schedule.Tiers = append(schedule.Tiers, domain.FeeTier{UpTo: t.UpTo, Flat: t.Flat, Percent: t.Percent})
+		}
+		feeSchedules = append(feeSchedules, schedule)
+	}
+	feeCalculator, err := service.NewFeeCalculator(feeSchedules)
+	if err != nil {
+		log.Fatal("Invalid fee configuration:", err)
+	}
+
+	addressRules := make([]address.Rule, 0, len(config.Addresses.Rules))
+	for _, a := range config.Addresses.Rules {
+		addressRules = append(addressRules, address.Rule{Currency: a.Currency, Network: a.Network, Format: address.Format(a.Format)})
+	}
+	addressValidator, err := address.NewRegistry(addressRules)
+	if err != nil {
+		log.Fatal("Invalid address configuration:", err)
+	}
+
+	destinationService := service.NewDestinationService(postgresql.NewDestinationRepository(db), addressValidator, config.Destinations.Cooldown)
+
+	approvalPolicy := domain.ApprovalPolicy{
+		Required:   config.Approvals.Required,
+		Thresholds: make(map[string]float64, len(config.Approvals.Thresholds)),
+	}
+	for _, t := range config.Approvals.Thresholds {
+		approvalPolicy.Thresholds[t.Currency] = t.Amount
+	}
+	if len(approvalPolicy.Thresholds) > 0 && approvalPolicy.Required < 1 {
+		log.Fatal("Approvals.required must be at least 1 when thresholds are set")
+	}
+
+	riskRules := make([]risk.RuleConfig, 0, len(config.Risk.Rules))
+	for _, rule := range config.Risk.Rules {
+		riskRules = append(riskRules, risk.RuleConfig{
+			Name:       rule.Name,
+			Type:       rule.Type,
+			Outcome:    rule.Outcome,
+			MaxCount:   rule.MaxCount,
+			Window:     rule.Window,
+			Multiplier: rule.Multiplier,
+			Lookback:   rule.Lookback,
+			MinHistory: rule.MinHistory,
+			Addresses:  rule.Addresses,
+		})
+	}
+	riskChain, err := risk.Build(riskRules, postgresql.NewWithdrawalHistory(db))
+	if err != nil {
+		log.Fatal("Invalid risk configuration:", err)
+	}
+
+	screeningLists := make([]screening.ListFile, 0, len(config.Screening.Lists))
+	for _, l := range config.Screening.Lists {
+		screeningLists = append(screeningLists, screening.ListFile{Name: l.Name, Path: l.Path})
+	}
+	screener, err := screening.NewScreener(screeningLists, config.Screening.ScreenUsers)
+	if err != nil {
+		log.Fatal("Failed to load screening lists:", err)
+	}
+	if len(screeningLists) == 0 {
+		logger.Warn("no screening lists configured; withdrawals are not screened")
+	}
+
+	withdrawalService := service.NewWithdrawalService(withdrawalRepo, balanceRepo,
+		service.WithEventPublisher(broadcaster),
+		service.WithActionRepository(actionRepo),
+		service.WithLimitChecker(limitService),
+		service.WithFeeCalculator(feeCalculator, feeLedger),
+		service.WithAddressValidator(addressValidator),
+		service.WithDestinationChecker(destinationService),
+		service.WithApprovals(approvalPolicy, postgresql.NewApprovalRepository(db)),
+		service.WithRiskEvaluator(riskChain, postgresql.NewRiskDenialRepository(db)),
+		service.WithScreening(screener, postgresql.NewScreeningHitRepository(db)),
+		service.WithMetrics(appMetrics),
+		service.WithAuditLog(auditRepo),
+	)
 
 	withdrawalHandler := handlerhttp.NewWithdrawalHandler(
-		service.NewWithdrawalService(withdrawalRepo, balanceRepo),
+		withdrawalService,
 		config.Token.AuthToken,
 	)
+	if config.Token.AuthToken != "" {
+		logger.Warn("legacy shared token enabled; move its clients to API keys")
+	}
 
-	// API routes with auth
-	r.Group(func(r chi.Router) {
-		r.Use(withdrawalHandler.AuthMiddleware)
-
-		r.Route("/v1/withdrawals", func(r chi.Router) {
-			r.Post("/", withdrawalHandler.CreateWithdrawal)
-			r.Get("/{id}", withdrawalHandler.GetWithdrawal)
-			r.Post("/{id}/confirm", withdrawalHandler.ConfirmWithdrawal)
+	if jwtCfg := config.Token.JWT; jwtCfg.HS256Secret != "" || jwtCfg.JWKSFile != "" {
+		verifier, err := auth.NewJWTVerifier(auth.JWTConfig{
+			HS256Secret: jwtCfg.HS256Secret,
+			JWKSFile:    jwtCfg.JWKSFile,
+			Issuer:      jwtCfg.Issuer,
+			Audience:    jwtCfg.Audience,
+			Leeway:      jwtCfg.Leeway,
 		})
-	})
+		if err != nil {
+			log.Fatal("Failed to configure JWT verification:", err)
+		}
+		withdrawalHandler.WithJWTVerifier(verifier)
+	}
+	withdrawalHandler.WithAPIKeys(auth.NewAPIKeyAuthenticator(postgresql.NewAPIClientRepository(db)))
 
-	// Health checks
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("OK"))
-	})
+	if signing := config.Token.Signing; len(signing.Clients) > 0 {
+		clients := make([]auth.SigningClient, 0, len(signing.Clients))
+		for _, c := range signing.Clients {
+			client := auth.SigningClient{ID: c.ID, Secret: c.Secret, TenantID: c.TenantID}
+			for _, scope := range c.Scopes {
+				if !domain.Scope(scope).Valid() {
+					log.Fatalf("Unknown scope %q for signing client %s", scope, c.ID)
+				}
+				client.Scopes = append(client.Scopes, domain.Scope(scope))
+			}
+			clients = append(clients, client)
+		}
+		withdrawalHandler.WithSignatureVerifier(auth.NewSignatureVerifier(clients, signing.MaxSkew))
+	}
 
-	r.Get("/ready", func(w http.ResponseWriter, r *http.Request) {
-		if err := db.Ping(); err != nil {
-			w.WriteHeader(http.StatusServiceUnavailable)
-			return
+	tlsCfg := config.Server.TLS
+	if len(tlsCfg.ClientIdentities) > 0 {
+		identities := make([]mtls.ClientIdentity, 0, len(tlsCfg.ClientIdentities))
+		for _, c := range tlsCfg.ClientIdentities {
+			identity := mtls.ClientIdentity{Match: c.Match, ClientID: c.ClientID, TenantID: c.TenantID}
+			for _, role := range c.Roles {
+				if !domain.Role(role).Valid() {
+					log.Fatalf("Unknown role %q for client certificate %s", role, c.Match)
+				}
+				identity.Roles = append(identity.Roles, domain.Role(role))
+			}
+			for _, scope := range c.Scopes {
+				if !domain.Scope(scope).Valid() {
+					log.Fatalf("Unknown scope %q for client certificate %s", scope, c.Match)
+				}
+				identity.Scopes = append(identity.Scopes, domain.Scope(scope))
+			}
+			identities = append(identities, identity)
 		}
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("Ready"))
-	})
+		mapper, err := mtls.NewIdentityMapper(identities)
+		if err != nil {
+			log.Fatal("Failed to configure client certificate identities:", err)
+		}
+		withdrawalHandler.WithClientCertificates(mapper)
+	}
+	handlerhttp.RegisterRoutes(r, handlerhttp.Handlers{
+		Withdrawals:  withdrawalHandler,
+		Destinations: handlerhttp.NewDestinationsHandler(destinationService),
+		Limits:       handlerhttp.NewLimitsHandler(limitService),
+		Screening:    handlerhttp.NewScreeningHandler(screener),
+		LogLevel:     handlerhttp.NewLogLevelHandler(logLevel),
+		Events:       handlerhttp.NewEventsHandler(withdrawalService, broadcaster, config.Events.HeartbeatInterval),
+	}, withdrawalHandler.AuthMiddleware)
 
+	checker := health.NewChecker()
+	checker.Register("database", health.Database(db, 100*time.Millisecond))
+	checker.Register("migrations", health.SchemaVersion(func(ctx context.Context) (int, error) {
+		return migration.CurrentVersion(ctx, db)
+	}, migration.SchemaVersion))
+	checker.Register("db_pool", health.Pool(db, 0.9))
+
+	// Health checks, metrics and the API description
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(30 * time.Second))
+
+		r.Get("/livez", checker.Livez)
+		r.Get("/readyz", checker.Readyz)
+		// Kept for existing probes and scripts
+		r.Get("/health", checker.Livez)
+		r.Get("/ready", checker.Readyz)
+
+		r.Method(http.MethodGet, "/metrics", appMetrics.Handler())
+		r.Get("/openapi.json", handlerhttp.OpenAPI)
+	})
 
 	httpServer := &http.Server{
 		Addr:         ":" + config.Server.Port,
@@ -99,10 +304,77 @@ func main() {
 		IdleTimeout:  config.Server.IdleTimeout,
 		Handler:      r,
 	}
+	// Open event streams would otherwise hold Shutdown until its deadline
+	httpServer.RegisterOnShutdown(broadcaster.Close)
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	if tlsCfg.CertFile != "" {
+		minVersion, err := mtls.ParseMinVersion(tlsCfg.MinVersion)
+		if err != nil {
+			log.Fatal("Invalid TLS configuration:", err)
+		}
+		clientAuth, err := mtls.ParseClientAuth(tlsCfg.ClientAuth)
+		if err != nil {
+			log.Fatal("Invalid TLS configuration:", err)
+		}
+		if clientAuth != tls.NoClientCert && tlsCfg.ClientCAFile == "" {
+			log.Fatal("Invalid TLS configuration: clientAuth requires clientCAFile")
+		}
+
+		reloader, err := mtls.NewReloader(tlsCfg.CertFile, tlsCfg.KeyFile, tlsCfg.ClientCAFile)
+		if err != nil {
+			log.Fatal("Failed to load TLS certificates:", err)
+		}
+		httpServer.TLSConfig = reloader.TLSConfig(minVersion, clientAuth)
+
+		go func() {
+			if err := reloader.Watch(workerCtx); err != nil {
+				logger.Error("TLS certificate watcher stopped", "error", err)
+			}
+		}()
+	}
+
+	if len(screeningLists) > 0 {
+		go func() {
+			if err := screener.Watch(workerCtx); err != nil {
+				logger.Error("screening list watcher stopped", "error", err)
+			}
+		}()
+	}
+
+	if config.Expiry.Enabled {
+		interval := config.Expiry.Interval
+		if interval <= 0 {
+			interval = time.Minute
+		}
+		heartbeat := health.NewHeartbeat()
+		checker.Register("expiry_worker", heartbeat.Check(3*interval))
+		// A withdrawal left pending a few intervals past its TTL is one the worker did not get to
+		ttl := config.Expiry.TTL
+		checker.Register("expiry_backlog", health.Backlog(func(ctx context.Context) (int, error) {
+			return withdrawalRepo.CountPendingBeforeAllTenants(ctx, time.Now().Add(-ttl-3*interval))
+		}, 0))
+		expiryWorker := service.NewExpiryWorker(withdrawalRepo, balanceRepo, feeLedger, broadcaster, config.Expiry.TTL, config.Expiry.BatchSize).
+			WithApprovalTTL(config.Approvals.TTL).
+			WithAuditLog(auditRepo).
+			WithActionRepository(actionRepo).
+			WithHeartbeat(heartbeat.Beat)
+		go expiryWorker.Run(workerCtx, interval)
+	}
 
 	go func() {
-		log.Printf("Server starting on port %s", config.Server.Port)
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if httpServer.TLSConfig != nil {
+			logger.Info("server starting with TLS", "port", config.Server.Port)
+			// Certificates come from TLSConfig so that they can be reloaded
+			err = httpServer.ListenAndServeTLS("", "")
+		} else {
+			logger.Info("server starting", "port", config.Server.Port)
+			err = httpServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Failed to start server: %v", err)
 		}
 	}()
@@ -111,7 +383,11 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	// Fail readiness first and keep serving until load balancers have noticed
+	logger.Info("shutting down server", "delay", config.Server.ShutdownDelay)
+	checker.Shutdown()
+	time.Sleep(config.Server.ShutdownDelay)
+	stopWorkers()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -120,6 +396,10 @@ func main() {
 		log.Fatal("Server forced to shutdown:", err)
 	}
 
-	log.Println("Server exited")
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("failed to flush traces", "error", err)
+	}
+
+	logger.Info("server exited")
 
 }
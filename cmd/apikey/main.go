@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"idempot/internal/auth"
+	"idempot/internal/config"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+	"idempot/internal/repository/postgresql"
+
+	_ "github.com/lib/pq"
+)
+
+const usage = `usage:
+  apikey issue  -client NAME -scopes withdrawals:create,withdrawals:read [-ttl 2160h]
+  apikey revoke -key-id ID [-grace 24h]
+  apikey list   -client NAME
+
+Rotate a key by issuing a new one for the same client, deploying it, and then
+revoking the old key (optionally with -grace to keep both valid for a while).`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	config, err := config.Load()
+	if err != nil {
+		log.Fatal("failed to load configuration:", err)
+	}
+
+	db, err := sql.Open("postgres", config.DB.DatabaseURL)
+	if err != nil {
+		log.Fatal("Failed to connect to DB:", err)
+	}
+	defer db.Close()
+
+	repo := postgresql.NewAPIClientRepository(db)
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "issue":
+		err = issue(ctx, repo, os.Args[2:])
+	case "revoke":
+		err = revoke(ctx, repo, os.Args[2:])
+	case "list":
+		err = list(ctx, repo, os.Args[2:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func issue(ctx context.Context, repo port.APIClientRepository, args []string) error {
+	fs := flag.NewFlagSet("issue", flag.ExitOnError)
+	client := fs.String("client", "", "client name")
+	tenant := fs.String("tenant", domain.DefaultTenant, "tenant the client belongs to")
+	scopeList := fs.String("scopes", "", "comma separated scopes")
+	ttl := fs.Duration("ttl", 0, "key lifetime, 0 for no expiry")
+	_ = fs.Parse(args)
+
+	if *client == "" || *scopeList == "" {
+		return fmt.Errorf("-client and -scopes are required")
+	}
+
+	var scopes []domain.Scope
+	for _, raw := range strings.Split(*scopeList, ",") {
+		scope := domain.Scope(strings.TrimSpace(raw))
+		if !scope.Valid() {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+		scopes = append(scopes, scope)
+	}
+
+	plaintext, keyID, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	key := &domain.APIKey{
+		KeyID:      keyID,
+		ClientName: *client,
+		TenantID:   *tenant,
+		KeyHash:    hash,
+		Scopes:     scopes,
+		CreatedAt:  now,
+	}
+	if *ttl > 0 {
+		expires := now.Add(*ttl)
+		key.ExpiresAt = &expires
+	}
+
+	if err := repo.Create(ctx, key); err != nil {
+		return fmt.Errorf("store key: %w", err)
+	}
+
+	fmt.Printf("key id: %s\n", keyID)
+	fmt.Printf("api key: %s\n", plaintext)
+	fmt.Println("The key is not stored and cannot be shown again.")
+	return nil
+}
+
+func revoke(ctx context.Context, repo port.APIClientRepository, args []string) error {
+	fs := flag.NewFlagSet("revoke", flag.ExitOnError)
+	keyID := fs.String("key-id", "", "key id to revoke")
+	grace := fs.Duration("grace", 0, "keep the key valid for this long")
+	_ = fs.Parse(args)
+
+	if *keyID == "" {
+		return fmt.Errorf("-key-id is required")
+	}
+
+	at := time.Now().Add(*grace)
+	if err := repo.Revoke(ctx, *keyID, at); err != nil {
+		return err
+	}
+
+	fmt.Printf("key %s revoked as of %s\n", *keyID, at.Format(time.RFC3339))
+	return nil
+}
+
+func list(ctx context.Context, repo port.APIClientRepository, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	client := fs.String("client", "", "client name")
+	_ = fs.Parse(args)
+
+	if *client == "" {
+		return fmt.Errorf("-client is required")
+	}
+
+	keys, err := repo.ListByClient(ctx, *client)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, k := range keys {
+		state := "active"
+		if !k.Active(now) {
+			state = "inactive"
+		}
+		scopes := make([]string, 0, len(k.Scopes))
+		for _, s := range k.Scopes {
+			scopes = append(scopes, string(s))
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\tcreated %s\n", k.KeyID, k.TenantID, state, strings.Join(scopes, ","), k.CreatedAt.Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"idempot/internal/audit"
+	"idempot/internal/config"
+	"idempot/internal/repository/postgresql"
+
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	tenant := flag.String("tenant", "", "tenant whose audit log is verified; all tenants if empty")
+	pageSize := flag.Int("page-size", 1000, "entries read per query")
+	flag.Parse()
+
+	config, err := config.Load()
+	if err != nil {
+		log.Fatal("failed to load configuration:", err)
+	}
+
+	db, err := sql.Open("postgres", config.DB.DatabaseURL)
+	if err != nil {
+		log.Fatal("Failed to connect to DB:", err)
+	}
+	defer db.Close()
+
+	verifier := audit.NewVerifier(postgresql.NewAuditRepository(db)).WithPageSize(*pageSize)
+
+	var results []*audit.Result
+	if *tenant != "" {
+		results, err = verifier.VerifyTenant(context.Background(), *tenant)
+	} else {
+		results, err = verifier.VerifyAll(context.Background())
+	}
+	if err != nil {
+		log.Fatal("Verification failed: ", err)
+	}
+
+	broken := 0
+	for _, result := range results {
+		if result.OK() {
+			log.Printf("Tenant %s, user %q: %d entries, head %s", result.TenantID, result.UserID, result.Entries, result.Head)
+			continue
+		}
+		broken++
+		log.Printf("Tenant %s, user %q: chain broken at entry %d: %s", result.TenantID, result.UserID, result.Break.Seq, result.Break.Reason)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(results); err != nil {
+		log.Fatal("Failed to write report: ", err)
+	}
+
+	if broken > 0 {
+		os.Exit(1)
+	}
+}
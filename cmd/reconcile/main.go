@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"idempot/internal/config"
+	"idempot/internal/domain"
+	"idempot/internal/reconcile"
+	"idempot/internal/repository/postgresql"
+
+	_ "github.com/lib/pq"
+)
+
+const dateLayout = "2006-01-02"
+
+func main() {
+	file := flag.String("file", "", "settlement file (.csv or .json)")
+	date := flag.String("date", "", "settlement day (YYYY-MM-DD, UTC); shorthand for -from/-to")
+	from := flag.String("from", "", "start of the reconciled period (YYYY-MM-DD, inclusive)")
+	to := flag.String("to", "", "end of the reconciled period (YYYY-MM-DD, exclusive)")
+	fix := flag.Bool("fix", false, "apply provider statuses where it is safe to do so")
+	tenant := flag.String("tenant", domain.DefaultTenant, "tenant whose withdrawals the file settles")
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	periodStart, periodEnd, err := period(*date, *from, *to)
+	if err != nil {
+		log.Fatal("Invalid period: ", err)
+	}
+
+	config, err := config.Load()
+	if err != nil {
+		log.Fatal("failed to load configuration:", err)
+	}
+
+	db, err := sql.Open("postgres", config.DB.DatabaseURL)
+	if err != nil {
+		log.Fatal("Failed to connect to DB:", err)
+	}
+	defer db.Close()
+
+	records, err := reconcile.ParseFile(*file)
+	if err != nil {
+		log.Fatal("Failed to parse settlement file: ", err)
+	}
+
+	reconciler := reconcile.NewReconciler(
+		postgresql.NewWithdrawalRepository(db),
+		postgresql.NewBalanceRepository(db),
+		postgresql.NewFeeLedger(db),
+		postgresql.NewReconciliationRepository(db),
+	).
+		WithAuditLog(postgresql.NewAuditRepository(db)).
+		WithActionRepository(postgresql.NewWithdrawalActionRepository(db))
+
+	run, err := reconciler.Run(context.Background(), records, reconcile.Options{
+		TenantID:   *tenant,
+		SourceFile: *file,
+		From:       periodStart,
+		To:         periodEnd,
+		Fix:        *fix,
+	})
+	if err != nil {
+		log.Fatal("Reconciliation failed: ", err)
+	}
+
+	log.Printf("Run %s: matched=%d missing=%d extra=%d amount_mismatched=%d status_mismatched=%d fixed=%d",
+		run.ID, run.Matched, run.Missing, run.Extra, run.AmountMismatched, run.StatusMismatched, run.Fixed)
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(run); err != nil {
+		log.Fatal("Failed to write report: ", err)
+	}
+
+	if len(run.Discrepancies) > run.Fixed {
+		os.Exit(1)
+	}
+}
+
+func period(date, from, to string) (time.Time, time.Time, error) {
+	if date != "" {
+		day, err := time.Parse(dateLayout, date)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		return day, day.AddDate(0, 0, 1), nil
+	}
+
+	start, err := time.Parse(dateLayout, from)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	end, err := time.Parse(dateLayout, to)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return start, end, nil
+}
@@ -0,0 +1,102 @@
+// Package address checks that withdrawal destinations are well-formed for the
+// currency and network they are sent on, so that a mistyped address is
+// rejected before any money moves.
+package address
+
+import (
+	"fmt"
+	"idempot/internal/domain"
+	"sort"
+	"strings"
+)
+
+// Format names a family of address encodings.
+type Format string
+
+const (
+	// FormatEVM is a 0x-prefixed 20-byte hex address with an EIP-55 checksum.
+	FormatEVM Format = "evm"
+	// FormatTron is a Base58Check address with the 0x41 version byte.
+	FormatTron Format = "tron"
+	// FormatBitcoin accepts legacy Base58Check P2PKH/P2SH and bech32/bech32m segwit addresses.
+	FormatBitcoin Format = "btc"
+	// FormatIBAN is an International Bank Account Number checked with mod-97.
+	FormatIBAN Format = "iban"
+)
+
+var checkers = map[Format]func(string) error{
+	FormatEVM:     checkEVM,
+	FormatTron:    checkTron,
+	FormatBitcoin: checkBitcoin,
+	FormatIBAN:    checkIBAN,
+}
+
+// Rule assigns a format to a currency on a network. An empty Network applies
+// to every network without a rule of its own.
+type Rule struct {
+	Currency string
+	Network  string
+	Format   Format
+}
+
+type ruleKey struct {
+	currency string
+	network  string
+}
+
+// Registry implements port.AddressValidator. Currencies without any rule are
+// not checked; a currency with rules only for some networks must name one of them.
+type Registry struct {
+	rules    map[ruleKey]Format
+	networks map[string][]string
+}
+
+func NewRegistry(rules []Rule) (*Registry, error) {
+	r := &Registry{
+		rules:    make(map[ruleKey]Format, len(rules)),
+		networks: make(map[string][]string),
+	}
+	for _, rule := range rules {
+		if rule.Currency == "" {
+			return nil, fmt.Errorf("address rule without currency")
+		}
+		if _, ok := checkers[rule.Format]; !ok {
+			return nil, fmt.Errorf("address rule %s/%s: unknown format %q", rule.Currency, rule.Network, rule.Format)
+		}
+		key := ruleKey{rule.Currency, rule.Network}
+		if _, dup := r.rules[key]; dup {
+			return nil, fmt.Errorf("address rule %s/%s: defined twice", rule.Currency, rule.Network)
+		}
+		r.rules[key] = rule.Format
+		r.networks[rule.Currency] = append(r.networks[rule.Currency], rule.Network)
+	}
+	for _, networks := range r.networks {
+		sort.Strings(networks)
+	}
+	return r, nil
+}
+
+// Validate returns a *domain.InvalidDestinationError naming the problem with destination.
+func (r *Registry) Validate(currency string, network string, destination string) error {
+	networks, known := r.networks[currency]
+	if !known {
+		return nil
+	}
+
+	format, ok := r.rules[ruleKey{currency, network}]
+	if !ok {
+		format, ok = r.rules[ruleKey{currency, ""}]
+	}
+	if !ok {
+		reason := fmt.Sprintf("network %q is not supported, use one of %s", network, strings.Join(networks, ", "))
+		if network == "" {
+			reason = "network is required, use one of " + strings.Join(networks, ", ")
+		}
+		return &domain.InvalidDestinationError{Currency: currency, Network: network, Reason: reason}
+	}
+
+	if err := checkers[format](destination); err != nil {
+		return &domain.InvalidDestinationError{Currency: currency, Network: network, Reason: err.Error()}
+	}
+	return nil
+}
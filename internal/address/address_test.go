@@ -0,0 +1,97 @@
+package address
+
+import (
+	"errors"
+	"testing"
+
+	"idempot/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormats(t *testing.T) {
+	cases := []struct {
+		format Format
+		addr   string
+		reason string
+	}{
+		{FormatEVM, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", ""},
+		{FormatEVM, "0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359", ""},
+		{FormatEVM, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD", "EIP-55 checksum mismatch, expected 0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"},
+		{FormatEVM, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeA", "address must have 40 hex digits after 0x, got 38"},
+		{FormatEVM, "5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", "address must start with 0x"},
+		{FormatEVM, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeg", "address contains non-hex characters"},
+
+		{FormatTron, "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", ""},
+		{FormatTron, "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6T", "Base58Check checksum mismatch"},
+		{FormatTron, "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj60", "character '0' at position 34 is not valid base58"},
+		{FormatTron, "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", "TRON address must start with T"},
+
+		{FormatBitcoin, "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", ""},
+		{FormatBitcoin, "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", ""},
+		{FormatBitcoin, "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNb", "Base58Check checksum mismatch"},
+		{FormatBitcoin, "BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", ""},
+		{FormatBitcoin, "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", ""},
+		{FormatBitcoin, "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5", "bech32 checksum mismatch"},
+		{FormatBitcoin, "bc1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", "bech32 address mixes upper and lower case"},
+		{FormatBitcoin, "bc1pw508d6qejxtdg4y5r3zarvary0c5xw7k8e76x7", "witness version 1 must use bech32m, not bech32"},
+		{FormatBitcoin, "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx", "segwit address must start with bc1, not tb1"},
+
+		{FormatIBAN, "DE89370400440532013000", ""},
+		{FormatIBAN, "GB82 WEST 1234 5698 7654 32", ""},
+		{FormatIBAN, "DE89370400440532013001", "IBAN check digits do not match"},
+		{FormatIBAN, "DE8937040044053201300", "IBAN for DE must be 22 characters, got 21"},
+		{FormatIBAN, "de89370400440532013000", "IBAN must start with a two-letter country code"},
+		{FormatIBAN, "DE8937", "IBAN must be 15 to 34 characters, got 6"},
+	}
+	for _, tc := range cases {
+		t.Run(string(tc.format)+"/"+tc.addr, func(t *testing.T) {
+			err := checkers[tc.format](tc.addr)
+			if tc.reason == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.reason)
+			}
+		})
+	}
+}
+
+func TestRegistry_Validate(t *testing.T) {
+	registry, err := NewRegistry([]Rule{
+		{Currency: "USDT", Network: "ethereum", Format: FormatEVM},
+		{Currency: "USDT", Network: "tron", Format: FormatTron},
+		{Currency: "EUR", Format: FormatIBAN},
+	})
+	require.NoError(t, err)
+
+	assert.NoError(t, registry.Validate("USDT", "tron", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"))
+	assert.NoError(t, registry.Validate("EUR", "sepa", "DE89370400440532013000"))
+	assert.NoError(t, registry.Validate("XYZ", "", "anything"), "currencies without rules are not checked")
+
+	err = registry.Validate("USDT", "ethereum", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t")
+	assert.True(t, errors.Is(err, domain.ErrInvalidDestination))
+	var destErr *domain.InvalidDestinationError
+	require.True(t, errors.As(err, &destErr))
+	assert.Equal(t, "address must start with 0x", destErr.Reason)
+
+	err = registry.Validate("USDT", "", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t")
+	assert.EqualError(t, err, "invalid destination for USDT: network is required, use one of ethereum, tron")
+
+	err = registry.Validate("USDT", "solana", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t")
+	assert.EqualError(t, err, `invalid destination for USDT on solana: network "solana" is not supported, use one of ethereum, tron`)
+}
+
+func TestNewRegistry_RejectsInvalidRules(t *testing.T) {
+	_, err := NewRegistry([]Rule{{Currency: "USDT", Format: "sepa"}})
+	assert.Error(t, err)
+
+	_, err = NewRegistry([]Rule{{Format: FormatEVM}})
+	assert.Error(t, err)
+
+	_, err = NewRegistry([]Rule{
+		{Currency: "USDT", Network: "tron", Format: FormatTron},
+		{Currency: "USDT", Network: "tron", Format: FormatEVM},
+	})
+	assert.Error(t, err)
+}
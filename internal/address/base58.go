@@ -0,0 +1,92 @@
+package address
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var base58Index = func() [256]int {
+	var idx [256]int
+	for i := range idx {
+		idx[i] = -1
+	}
+	for i := 0; i < len(base58Alphabet); i++ {
+		idx[base58Alphabet[i]] = i
+	}
+	return idx
+}()
+
+func base58Decode(s string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for i := 0; i < len(s); i++ {
+		d := base58Index[s[i]]
+		if d < 0 {
+			return nil, fmt.Errorf("character %q at position %d is not valid base58", s[i], i+1)
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(d)))
+	}
+
+	// Each leading '1' encodes a leading zero byte
+	zeros := 0
+	for zeros < len(s) && s[zeros] == '1' {
+		zeros++
+	}
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
+
+// base58CheckDecode verifies the trailing double-SHA256 checksum and returns
+// the version byte and payload.
+func base58CheckDecode(s string) (byte, []byte, error) {
+	raw, err := base58Decode(s)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(raw) < 5 {
+		return 0, nil, fmt.Errorf("address is too short")
+	}
+	body, checksum := raw[:len(raw)-4], raw[len(raw)-4:]
+	first := sha256.Sum256(body)
+	second := sha256.Sum256(first[:])
+	if !bytes.Equal(second[:4], checksum) {
+		return 0, nil, fmt.Errorf("Base58Check checksum mismatch")
+	}
+	return body[0], body[1:], nil
+}
+
+const tronVersion = 0x41
+
+func checkTron(addr string) error {
+	if len(addr) == 0 || addr[0] != 'T' {
+		return fmt.Errorf("TRON address must start with T")
+	}
+	version, payload, err := base58CheckDecode(addr)
+	if err != nil {
+		return err
+	}
+	if version != tronVersion || len(payload) != 20 {
+		return fmt.Errorf("not a TRON address: version 0x%02x with %d-byte payload", version, len(payload))
+	}
+	return nil
+}
+
+const (
+	bitcoinP2PKH = 0x00
+	bitcoinP2SH  = 0x05
+)
+
+func checkBitcoinLegacy(addr string) error {
+	version, payload, err := base58CheckDecode(addr)
+	if err != nil {
+		return err
+	}
+	if (version != bitcoinP2PKH && version != bitcoinP2SH) || len(payload) != 20 {
+		return fmt.Errorf("not a mainnet P2PKH or P2SH address: version 0x%02x with %d-byte payload", version, len(payload))
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package address
+
+import (
+	"fmt"
+	"strings"
+)
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+const (
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+)
+
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	out := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]>>5)
+	}
+	out = append(out, 0)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]&31)
+	}
+	return out
+}
+
+// bech32Decode returns the human-readable part, the 5-bit data without the
+// checksum and the checksum constant it verified against (bech32 or bech32m).
+func bech32Decode(s string) (string, []byte, uint32, error) {
+	if len(s) > 90 {
+		return "", nil, 0, fmt.Errorf("bech32 address is longer than 90 characters")
+	}
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, 0, fmt.Errorf("bech32 address mixes upper and lower case")
+	}
+	s = strings.ToLower(s)
+
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || sep+7 > len(s) {
+		return "", nil, 0, fmt.Errorf("bech32 separator is missing or misplaced")
+	}
+	hrp := s[:sep]
+	data := make([]byte, 0, len(s)-sep-1)
+	for i := sep + 1; i < len(s); i++ {
+		d := strings.IndexByte(bech32Charset, s[i])
+		if d < 0 {
+			return "", nil, 0, fmt.Errorf("character %q at position %d is not valid bech32", s[i], i+1)
+		}
+		data = append(data, byte(d))
+	}
+
+	constant := bech32Polymod(append(bech32HRPExpand(hrp), data...))
+	if constant != bech32Const && constant != bech32mConst {
+		return "", nil, 0, fmt.Errorf("bech32 checksum mismatch")
+	}
+	return hrp, data[:len(data)-6], constant, nil
+}
+
+// convertBits regroups 5-bit words into bytes, rejecting non-zero padding.
+func convertBits(data []byte, from, to uint) ([]byte, error) {
+	var acc, bits uint
+	maxv := uint(1)<<to - 1
+	out := make([]byte, 0, len(data)*int(from)/int(to))
+	for _, v := range data {
+		acc = acc<<from | uint(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits&maxv))
+		}
+	}
+	if bits >= from || (acc<<(to-bits))&maxv != 0 {
+		return nil, fmt.Errorf("invalid padding in witness program")
+	}
+	return out, nil
+}
+
+const bitcoinHRP = "bc"
+
+// checkSegwit follows BIP-173 and BIP-350: version 0 uses bech32 with a 20 or
+// 32-byte program, later versions use bech32m.
+func checkSegwit(addr string) error {
+	hrp, data, constant, err := bech32Decode(addr)
+	if err != nil {
+		return err
+	}
+	if hrp != bitcoinHRP {
+		return fmt.Errorf("segwit address must start with %s1, not %s1", bitcoinHRP, hrp)
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("segwit address has no witness version")
+	}
+
+	version := data[0]
+	if version > 16 {
+		return fmt.Errorf("witness version %d is out of range", version)
+	}
+	program, err := convertBits(data[1:], 5, 8)
+	if err != nil {
+		return err
+	}
+	if len(program) < 2 || len(program) > 40 {
+		return fmt.Errorf("witness program of %d bytes is out of range", len(program))
+	}
+	if version == 0 {
+		if constant != bech32Const {
+			return fmt.Errorf("witness version 0 must use bech32, not bech32m")
+		}
+		if len(program) != 20 && len(program) != 32 {
+			return fmt.Errorf("witness version 0 program must be 20 or 32 bytes, got %d", len(program))
+		}
+	} else if constant != bech32mConst {
+		return fmt.Errorf("witness version %d must use bech32m, not bech32", version)
+	}
+	return nil
+}
+
+// checkBitcoin tells the two encodings apart by prefix; a well-formed bech32
+// string with another prefix, such as a testnet tb1 address, is reported as such.
+func checkBitcoin(addr string) error {
+	if strings.HasPrefix(strings.ToLower(addr), bitcoinHRP+"1") {
+		return checkSegwit(addr)
+	}
+	if _, _, _, err := bech32Decode(addr); err == nil {
+		return checkSegwit(addr)
+	}
+	return checkBitcoinLegacy(addr)
+}
@@ -0,0 +1,51 @@
+package address
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// checkEVM accepts all-lowercase and all-uppercase addresses, which carry no
+// checksum under EIP-55, and otherwise requires the mixed-case checksum to match.
+func checkEVM(addr string) error {
+	if !strings.HasPrefix(addr, "0x") {
+		return fmt.Errorf("address must start with 0x")
+	}
+	body := addr[2:]
+	if len(body) != 40 {
+		return fmt.Errorf("address must have 40 hex digits after 0x, got %d", len(body))
+	}
+	if _, err := hex.DecodeString(body); err != nil {
+		return fmt.Errorf("address contains non-hex characters")
+	}
+	if body == strings.ToLower(body) || body == strings.ToUpper(body) {
+		return nil
+	}
+	if want := eip55(body); body != want {
+		return fmt.Errorf("EIP-55 checksum mismatch, expected 0x%s", want)
+	}
+	return nil
+}
+
+// eip55 capitalises every letter whose nibble in keccak256(lowercase address) is 8 or more.
+func eip55(body string) string {
+	lower := strings.ToLower(body)
+	h := sha3.NewLegacyKeccak256()
+	h.Write([]byte(lower))
+	digest := h.Sum(nil)
+
+	out := []byte(lower)
+	for i, c := range out {
+		nibble := digest[i/2]
+		if i%2 == 0 {
+			nibble >>= 4
+		}
+		if c >= 'a' && c <= 'f' && nibble&0x0f >= 8 {
+			out[i] = c - 'a' + 'A'
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,62 @@
+package address
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ibanLengths covers the SEPA countries; other countries only get the generic checks.
+var ibanLengths = map[string]int{
+	"AD": 24, "AT": 20, "BE": 16, "BG": 22, "CH": 21, "CY": 28, "CZ": 24, "DE": 22,
+	"DK": 18, "EE": 20, "ES": 24, "FI": 18, "FR": 27, "GB": 22, "GI": 23, "GR": 27,
+	"HR": 21, "HU": 28, "IE": 22, "IS": 26, "IT": 27, "LI": 21, "LT": 20, "LU": 20,
+	"LV": 21, "MC": 27, "MT": 31, "NL": 18, "NO": 15, "PL": 28, "PT": 25, "RO": 24,
+	"SE": 24, "SI": 19, "SK": 24, "SM": 27, "VA": 22,
+}
+
+// checkIBAN accepts the paper format with spaces but otherwise expects upper case.
+func checkIBAN(addr string) error {
+	iban := strings.ReplaceAll(addr, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return fmt.Errorf("IBAN must be 15 to 34 characters, got %d", len(iban))
+	}
+
+	country := iban[:2]
+	if !isUpperLetters(country) {
+		return fmt.Errorf("IBAN must start with a two-letter country code")
+	}
+	if iban[2] < '0' || iban[2] > '9' || iban[3] < '0' || iban[3] > '9' {
+		return fmt.Errorf("IBAN check digits must be numeric")
+	}
+	if want, ok := ibanLengths[country]; ok && len(iban) != want {
+		return fmt.Errorf("IBAN for %s must be %d characters, got %d", country, want, len(iban))
+	}
+
+	// Move the first four characters to the end, spell letters as 10..35, mod 97 must be 1
+	rearranged := iban[4:] + iban[:4]
+	remainder := 0
+	for i := 0; i < len(rearranged); i++ {
+		c := rearranged[i]
+		switch {
+		case c >= '0' && c <= '9':
+			remainder = (remainder*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			remainder = (remainder*100 + int(c-'A'+10)) % 97
+		default:
+			return fmt.Errorf("IBAN contains invalid character %q", c)
+		}
+	}
+	if remainder != 1 {
+		return fmt.Errorf("IBAN check digits do not match")
+	}
+	return nil
+}
+
+func isUpperLetters(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 'A' || s[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
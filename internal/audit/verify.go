@@ -0,0 +1,128 @@
+// Package audit checks the hash-chained audit logs for tampering.
+package audit
+
+import (
+	"context"
+	"fmt"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+)
+
+const defaultPageSize = 1000
+
+// Break is the first entry at which a tenant's chain does not hold.
+type Break struct {
+	Seq    int64  `json:"seq"`
+	Reason string `json:"reason"`
+}
+
+// Result reports on one chain of a tenant's log; UserID is empty for the
+// tenant-wide chain of entries from before per-user chains. Entries counts
+// the entries read and Head is the hash of the last one.
+type Result struct {
+	TenantID string `json:"tenant_id"`
+	UserID   string `json:"user_id,omitempty"`
+	Entries  int64  `json:"entries"`
+	Head     string `json:"head"`
+	Break    *Break `json:"break,omitempty"`
+}
+
+func (r *Result) OK() bool {
+	return r.Break == nil
+}
+
+type Verifier struct {
+	repo     port.AuditRepository
+	pageSize int
+}
+
+func NewVerifier(repo port.AuditRepository) *Verifier {
+	return &Verifier{repo: repo, pageSize: defaultPageSize}
+}
+
+// WithPageSize sets how many entries are read per query.
+func (v *Verifier) WithPageSize(n int) *Verifier {
+	if n > 0 {
+		v.pageSize = n
+	}
+	return v
+}
+
+// VerifyAll verifies every chain of every tenant.
+func (v *Verifier) VerifyAll(ctx context.Context) ([]*Result, error) {
+	return v.verifyChains(ctx, func(domain.AuditChain) bool { return true })
+}
+
+// VerifyTenant verifies every chain of one tenant.
+func (v *Verifier) VerifyTenant(ctx context.Context, tenantID string) ([]*Result, error) {
+	return v.verifyChains(ctx, func(chain domain.AuditChain) bool { return chain.TenantID == tenantID })
+}
+
+func (v *Verifier) verifyChains(ctx context.Context, include func(domain.AuditChain) bool) ([]*Result, error) {
+	chains, err := v.repo.ListChains(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list audit chains: %w", err)
+	}
+
+	var results []*Result
+	for _, chain := range chains {
+		if !include(chain) {
+			continue
+		}
+		result, err := v.Verify(ctx, chain)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Verify recomputes every entry's hash in order and checks that entries are
+// numbered without gaps, that each links to the hash of the one before and
+// that the chain ends where its head says it does. It stops at the first
+// break.
+func (v *Verifier) Verify(ctx context.Context, chain domain.AuditChain) (*Result, error) {
+	result := &Result{TenantID: chain.TenantID, UserID: chain.UserID}
+
+	for {
+		events, err := v.repo.List(ctx, chain, result.Entries, v.pageSize)
+		if err != nil {
+			return nil, fmt.Errorf("list audit events of %s/%s: %w", chain.TenantID, chain.UserID, err)
+		}
+
+		for _, e := range events {
+			want := result.Entries + 1
+			switch {
+			case e.Seq != want:
+				result.Break = &Break{Seq: want, Reason: fmt.Sprintf("entries %d to %d are missing", want, e.Seq-1)}
+			case e.PrevHash != result.Head:
+				result.Break = &Break{Seq: e.Seq, Reason: "previous hash does not match the entry before"}
+			case e.ComputeHash() != e.Hash:
+				result.Break = &Break{Seq: e.Seq, Reason: "content does not match its hash"}
+			}
+			if result.Break != nil {
+				return result, nil
+			}
+			result.Entries = e.Seq
+			result.Head = e.Hash
+		}
+
+		if len(events) < v.pageSize {
+			break
+		}
+	}
+
+	// Entries removed from the end leave a valid but shorter chain
+	headSeq, headHash, err := v.repo.GetHead(ctx, chain)
+	if err != nil {
+		return nil, fmt.Errorf("get audit chain head of %s/%s: %w", chain.TenantID, chain.UserID, err)
+	}
+	switch {
+	case headSeq != result.Entries:
+		result.Break = &Break{Seq: min(headSeq, result.Entries) + 1, Reason: fmt.Sprintf("the chain head is at entry %d", headSeq)}
+	case headHash != result.Head:
+		result.Break = &Break{Seq: result.Entries, Reason: "the chain head does not match the last entry"}
+	}
+	return result, nil
+}
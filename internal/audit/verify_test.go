@@ -0,0 +1,173 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"idempot/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryLog chains events like the PostgreSQL repository does.
+type memoryLog struct {
+	events map[domain.AuditChain][]*domain.AuditEvent
+	heads  map[domain.AuditChain]*domain.AuditEvent
+}
+
+func newMemoryLog() *memoryLog {
+	return &memoryLog{events: map[domain.AuditChain][]*domain.AuditEvent{}, heads: map[domain.AuditChain]*domain.AuditEvent{}}
+}
+
+func (l *memoryLog) Append(_ context.Context, e *domain.AuditEvent) error {
+	chain := domain.AuditChain{TenantID: e.TenantID, UserID: e.UserID}
+	e.Seq, e.PrevHash = 1, ""
+	if head := l.heads[chain]; head != nil {
+		e.Seq, e.PrevHash = head.Seq+1, head.Hash
+	}
+	e.Hash = e.ComputeHash()
+	l.events[chain] = append(l.events[chain], e)
+	l.heads[chain] = e
+	return nil
+}
+
+func (l *memoryLog) ListChains(context.Context) ([]domain.AuditChain, error) {
+	var chains []domain.AuditChain
+	for chain := range l.heads {
+		chains = append(chains, chain)
+	}
+	return chains, nil
+}
+
+func (l *memoryLog) List(_ context.Context, chain domain.AuditChain, afterSeq int64, limit int) ([]*domain.AuditEvent, error) {
+	var out []*domain.AuditEvent
+	for _, e := range l.events[chain] {
+		if e.Seq > afterSeq && len(out) < limit {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (l *memoryLog) GetHead(_ context.Context, chain domain.AuditChain) (int64, string, error) {
+	if head := l.heads[chain]; head != nil {
+		return head.Seq, head.Hash, nil
+	}
+	return 0, "", nil
+}
+
+// seed appends n limit changes of the user by an admin to the tenant's log.
+func seed(t *testing.T, l *memoryLog, tenantID, userID string, n int) {
+	t.Helper()
+	ctx := domain.WithPrincipal(context.Background(), &domain.Principal{Subject: "admin-1", Kind: domain.PrincipalUser, TenantID: tenantID})
+	ctx = domain.WithRequestMeta(ctx, domain.RequestMeta{ClientIP: "203.0.113.7", RequestID: "req-1"})
+	for i := 0; i < n; i++ {
+		e, err := domain.NewAuditEvent(ctx, domain.AuditLimitsChanged, domain.AuditEntityLimits, userID, userID,
+			&domain.WithdrawalLimits{Currency: "USDT", Daily: float64(i)},
+			&domain.WithdrawalLimits{Currency: "USDT", Daily: float64(i + 1)})
+		require.NoError(t, err)
+		require.NoError(t, l.Append(ctx, e))
+	}
+}
+
+var userA = domain.AuditChain{TenantID: "brand-a", UserID: "user-1"}
+
+func TestVerify_IntactChain(t *testing.T) {
+	l := newMemoryLog()
+	seed(t, l, "brand-a", "user-1", 7)
+	seed(t, l, "brand-a", "user-2", 1)
+	seed(t, l, "brand-b", "user-1", 2)
+	// Entries from before per-user chains
+	seed(t, l, "brand-a", "", 2)
+
+	// A page size that does not divide the log exercises paging
+	results, err := NewVerifier(l).WithPageSize(3).VerifyAll(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+	for _, r := range results {
+		assert.True(t, r.OK(), "%s/%s: %+v", r.TenantID, r.UserID, r.Break)
+	}
+
+	results, err = NewVerifier(l).VerifyTenant(context.Background(), "brand-a")
+	require.NoError(t, err)
+	assert.Len(t, results, 3)
+
+	r, err := NewVerifier(l).Verify(context.Background(), userA)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), r.Entries)
+	assert.Equal(t, l.heads[userA].Hash, r.Head)
+
+	first := l.events[userA][0]
+	assert.Equal(t, "admin-1", first.ActorSubject)
+	assert.Equal(t, "203.0.113.7", first.ClientIP)
+	assert.Equal(t, "req-1", first.RequestID)
+	assert.Empty(t, first.PrevHash)
+}
+
+func TestVerify_EmptyLog(t *testing.T) {
+	r, err := NewVerifier(newMemoryLog()).Verify(context.Background(), userA)
+	require.NoError(t, err)
+	assert.True(t, r.OK())
+	assert.Zero(t, r.Entries)
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	for name, tc := range map[string]struct {
+		tamper func(l *memoryLog)
+		seq    int64
+	}{
+		"edited snapshot": {
+			tamper: func(l *memoryLog) {
+				l.events[userA][2].After = json.RawMessage(`{"currency":"USDT","daily":1000000}`)
+			},
+			seq: 3,
+		},
+		"edited actor with recomputed hash": {
+			tamper: func(l *memoryLog) {
+				e := l.events[userA][2]
+				e.ActorSubject = "someone-else"
+				e.Hash = e.ComputeHash()
+			},
+			seq: 4,
+		},
+		"deleted entry": {
+			tamper: func(l *memoryLog) {
+				events := l.events[userA]
+				l.events[userA] = append(events[:1:1], events[2:]...)
+			},
+			seq: 2,
+		},
+		"swapped entries": {
+			tamper: func(l *memoryLog) {
+				events := l.events[userA]
+				events[1], events[2] = events[2], events[1]
+			},
+			seq: 2,
+		},
+		"entry moved to another user's chain": {
+			tamper: func(l *memoryLog) {
+				l.events[userA][2].UserID = "user-2"
+			},
+			seq: 3,
+		},
+		"deleted tail": {
+			tamper: func(l *memoryLog) {
+				l.events[userA] = l.events[userA][:3]
+			},
+			seq: 4,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			l := newMemoryLog()
+			seed(t, l, "brand-a", "user-1", 5)
+			tc.tamper(l)
+
+			r, err := NewVerifier(l).Verify(context.Background(), userA)
+			require.NoError(t, err)
+			require.False(t, r.OK())
+			assert.Equal(t, tc.seq, r.Break.Seq, r.Break.Reason)
+		})
+	}
+}
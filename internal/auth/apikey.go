@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+	"strings"
+	"time"
+)
+
+// API keys look like "idk_<key id>.<secret>". The key id is public and used for
+// lookup, only the SHA-256 of the secret is stored.
+const apiKeyPrefix = "idk_"
+
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// IsAPIKey reports whether token has the API key shape.
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyPrefix)
+}
+
+// GenerateAPIKey returns a new plaintext key together with its key id and hash.
+// The plaintext is shown to the operator once and never stored.
+func GenerateAPIKey() (plaintext, keyID, hash string, err error) {
+	idBytes := make([]byte, 8)
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", "", "", err
+	}
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", "", err
+	}
+
+	keyID = hex.EncodeToString(idBytes)
+	secret := base64.RawURLEncoding.EncodeToString(secretBytes)
+	return apiKeyPrefix + keyID + "." + secret, keyID, HashSecret(secret), nil
+}
+
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func parseAPIKey(token string) (keyID, secret string, ok bool) {
+	keyID, secret, ok = strings.Cut(strings.TrimPrefix(token, apiKeyPrefix), ".")
+	return keyID, secret, ok && IsAPIKey(token) && keyID != "" && secret != ""
+}
+
+type APIKeyAuthenticator struct {
+	repo port.APIClientRepository
+	now  func() time.Time
+}
+
+func NewAPIKeyAuthenticator(repo port.APIClientRepository) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{repo: repo, now: time.Now}
+}
+
+// Authenticate resolves token to the owning client. Unknown, expired, revoked and
+// mismatching keys all fail with ErrInvalidAPIKey.
+func (a *APIKeyAuthenticator) Authenticate(ctx context.Context, token string) (*domain.Principal, error) {
+	keyID, secret, ok := parseAPIKey(token)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := a.repo.GetByKeyID(ctx, keyID)
+	if errors.Is(err, domain.ErrAPIKeyNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(HashSecret(secret)), []byte(key.KeyHash)) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+	if !key.Active(a.now()) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	return &domain.Principal{
+		Subject:  key.ClientName,
+		Kind:     domain.PrincipalService,
+		TenantID: tenantOrDefault(key.TenantID),
+		Scopes:   key.Scopes,
+	}, nil
+}
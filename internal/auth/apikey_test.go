@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"idempot/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryAPIClients map[string]*domain.APIKey
+
+func (m memoryAPIClients) Create(_ context.Context, key *domain.APIKey) error {
+	m[key.KeyID] = key
+	return nil
+}
+
+func (m memoryAPIClients) GetByKeyID(_ context.Context, keyID string) (*domain.APIKey, error) {
+	if k, ok := m[keyID]; ok {
+		return k, nil
+	}
+	return nil, domain.ErrAPIKeyNotFound
+}
+
+func (m memoryAPIClients) ListByClient(_ context.Context, clientName string) ([]*domain.APIKey, error) {
+	var keys []*domain.APIKey
+	for _, k := range m {
+		if k.ClientName == clientName {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (m memoryAPIClients) Revoke(_ context.Context, keyID string, at time.Time) error {
+	k, ok := m[keyID]
+	if !ok {
+		return domain.ErrAPIKeyNotFound
+	}
+	k.RevokedAt = &at
+	return nil
+}
+
+func issueKey(t *testing.T, repo memoryAPIClients, client string, scopes ...domain.Scope) (string, string) {
+	t.Helper()
+	plaintext, keyID, hash, err := GenerateAPIKey()
+	require.NoError(t, err)
+	require.NoError(t, repo.Create(context.Background(), &domain.APIKey{
+		KeyID: keyID, ClientName: client, KeyHash: hash, Scopes: scopes, CreatedAt: time.Now(),
+	}))
+	return plaintext, keyID
+}
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	repo := memoryAPIClients{}
+	a := NewAPIKeyAuthenticator(repo)
+	ctx := context.Background()
+
+	key, keyID := issueKey(t, repo, "payouts", domain.ScopeWithdrawalsRead)
+	assert.NotContains(t, repo[keyID].KeyHash, key[len(apiKeyPrefix)+len(keyID)+1:])
+
+	principal, err := a.Authenticate(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, "payouts", principal.Subject)
+	assert.True(t, principal.HasScope(domain.ScopeWithdrawalsRead))
+	assert.False(t, principal.HasScope(domain.ScopeWithdrawalsCreate))
+
+	_, err = a.Authenticate(ctx, key+"x")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	_, err = a.Authenticate(ctx, "idk_unknown.secret")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestAPIKeyAuthenticator_Rotation(t *testing.T) {
+	repo := memoryAPIClients{}
+	a := NewAPIKeyAuthenticator(repo)
+	ctx := context.Background()
+
+	oldKey, oldID := issueKey(t, repo, "payouts", domain.ScopeWithdrawalsCreate)
+	newKey, _ := issueKey(t, repo, "payouts", domain.ScopeWithdrawalsCreate)
+
+	// Both keys work during the grace period
+	require.NoError(t, repo.Revoke(ctx, oldID, time.Now().Add(time.Hour)))
+	_, err := a.Authenticate(ctx, oldKey)
+	assert.NoError(t, err)
+	_, err = a.Authenticate(ctx, newKey)
+	assert.NoError(t, err)
+
+	a.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, err = a.Authenticate(ctx, oldKey)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	_, err = a.Authenticate(ctx, newKey)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"idempot/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeSegment(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func signHS256(t *testing.T, secret string, claims map[string]any) string {
+	t.Helper()
+	signed := encodeSegment(t, map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + encodeSegment(t, claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	signed := encodeSegment(t, map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func writeJWKS(t *testing.T, kid string, key *rsa.PublicKey) string {
+	t.Helper()
+	set := map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"alg": "RS256",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func TestJWTVerifier_HS256(t *testing.T) {
+	v, err := NewJWTVerifier(JWTConfig{HS256Secret: "secret", Issuer: "idp", Audience: "withdrawals"})
+	require.NoError(t, err)
+
+	exp := time.Now().Add(time.Hour).Unix()
+
+	token := signHS256(t, "secret", map[string]any{"sub": "user-123", "iss": "idp", "aud": []string{"withdrawals"}, "exp": exp})
+	principal, err := v.Principal(token)
+	require.NoError(t, err)
+	assert.Equal(t, "user-123", principal.Subject)
+
+	_, err = v.Verify(signHS256(t, "other", map[string]any{"sub": "user-123", "iss": "idp", "aud": "withdrawals", "exp": exp}))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = v.Verify(signHS256(t, "secret", map[string]any{"sub": "user-123", "iss": "idp", "aud": "withdrawals", "exp": time.Now().Add(-time.Hour).Unix()}))
+	assert.ErrorIs(t, err, ErrTokenExpired)
+
+	_, err = v.Verify(signHS256(t, "secret", map[string]any{"sub": "user-123", "iss": "evil", "aud": "withdrawals", "exp": exp}))
+	assert.ErrorIs(t, err, ErrInvalidClaims)
+}
+
+func TestJWTVerifier_RS256WithJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	v, err := NewJWTVerifier(JWTConfig{JWKSFile: writeJWKS(t, "key-1", &key.PublicKey)})
+	require.NoError(t, err)
+
+	claims := map[string]any{"sub": "user-123", "exp": time.Now().Add(time.Hour).Unix()}
+
+	got, err := v.Verify(signRS256(t, key, "key-1", claims))
+	require.NoError(t, err)
+	assert.Equal(t, "user-123", got.Subject)
+
+	_, err = v.Verify(signRS256(t, key, "key-2", claims))
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	// An HS256 token must not be accepted when only RSA keys are configured
+	_, err = v.Verify(signHS256(t, "secret", claims))
+	assert.ErrorIs(t, err, ErrUnsupportedAlg)
+}
+
+func TestJWTVerifier_Roles(t *testing.T) {
+	v, err := NewJWTVerifier(JWTConfig{HS256Secret: "secret"})
+	require.NoError(t, err)
+	exp := time.Now().Add(time.Hour).Unix()
+
+	customer, err := v.Principal(signHS256(t, "secret", map[string]any{"sub": "user-1", "exp": exp}))
+	require.NoError(t, err)
+	assert.True(t, customer.HasRole(domain.RoleCustomer))
+	assert.True(t, customer.HasScope(domain.ScopeWithdrawalsCreate))
+	// A customer cannot grant itself confirmation rights through the scope claim
+	assert.False(t, customer.HasScope(domain.ScopeWithdrawalsConfirm))
+
+	narrowed, err := v.Principal(signHS256(t, "secret", map[string]any{"sub": "user-1", "exp": exp, "scope": "withdrawals:read withdrawals:confirm"}))
+	require.NoError(t, err)
+	assert.True(t, narrowed.HasScope(domain.ScopeWithdrawalsRead))
+	assert.False(t, narrowed.HasScope(domain.ScopeWithdrawalsCreate))
+	assert.False(t, narrowed.HasScope(domain.ScopeWithdrawalsConfirm))
+
+	operator, err := v.Principal(signHS256(t, "secret", map[string]any{"sub": "op-1", "exp": exp, "roles": []string{"operator"}}))
+	require.NoError(t, err)
+	assert.True(t, operator.HasScope(domain.ScopeWithdrawalsConfirm))
+	assert.False(t, operator.HasScope(domain.ScopeWithdrawalsCreate))
+	assert.True(t, operator.OwnsUser("user-1"))
+
+	_, err = v.Principal(signHS256(t, "secret", map[string]any{"sub": "x", "exp": exp, "roles": []string{"root"}}))
+	assert.ErrorIs(t, err, ErrInvalidClaims)
+}
+
+func TestJWTVerifier_Tenant(t *testing.T) {
+	v, err := NewJWTVerifier(JWTConfig{HS256Secret: "secret"})
+	require.NoError(t, err)
+	exp := time.Now().Add(time.Hour).Unix()
+
+	legacy, err := v.Principal(signHS256(t, "secret", map[string]any{"sub": "user-1", "exp": exp}))
+	require.NoError(t, err)
+	assert.Equal(t, domain.DefaultTenant, legacy.TenantID)
+
+	branded, err := v.Principal(signHS256(t, "secret", map[string]any{"sub": "user-1", "exp": exp, "tenant_id": "brand-b"}))
+	require.NoError(t, err)
+	assert.Equal(t, "brand-b", branded.TenantID)
+}
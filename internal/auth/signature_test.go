@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"idempot/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signedRequest(secret string, at time.Time, nonce, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/v1/withdrawals?dry=1", strings.NewReader(body))
+	ts := at.Unix()
+	r.Header.Set(HeaderClientID, "ledger")
+	r.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	r.Header.Set(HeaderNonce, nonce)
+	r.Header.Set(HeaderSignature, Sign(secret, CanonicalString(http.MethodPost, "/v1/withdrawals?dry=1", ts, nonce, []byte(body))))
+	return r
+}
+
+func TestSignatureVerifier(t *testing.T) {
+	v := NewSignatureVerifier([]SigningClient{{
+		ID: "ledger", Secret: "s3cret", Scopes: []domain.Scope{domain.ScopeWithdrawalsCreate},
+	}}, time.Minute)
+
+	r := signedRequest("s3cret", time.Now(), "nonce-1", `{"amount":1}`)
+	principal, err := v.Verify(r)
+	require.NoError(t, err)
+	assert.Equal(t, "ledger", principal.Subject)
+	assert.True(t, principal.HasScope(domain.ScopeWithdrawalsCreate))
+
+	// The handler still sees the body
+	body, _ := io.ReadAll(r.Body)
+	assert.Equal(t, `{"amount":1}`, string(body))
+
+	_, err = v.Verify(signedRequest("s3cret", time.Now(), "nonce-1", `{"amount":1}`))
+	assert.ErrorIs(t, err, ErrReplayedRequest)
+
+	_, err = v.Verify(signedRequest("s3cret", time.Now().Add(-2*time.Minute), "nonce-2", `{"amount":1}`))
+	assert.ErrorIs(t, err, ErrClockSkew)
+
+	_, err = v.Verify(signedRequest("wrong", time.Now(), "nonce-3", `{"amount":1}`))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	tampered := signedRequest("s3cret", time.Now(), "nonce-4", `{"amount":1}`)
+	tampered.Body = io.NopCloser(strings.NewReader(`{"amount":1000}`))
+	_, err = v.Verify(tampered)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestNonceCache_Expires(t *testing.T) {
+	c := NewNonceCache(time.Minute)
+	now := time.Now()
+
+	assert.True(t, c.Add("a", now))
+	assert.False(t, c.Add("a", now.Add(30*time.Second)))
+	assert.True(t, c.Add("a", now.Add(2*time.Minute)))
+}
@@ -2,7 +2,7 @@ package config
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"time"
 
@@ -10,10 +10,20 @@ import (
 )
 
 type Config struct {
-	Server ServerConfig `yaml:"Server"`
-	DB     DBConfig     `yaml:"DB"`
-	Token  TokenConfig  `yaml:"Token"`
-	Logger LoggerConfig `yaml:"Logger"`
+	Server       ServerConfig       `yaml:"Server"`
+	DB           DBConfig           `yaml:"DB"`
+	Token        TokenConfig        `yaml:"Token"`
+	Logger       LoggerConfig       `yaml:"Logger"`
+	Events       EventsConfig       `yaml:"Events"`
+	Expiry       ExpiryConfig       `yaml:"Expiry"`
+	Limits       LimitsConfig       `yaml:"Limits"`
+	Fees         FeesConfig         `yaml:"Fees"`
+	Addresses    AddressesConfig    `yaml:"Addresses"`
+	Destinations DestinationsConfig `yaml:"Destinations"`
+	Approvals    ApprovalsConfig    `yaml:"Approvals"`
+	Risk         RiskConfig         `yaml:"Risk"`
+	Screening    ScreeningConfig    `yaml:"Screening"`
+	Tracing      TracingConfig      `yaml:"Tracing"`
 }
 
 type ServerConfig struct {
@@ -21,6 +31,32 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration `yaml:"readTimeout" default:"10s"`
 	WriteTimeout time.Duration `yaml:"writeTimeout" default:"10s"`
 	IdleTimeout  time.Duration `yaml:"idleTimeout" default:"10s"`
+	// ShutdownDelay is how long /readyz fails before the server stops accepting
+	// connections, so that load balancers stop routing to it first
+	ShutdownDelay time.Duration `yaml:"shutdownDelay" default:"5s"`
+	TLS           TLSConfig     `yaml:"tls"`
+}
+
+// TLSConfig enables HTTPS when CertFile is set. Certificates are reloaded when
+// the files change.
+type TLSConfig struct {
+	CertFile     string `yaml:"certFile"`
+	KeyFile      string `yaml:"keyFile"`
+	ClientCAFile string `yaml:"clientCAFile"`
+	MinVersion   string `yaml:"minVersion" default:"1.2"`
+	// ClientAuth is one of none, optional or require
+	ClientAuth       string                 `yaml:"clientAuth" default:"none"`
+	ClientIdentities []ClientIdentityConfig `yaml:"clientIdentities"`
+}
+
+// ClientIdentityConfig maps a client certificate to a service principal. Match
+// is "cn:", "dns:", "uri:" or "email:" followed by the expected value.
+type ClientIdentityConfig struct {
+	Match    string   `yaml:"match"`
+	ClientID string   `yaml:"clientID"`
+	TenantID string   `yaml:"tenantID" default:"default"`
+	Roles    []string `yaml:"roles"`
+	Scopes   []string `yaml:"scopes"`
 }
 
 type DBConfig struct {
@@ -32,11 +68,169 @@ type DBConfig struct {
 }
 
 type TokenConfig struct {
-	AuthToken string `yaml:"authToken" default:"test-token"`
+	// AuthToken enables the legacy shared bearer token when set. Leave it empty
+	// and give each client an API key instead.
+	AuthToken string        `yaml:"authToken"`
+	JWT       JWTConfig     `yaml:"jwt"`
+	Signing   SigningConfig `yaml:"signing"`
+}
+
+// JWTConfig enables end-user tokens; leave both HS256Secret and JWKSFile empty to disable.
+type JWTConfig struct {
+	HS256Secret string        `yaml:"hs256Secret"`
+	JWKSFile    string        `yaml:"jwksFile"`
+	Issuer      string        `yaml:"issuer"`
+	Audience    string        `yaml:"audience"`
+	Leeway      time.Duration `yaml:"leeway" default:"30s"`
 }
 
+// LoggerConfig sets the initial level, which can be changed at runtime through
+// the admin API, and the output format: json or text. PII is how user IDs and
+// destination addresses appear in logs: plain, truncate or hash; PIISalt keys
+// the hash so that it cannot be reversed by hashing known IDs.
 type LoggerConfig struct {
 	LoggerLevel string `yaml:"loggerLevel" default:"info"`
+	Format      string `yaml:"format" default:"json"`
+	PII         string `yaml:"pii" default:"hash"`
+	PIISalt     string `yaml:"piiSalt"`
+}
+
+// TracingConfig exports OpenTelemetry spans. Exporter is none, stdout or file;
+// SampleRatio is the share of new traces recorded, zero meaning all of them.
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" default:"none"`
+	File        string  `yaml:"file"`
+	ServiceName string  `yaml:"serviceName" default:"idempot"`
+	SampleRatio float64 `yaml:"sampleRatio" default:"1"`
+}
+
+type EventsConfig struct {
+	HeartbeatInterval time.Duration `yaml:"heartbeatInterval" default:"15s"`
+	HistorySize       int           `yaml:"historySize" default:"1024"`
+}
+
+type ExpiryConfig struct {
+	Enabled   bool          `yaml:"enabled" default:"true"`
+	TTL       time.Duration `yaml:"ttl" default:"24h"`
+	Interval  time.Duration `yaml:"interval" default:"1m"`
+	BatchSize int           `yaml:"batchSize" default:"100"`
+}
+
+// LimitsConfig holds the global withdrawal limits; users can be given overrides
+// in the withdrawal_limits table. Zero means no limit.
+type LimitsConfig struct {
+	Currencies []CurrencyLimitsConfig `yaml:"currencies"`
+}
+
+type CurrencyLimitsConfig struct {
+	Currency          string  `yaml:"currency"`
+	MinPerTransaction float64 `yaml:"minPerTransaction"`
+	MaxPerTransaction float64 `yaml:"maxPerTransaction"`
+	Daily             float64 `yaml:"daily"`
+	Monthly           float64 `yaml:"monthly"`
+}
+
+// FeesConfig holds one schedule per currency and, optionally, network. Fees are
+// booked to the fee ledger under the withdrawal's tenant.
+type FeesConfig struct {
+	Schedules []FeeScheduleConfig `yaml:"schedules"`
+}
+
+type FeeScheduleConfig struct {
+	Currency string `yaml:"currency"`
+	Network  string `yaml:"network"`
+	// Type is one of flat, percentage or tiered
+	Type    string          `yaml:"type"`
+	Flat    float64         `yaml:"flat"`
+	Percent float64         `yaml:"percent"`
+	Tiers   []FeeTierConfig `yaml:"tiers"`
+	Min     float64         `yaml:"min"`
+	Max     float64         `yaml:"max"`
+}
+
+type FeeTierConfig struct {
+	UpTo    float64 `yaml:"upTo"`
+	Flat    float64 `yaml:"flat"`
+	Percent float64 `yaml:"percent"`
+}
+
+// AddressesConfig says which address format each currency and network uses.
+// Destinations in currencies without a rule are not checked.
+type AddressesConfig struct {
+	Rules []AddressRuleConfig `yaml:"rules"`
+}
+
+type AddressRuleConfig struct {
+	Currency string `yaml:"currency"`
+	Network  string `yaml:"network"`
+	// Format is one of evm, tron, btc or iban
+	Format string `yaml:"format"`
+}
+
+// DestinationsConfig sets how long a new address book entry waits before it
+// can be withdrawn to.
+type DestinationsConfig struct {
+	Cooldown time.Duration `yaml:"cooldown" default:"24h"`
+}
+
+// ApprovalsConfig holds withdrawals above a currency's threshold until
+// Required distinct operators, none of them the requester, approve them.
+// A withdrawal nobody decided on within TTL expires and is refunded; zero
+// holds it until decided.
+type ApprovalsConfig struct {
+	Required   int                       `yaml:"required" default:"2"`
+	TTL        time.Duration             `yaml:"ttl" default:"72h"`
+	Thresholds []ApprovalThresholdConfig `yaml:"thresholds"`
+}
+
+type ApprovalThresholdConfig struct {
+	Currency string  `yaml:"currency"`
+	Amount   float64 `yaml:"amount"`
+}
+
+// RiskConfig lists the risk rules run before a withdrawal debits the balance,
+// in order. Each rule holds the withdrawal for approval or denies it.
+type RiskConfig struct {
+	Rules []RiskRuleConfig `yaml:"rules"`
+}
+
+type RiskRuleConfig struct {
+	Name string `yaml:"name"`
+	// Type is one of velocity, new_destination, unusual_amount or sanctioned_address
+	Type string `yaml:"type"`
+	// Outcome is hold or deny
+	Outcome    string        `yaml:"outcome"`
+	MaxCount   int           `yaml:"maxCount"`
+	Window     time.Duration `yaml:"window"`
+	Multiplier float64       `yaml:"multiplier"`
+	Lookback   int           `yaml:"lookback"`
+	MinHistory int           `yaml:"minHistory"`
+	Addresses  []string      `yaml:"addresses"`
+}
+
+// ScreeningConfig lists the compliance deny-lists, CSV or JSON, that are
+// reloaded whenever the files change.
+type ScreeningConfig struct {
+	ScreenUsers bool                  `yaml:"screenUsers"`
+	Lists       []ScreeningListConfig `yaml:"lists"`
+}
+
+type ScreeningListConfig struct {
+	Name string `yaml:"name"`
+	Path string `yaml:"path"`
+}
+
+// SigningConfig lists server-to-server clients that sign requests with HMAC-SHA256.
+type SigningConfig struct {
+	MaxSkew time.Duration         `yaml:"maxSkew" default:"5m"`
+	Clients []SigningClientConfig `yaml:"clients"`
+}
+
+type SigningClientConfig struct {
+	ID       string   `yaml:"id"`
+	Secret   string   `yaml:"secret"`
+	TenantID string   `yaml:"tenantID" default:"default"`
+	Scopes   []string `yaml:"scopes"`
 }
 
 func Load() (*Config, error) {
@@ -53,15 +247,15 @@ func Load() (*Config, error) {
 
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			log.Println("file not found")
+			slog.Warn("config file not found")
 		} else {
-			log.Println("error reading config file")
+			slog.Error("error reading config file", "error", err)
 		}
 	} else {
-		log.Printf("using config file: %s", viper.ConfigFileUsed())
+		slog.Info("using config file", "path", viper.ConfigFileUsed())
 	}
 
-	log.Printf("all settings: %v", viper.AllSettings())
+	slog.Info("loaded settings", "settings", RedactSettings(viper.AllSettings()))
 
 	var config Config
 
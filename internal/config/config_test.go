@@ -0,0 +1,83 @@
+package config
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const secretConfig = `
+DB:
+  databaseURL: "postgres://app:db-pa55word@db:5432/idempot?sslmode=disable"
+Token:
+  authToken: "static-t0ken"
+  jwt:
+    hs256Secret: "jwt-s3cret"
+  signing:
+    clients:
+      - id: "payments"
+        secret: "hmac-s3cret"
+        scopes: ["withdrawals:create"]
+Logger:
+  loggerLevel: "info"
+  piiSalt: "pii-s4lt"
+`
+
+var secrets = []string{"db-pa55word", "static-t0ken", "jwt-s3cret", "hmac-s3cret", "pii-s4lt"}
+
+func TestLoad_DoesNotLogSecrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(secretConfig), 0o600))
+	t.Setenv("CONFIG_FILE", path)
+
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	defer slog.SetDefault(previous)
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	// The secrets are loaded, just never written out
+	assert.Equal(t, "static-t0ken", cfg.Token.AuthToken)
+	assert.Equal(t, "hmac-s3cret", cfg.Token.Signing.Clients[0].Secret)
+
+	out := buf.String()
+	assert.Contains(t, out, "loaded settings")
+	assert.Contains(t, out, "postgres://app:[REDACTED]@db:5432/idempot")
+	for _, secret := range secrets {
+		assert.NotContains(t, out, secret)
+	}
+}
+
+func TestRedactSettings(t *testing.T) {
+	settings := map[string]any{
+		"db": map[string]any{
+			"databaseurl": "host=db user=app password=pa55 dbname=idempot",
+			"port":        5432,
+		},
+		"token": map[string]any{
+			"authtoken": "t0ken",
+			"jwt":       map[string]any{"hs256secret": "", "issuer": "idempot"},
+		},
+		"dsn": "postgres://db/idempot?password=pa55&sslmode=disable",
+	}
+
+	got := RedactSettings(settings)
+	db := got["db"].(map[string]any)
+	assert.Equal(t, "host=db user=app password=[REDACTED] dbname=idempot", db["databaseurl"])
+	assert.Equal(t, 5432, db["port"])
+	token := got["token"].(map[string]any)
+	assert.Equal(t, "[REDACTED]", token["authtoken"])
+	// Unset secrets stay empty, so a dump still shows what is configured
+	assert.Equal(t, "", token["jwt"].(map[string]any)["hs256secret"])
+	assert.Equal(t, "idempot", token["jwt"].(map[string]any)["issuer"])
+	assert.NotContains(t, got["dsn"], "pa55")
+
+	// The input is left untouched
+	assert.Equal(t, "t0ken", settings["token"].(map[string]any)["authtoken"])
+}
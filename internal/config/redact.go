@@ -0,0 +1,65 @@
+package config
+
+import (
+	"idempot/internal/logging"
+	"net/url"
+	"regexp"
+)
+
+// keyValuePassword matches the password in a key=value connection string.
+var keyValuePassword = regexp.MustCompile(`(?i)(password\s*=\s*)('[^']*'|\S+)`)
+
+// RedactSettings returns a copy of settings, as returned by viper, that is safe
+// to log: secret values are replaced and passwords in connection URLs removed.
+func RedactSettings(settings map[string]any) map[string]any {
+	out := make(map[string]any, len(settings))
+	for k, v := range settings {
+		out[k] = redactValue(k, v)
+	}
+	return out
+}
+
+func redactValue(key string, v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		return RedactSettings(v)
+	case []any:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = redactValue(key, item)
+		}
+		return items
+	case string:
+		if logging.IsSecretKey(key) {
+			if v == "" {
+				return v
+			}
+			return logging.Redacted
+		}
+		return redactConnectionString(v)
+	}
+	if logging.IsSecretKey(key) {
+		return logging.Redacted
+	}
+	return v
+}
+
+// redactConnectionString strips the password from URL and key=value DSNs and
+// leaves any other string alone.
+func redactConnectionString(s string) string {
+	if u, err := url.Parse(s); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), logging.Redacted)
+		}
+		query := u.Query()
+		for k := range query {
+			if logging.IsSecretKey(k) {
+				query.Set(k, logging.Redacted)
+			}
+		}
+		u.RawQuery = query.Encode()
+		redacted, _ := url.PathUnescape(u.String())
+		return redacted
+	}
+	return keyValuePassword.ReplaceAllString(s, "${1}"+logging.Redacted)
+}
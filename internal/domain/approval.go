@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ApprovalPolicy holds withdrawals above a per-currency threshold in
+// StatusAwaitingApproval until Required distinct operators approve them.
+type ApprovalPolicy struct {
+	Required   int
+	Thresholds map[string]float64
+}
+
+// Requires tells whether a withdrawal needs approval. Currencies without a
+// threshold never do.
+func (p ApprovalPolicy) Requires(currency string, amount float64) bool {
+	threshold, ok := p.Thresholds[currency]
+	return ok && p.Required > 0 && amount > threshold
+}
+
+type ApprovalDecision string
+
+const (
+	DecisionApprove ApprovalDecision = "approve"
+	DecisionReject  ApprovalDecision = "reject"
+)
+
+// Approval is one operator's decision on a withdrawal awaiting approval.
+type Approval struct {
+	WithdrawalID uuid.UUID
+	Decision     ApprovalDecision
+	ActorSubject string
+	ActorKind    PrincipalKind
+	Reason       string
+	CreatedAt    time.Time
+}
+
+// ApprovalProgress is where a withdrawal stands after a decision.
+type ApprovalProgress struct {
+	WithdrawalID uuid.UUID        `json:"withdrawal_id"`
+	Status       WithdrawalStatus `json:"status"`
+	Approvals    int              `json:"approvals"`
+	Required     int              `json:"required"`
+}
+
+var (
+	ErrSelfApproval   = errors.New("the requester cannot approve or reject their own withdrawal")
+	ErrAlreadyDecided = errors.New("operator has already decided on this withdrawal")
+)
@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidDestination = errors.New("invalid destination")
+
+// InvalidDestinationError says why a destination cannot receive the currency on
+// the network. It matches ErrInvalidDestination with errors.Is.
+type InvalidDestinationError struct {
+	Currency string
+	Network  string
+	Reason   string
+}
+
+func (e *InvalidDestinationError) Error() string {
+	if e.Network == "" {
+		return fmt.Sprintf("%s for %s: %s", ErrInvalidDestination, e.Currency, e.Reason)
+	}
+	return fmt.Sprintf("%s for %s on %s: %s", ErrInvalidDestination, e.Currency, e.Network, e.Reason)
+}
+
+func (e *InvalidDestinationError) Is(target error) bool {
+	return target == ErrInvalidDestination
+}
+
+// Destination is an address in a user's address book. It cannot be withdrawn
+// to before UsableAt, so that an attacker who adds an address cannot use it at once.
+type Destination struct {
+	ID        uuid.UUID `json:"id"`
+	TenantID  string    `json:"-"`
+	UserID    string    `json:"user_id"`
+	Currency  string    `json:"currency"`
+	Network   string    `json:"network"`
+	Address   string    `json:"address"`
+	Label     string    `json:"label,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UsableAt  time.Time `json:"usable_at"`
+}
+
+func (d *Destination) Usable(now time.Time) bool {
+	return !now.Before(d.UsableAt)
+}
+
+type DestinationReq struct {
+	UserID   string `json:"user_id" validate:"required,notreserved"`
+	Currency string `json:"currency" validate:"required"`
+	Network  string `json:"network"`
+	Address  string `json:"address" validate:"required"`
+	Label    string `json:"label" validate:"max=100"`
+}
+
+// DestinationSettings is a user's allow-list mode. Turning the mode off only
+// takes effect at EnforcedUntil, after the same cooldown as a new address.
+type DestinationSettings struct {
+	UserID        string     `json:"user_id"`
+	AllowListOnly bool       `json:"allow_list_only"`
+	EnforcedUntil *time.Time `json:"enforced_until,omitempty"`
+}
+
+// Enforced tells whether withdrawals must go to the address book at now.
+func (s *DestinationSettings) Enforced(now time.Time) bool {
+	return s.AllowListOnly || (s.EnforcedUntil != nil && now.Before(*s.EnforcedUntil))
+}
+
+var (
+	ErrDestinationNotFound   = errors.New("destination not found")
+	ErrDestinationExists     = errors.New("destination already in address book")
+	ErrDestinationNotAllowed = errors.New("destination not allowed")
+)
+
+// DestinationNotAllowedError rejects a withdrawal in allow-list mode. UsableAt
+// is set when the address is in the book but still cooling down.
+// It matches ErrDestinationNotAllowed with errors.Is.
+type DestinationNotAllowedError struct {
+	Address  string
+	UsableAt *time.Time
+}
+
+func (e *DestinationNotAllowedError) Error() string {
+	if e.UsableAt != nil {
+		return fmt.Sprintf("%s: %s cannot be used before %s", ErrDestinationNotAllowed, e.Address, e.UsableAt.UTC().Format(time.RFC3339))
+	}
+	return fmt.Sprintf("%s: %s is not in the address book", ErrDestinationNotAllowed, e.Address)
+}
+
+func (e *DestinationNotAllowedError) Is(target error) bool {
+	return target == ErrDestinationNotAllowed
+}
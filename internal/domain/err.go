@@ -1,6 +1,10 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 var (
 	ErrDuplicateRequest       = errors.New("duplicate request")
@@ -8,5 +12,41 @@ var (
 	ErrInsufficientBalance    = errors.New("insufficient balance")
 	ErrIdempotencyKeyMismatch = errors.New("idempotency key mismatch")
 	ErrUnauthorized           = errors.New("unauthorized")
+	ErrForbidden              = errors.New("forbidden")
 	ErrLockTimeout            = errors.New("lock timeout")
+	ErrAPIKeyNotFound         = errors.New("api key not found")
+	ErrStatusConflict         = errors.New("withdrawal status changed concurrently")
+	ErrMissingTenant          = errors.New("tenant is required")
+	ErrReservedUserID         = errors.New("user id is reserved")
 )
+
+// InsufficientBalanceError tells how much was requested against how much the
+// balance holds. It matches ErrInsufficientBalance with errors.Is.
+type InsufficientBalanceError struct {
+	Currency  string
+	Available float64
+	Requested float64
+}
+
+func (e *InsufficientBalanceError) Error() string {
+	return fmt.Sprintf("%s: %g %s available, %g requested", ErrInsufficientBalance, e.Available, e.Currency, e.Requested)
+}
+
+func (e *InsufficientBalanceError) Is(target error) bool {
+	return target == ErrInsufficientBalance
+}
+
+// IdempotencyKeyMismatchError lists the fields, by their JSON names, in which
+// a request differs from the one first sent under its idempotency key.
+// It matches ErrIdempotencyKeyMismatch with errors.Is.
+type IdempotencyKeyMismatchError struct {
+	Fields []string
+}
+
+func (e *IdempotencyKeyMismatchError) Error() string {
+	return fmt.Sprintf("%s: %s differ from the original request", ErrIdempotencyKeyMismatch, strings.Join(e.Fields, ", "))
+}
+
+func (e *IdempotencyKeyMismatchError) Is(target error) bool {
+	return target == ErrIdempotencyKeyMismatch
+}
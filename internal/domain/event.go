@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WithdrawalEvent describes a single status transition of a withdrawal.
+// ID is assigned by the broadcaster and increases within one process, so it
+// can be used as the SSE event id for Last-Event-ID resume against that process.
+type WithdrawalEvent struct {
+	ID             uint64           `json:"id"`
+	WithdrawalID   uuid.UUID        `json:"withdrawal_id"`
+	TenantID       string           `json:"tenant_id"`
+	UserID         string           `json:"user_id"`
+	Status         WithdrawalStatus `json:"status"`
+	PreviousStatus WithdrawalStatus `json:"previous_status"`
+	OccurredAt     time.Time        `json:"occurred_at"`
+}
+
+// WithdrawalEventFilter selects events for a subscriber. Empty fields match anything.
+type WithdrawalEventFilter struct {
+	TenantID     string
+	WithdrawalID uuid.UUID
+	UserID       string
+}
+
+func (f WithdrawalEventFilter) Match(e WithdrawalEvent) bool {
+	if f.TenantID != "" && f.TenantID != e.TenantID {
+		return false
+	}
+	if f.WithdrawalID != uuid.Nil && f.WithdrawalID != e.WithdrawalID {
+		return false
+	}
+	if f.UserID != "" && f.UserID != e.UserID {
+		return false
+	}
+	return true
+}
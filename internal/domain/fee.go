@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type FeeType string
+
+const (
+	FeeFlat       FeeType = "flat"
+	FeePercentage FeeType = "percentage"
+	// FeeTiered picks the first tier whose UpTo covers the amount.
+	FeeTiered FeeType = "tiered"
+)
+
+// FeeTier applies to amounts up to and including UpTo; zero UpTo means no upper bound.
+type FeeTier struct {
+	UpTo    float64
+	Flat    float64
+	Percent float64
+}
+
+// FeeSchedule prices withdrawals of one currency, optionally on one network.
+// An empty Network applies to every network without a schedule of its own.
+// The fee is Flat plus Percent of the amount, clamped to [Min, Max]; zero Max
+// means no maximum.
+type FeeSchedule struct {
+	Currency string
+	Network  string
+	Type     FeeType
+	Flat     float64
+	Percent  float64
+	Tiers    []FeeTier
+	Min      float64
+	Max      float64
+}
+
+type FeeQuote struct {
+	Amount    float64 `json:"amount"`
+	Fee       float64 `json:"fee"`
+	NetAmount float64 `json:"net_amount"`
+	Currency  string  `json:"currency"`
+	Network   string  `json:"network,omitempty"`
+}
+
+type FeeEntryKind string
+
+const (
+	FeeCharged  FeeEntryKind = "charge"
+	FeeRefunded FeeEntryKind = "refund"
+)
+
+// FeeEntry is one row of the append-only fee ledger. Refunds carry a negative
+// Amount, so a tenant's collected fees are the sum of its entries.
+type FeeEntry struct {
+	ID           uuid.UUID
+	TenantID     string
+	WithdrawalID uuid.UUID
+	Currency     string
+	Amount       float64
+	Kind         FeeEntryKind
+	CreatedAt    time.Time
+}
+
+var ErrFeeExceedsAmount = errors.New("fee exceeds withdrawal amount")
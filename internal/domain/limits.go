@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// WithdrawalLimits caps withdrawals of one currency. Daily and Monthly are
+// rolling windows of 24 hours and 30 days. In the defaults and the limits in
+// effect, zero means no limit; in a user's override, zero keeps the default.
+type WithdrawalLimits struct {
+	Currency          string  `json:"currency"`
+	MinPerTransaction float64 `json:"min_per_transaction"`
+	MaxPerTransaction float64 `json:"max_per_transaction"`
+	Daily             float64 `json:"daily"`
+	Monthly           float64 `json:"monthly"`
+}
+
+const (
+	DailyLimitWindow   = 24 * time.Hour
+	MonthlyLimitWindow = 30 * 24 * time.Hour
+)
+
+type LimitKind string
+
+const (
+	LimitMinPerTransaction LimitKind = "min_per_transaction"
+	LimitMaxPerTransaction LimitKind = "max_per_transaction"
+	LimitDaily             LimitKind = "daily"
+	LimitMonthly           LimitKind = "monthly"
+)
+
+var (
+	ErrLimitExceeded = errors.New("withdrawal limit exceeded")
+	// ErrInvertedLimits rejects limits, once merged with the defaults, whose
+	// minimum exceeds their maximum: no withdrawal could pass them.
+	ErrInvertedLimits = errors.New("min_per_transaction exceeds max_per_transaction")
+)
+
+// LimitExceededError tells which limit a withdrawal would break and how much
+// may still be withdrawn under it. It matches ErrLimitExceeded with errors.Is.
+type LimitExceededError struct {
+	Kind      LimitKind
+	Currency  string
+	Limit     float64
+	Remaining float64
+}
+
+func (e *LimitExceededError) Error() string {
+	if e.Kind == LimitMinPerTransaction {
+		return fmt.Sprintf("%s: amount is below the minimum of %g %s", ErrLimitExceeded, e.Limit, e.Currency)
+	}
+	return fmt.Sprintf("%s: %s limit of %g %s, %g remaining", ErrLimitExceeded, e.Kind, e.Limit, e.Currency, e.Remaining)
+}
+
+func (e *LimitExceededError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
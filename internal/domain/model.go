@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,30 +13,153 @@ const (
 	StatusPending   WithdrawalStatus = "pending"
 	StatusConfirmed WithdrawalStatus = "confirmed"
 	StatusFailed    WithdrawalStatus = "failed"
+	StatusExpired   WithdrawalStatus = "expired"
+	StatusCancelled WithdrawalStatus = "cancelled"
+	// StatusAwaitingApproval holds a large withdrawal until enough operators approve it;
+	// approval makes it pending, a rejection refunds it.
+	StatusAwaitingApproval WithdrawalStatus = "awaiting_approval"
+	StatusRejected         WithdrawalStatus = "rejected"
 )
 
+// ReservedUserIDPrefix marks IDs kept for the service's own accounts, such as
+// the "__house__" balance that collected fees before the fee ledger.
+const ReservedUserIDPrefix = "__"
+
+// IsReservedUserID reports whether id belongs to the service rather than a customer.
+func IsReservedUserID(id string) bool {
+	return strings.HasPrefix(id, ReservedUserIDPrefix)
+}
+
 type WithdrawalReq struct {
-	UserID         string  `json:"user_id" validate:"required"`
+	UserID         string  `json:"user_id" validate:"required,notreserved"`
 	Amount         float64 `json:"amount" validate:"gt=0"`
 	Currency       string  `json:"currency" validate:"required"`
+	Network        string  `json:"network"`
 	Destination    string  `json:"destination" validate:"required"`
 	IdempotencyKey string  `json:"idempotency_key" validate:"required"`
 }
 
+// ConflictingFields lists the fields, by their JSON names, in which req differs
+// from the withdrawal w created under the same idempotency key.
+func (req *WithdrawalReq) ConflictingFields(w *Withdrawal) []string {
+	var fields []string
+	if req.UserID != w.UserID {
+		fields = append(fields, "user_id")
+	}
+	if req.Amount != w.Amount {
+		fields = append(fields, "amount")
+	}
+	if req.Currency != w.Currency {
+		fields = append(fields, "currency")
+	}
+	if req.Network != w.Network {
+		fields = append(fields, "network")
+	}
+	if req.Destination != w.Destination {
+		fields = append(fields, "destination")
+	}
+	return fields
+}
+
+// QuoteReq asks for the fee of a withdrawal without creating it.
+type QuoteReq struct {
+	Amount   float64 `json:"amount" validate:"gt=0"`
+	Currency string  `json:"currency" validate:"required"`
+	Network  string  `json:"network"`
+}
+
+// Withdrawal debits Amount from the user; the provider pays out NetAmount and
+// Fee goes to the fee ledger. RequestedBy is the subject of the principal
+// that created it; RiskDecision, RiskRule and RiskReason record the risk check
+// that let it through or held it. StatusSince is when it entered its current
+// status, which is what expiry measures.
 type Withdrawal struct {
-	ID             uuid.UUID
-	UserID         string
-	Amount         float64
-	Currency       string
-	Destination    string
-	IdempotencyKey string
-	Status         WithdrawalStatus
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
+	ID                uuid.UUID
+	TenantID          string
+	UserID            string
+	Amount            float64
+	Fee               float64
+	NetAmount         float64
+	Currency          string
+	Network           string
+	Destination       string
+	IdempotencyKey    string
+	Status            WithdrawalStatus
+	RequestedBy       string
+	RiskDecision      RiskOutcome
+	RiskRule          string
+	RiskReason        string
+	ProviderReference string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	StatusSince       time.Time
+}
+
+// Payout is what the provider is expected to send: the amount net of fees.
+func (w *Withdrawal) Payout() float64 {
+	if w.NetAmount == 0 {
+		return w.Amount
+	}
+	return w.NetAmount
+}
+
+type WithdrawalAction string
+
+const (
+	ActionConfirm WithdrawalAction = "confirm"
+	ActionFail    WithdrawalAction = "fail"
+	ActionCancel  WithdrawalAction = "cancel"
+	ActionApprove WithdrawalAction = "approve"
+	ActionReject  WithdrawalAction = "reject"
+	// ActionExpire is taken by the expiry worker, never by a caller.
+	ActionExpire WithdrawalAction = "expire"
+)
+
+// WithdrawalActionRecord tells who moved a withdrawal from one status to another.
+type WithdrawalActionRecord struct {
+	WithdrawalID uuid.UUID
+	Action       WithdrawalAction
+	FromStatus   WithdrawalStatus
+	ToStatus     WithdrawalStatus
+	ActorSubject string
+	ActorKind    PrincipalKind
+	ActorRoles   []Role
+	Reason       string
+	CreatedAt    time.Time
+}
+
+// PageCursor is a keyset position over withdrawals ordered by a timestamp, then id.
+type PageCursor struct {
+	At time.Time
+	ID uuid.UUID
 }
 
 type Balance struct {
+	TenantID string
 	UserID   string
 	Amount   float64 //maybe string
 	Currency string
 }
+
+// APIKey is a hashed per-client credential. A client may hold several active
+// keys at once so that they can be rotated without downtime.
+type APIKey struct {
+	KeyID      string
+	ClientName string
+	TenantID   string
+	KeyHash    string
+	Scopes     []Scope
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	RevokedAt  *time.Time
+}
+
+func (k *APIKey) Active(now time.Time) bool {
+	if k.ExpiresAt != nil && !now.Before(*k.ExpiresAt) {
+		return false
+	}
+	if k.RevokedAt != nil && !now.Before(*k.RevokedAt) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,148 @@
+package domain
+
+import "context"
+
+type PrincipalKind string
+
+const (
+	// PrincipalUser is an end user; it may only act on its own withdrawals.
+	PrincipalUser PrincipalKind = "user"
+	// PrincipalService is a trusted backend client acting on behalf of any user.
+	PrincipalService PrincipalKind = "service"
+	// PrincipalSystem is a background job of this service, such as the expiry worker.
+	PrincipalSystem PrincipalKind = "system"
+)
+
+type Scope string
+
+const (
+	ScopeWithdrawalsCreate Scope = "withdrawals:create"
+	ScopeWithdrawalsRead   Scope = "withdrawals:read"
+	// ScopeWithdrawalsConfirm covers payout decisions: confirm and fail, and
+	// approve and reject for callers that also have the operator or admin role.
+	ScopeWithdrawalsConfirm Scope = "withdrawals:confirm"
+	ScopeWithdrawalsCancel  Scope = "withdrawals:cancel"
+	// ScopeAdmin grants every other scope.
+	ScopeAdmin Scope = "admin"
+)
+
+var knownScopes = map[Scope]bool{
+	ScopeWithdrawalsCreate:  true,
+	ScopeWithdrawalsRead:    true,
+	ScopeWithdrawalsConfirm: true,
+	ScopeWithdrawalsCancel:  true,
+	ScopeAdmin:              true,
+}
+
+type Role string
+
+const (
+	RoleCustomer Role = "customer"
+	RoleOperator Role = "operator"
+	RoleAdmin    Role = "admin"
+)
+
+var roleScopes = map[Role][]Scope{
+	RoleCustomer: {ScopeWithdrawalsCreate, ScopeWithdrawalsRead, ScopeWithdrawalsCancel},
+	RoleOperator: {ScopeWithdrawalsRead, ScopeWithdrawalsConfirm, ScopeWithdrawalsCancel},
+	RoleAdmin:    {ScopeAdmin},
+}
+
+func (r Role) Valid() bool {
+	_, ok := roleScopes[r]
+	return ok
+}
+
+// ScopesForRoles returns every scope granted by roles.
+func ScopesForRoles(roles []Role) []Scope {
+	var scopes []Scope
+	seen := make(map[Scope]bool)
+	for _, r := range roles {
+		for _, s := range roleScopes[r] {
+			if !seen[s] {
+				seen[s] = true
+				scopes = append(scopes, s)
+			}
+		}
+	}
+	return scopes
+}
+
+func (s Scope) Valid() bool {
+	return knownScopes[s]
+}
+
+// DefaultTenant owns callers and data that predate tenancy.
+const DefaultTenant = "default"
+
+// Principal is the authenticated caller of a request.
+// Scopes are the effective permissions; for role-based principals they are
+// derived from Roles when the principal is authenticated.
+// TenantID is the brand the caller belongs to; it never sees other tenants' data.
+type Principal struct {
+	Subject  string
+	Kind     PrincipalKind
+	TenantID string
+	Roles    []Role
+	Scopes   []Scope
+}
+
+// Tenant returns the principal's tenant, falling back to DefaultTenant.
+func (p *Principal) Tenant() string {
+	if p == nil || p.TenantID == "" {
+		return DefaultTenant
+	}
+	return p.TenantID
+}
+
+func (p *Principal) HasRole(role Role) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Principal) HasScope(scope Scope) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// OwnsUser reports whether the principal may act for userID. Operators and
+// admins work on every user's withdrawals.
+func (p *Principal) OwnsUser(userID string) bool {
+	return p.Kind != PrincipalUser || p.Subject == userID || p.HasRole(RoleOperator) || p.HasRole(RoleAdmin)
+}
+
+// SystemPrincipal identifies a background job acting within tenantID, so that
+// its changes are attributed to it in the audit log.
+func SystemPrincipal(name, tenantID string) *Principal {
+	return &Principal{Subject: name, Kind: PrincipalSystem, TenantID: tenantID}
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// TenantFromContext returns the tenant of the authenticated caller, or ""
+// when ctx carries no principal, which repositories reject with
+// ErrMissingTenant. Background jobs name their tenant with SystemPrincipal.
+func TenantFromContext(ctx context.Context) string {
+	p, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	return p.Tenant()
+}
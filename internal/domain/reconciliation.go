@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SettlementRecord is one line of a payout provider settlement report.
+type SettlementRecord struct {
+	WithdrawalID      string           `json:"withdrawal_id"`
+	ProviderReference string           `json:"provider_reference"`
+	Amount            float64          `json:"amount"`
+	Currency          string           `json:"currency"`
+	Status            WithdrawalStatus `json:"status"`
+}
+
+type DiscrepancyKind string
+
+const (
+	DiscrepancyMissing        DiscrepancyKind = "missing"
+	DiscrepancyExtra          DiscrepancyKind = "extra"
+	DiscrepancyAmountMismatch DiscrepancyKind = "amount_mismatch"
+	DiscrepancyStatusMismatch DiscrepancyKind = "status_mismatch"
+)
+
+// Discrepancy describes a withdrawal and settlement record that do not agree.
+// Missing entries have no settlement, extra entries have no withdrawal.
+type Discrepancy struct {
+	Kind              DiscrepancyKind   `json:"kind"`
+	WithdrawalID      uuid.UUID         `json:"withdrawal_id,omitempty"`
+	ProviderReference string            `json:"provider_reference,omitempty"`
+	Withdrawal        *Withdrawal       `json:"-"`
+	Settlement        *SettlementRecord `json:"settlement,omitempty"`
+	ExpectedAmount    float64           `json:"expected_amount,omitempty"`
+	SettledAmount     float64           `json:"settled_amount,omitempty"`
+	ExpectedStatus    WithdrawalStatus  `json:"expected_status,omitempty"`
+	SettledStatus     WithdrawalStatus  `json:"settled_status,omitempty"`
+	Fixed             bool              `json:"fixed,omitempty"`
+	FixError          string            `json:"fix_error,omitempty"`
+}
+
+type ReconciliationRun struct {
+	ID               uuid.UUID     `json:"id"`
+	TenantID         string        `json:"tenant_id"`
+	SourceFile       string        `json:"source_file"`
+	PeriodStart      time.Time     `json:"period_start"`
+	PeriodEnd        time.Time     `json:"period_end"`
+	Matched          int           `json:"matched"`
+	Missing          int           `json:"missing"`
+	Extra            int           `json:"extra"`
+	AmountMismatched int           `json:"amount_mismatched"`
+	StatusMismatched int           `json:"status_mismatched"`
+	Fixed            int           `json:"fixed"`
+	Discrepancies    []Discrepancy `json:"discrepancies"`
+	StartedAt        time.Time     `json:"started_at"`
+	FinishedAt       time.Time     `json:"finished_at"`
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RiskOutcome string
+
+const (
+	RiskAllow RiskOutcome = "allow"
+	// RiskHold creates the withdrawal in StatusAwaitingApproval for manual review.
+	RiskHold RiskOutcome = "hold"
+	RiskDeny RiskOutcome = "deny"
+)
+
+func (o RiskOutcome) Valid() bool {
+	return o == RiskAllow || o == RiskHold || o == RiskDeny
+}
+
+// severity orders outcomes so that the strictest one wins.
+func (o RiskOutcome) severity() int {
+	switch o {
+	case RiskDeny:
+		return 2
+	case RiskHold:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// StricterThan tells whether o overrides other when several rules fire.
+func (o RiskOutcome) StricterThan(other RiskOutcome) bool {
+	return o.severity() > other.severity()
+}
+
+// RiskInput is the withdrawal being assessed, before any money moves.
+type RiskInput struct {
+	TenantID    string
+	UserID      string
+	Amount      float64
+	Currency    string
+	Network     string
+	Destination string
+	Now         time.Time
+}
+
+// RiskDecision is the outcome of the risk checks; Rule and Reason are empty
+// when no rule fired.
+type RiskDecision struct {
+	Outcome RiskOutcome
+	Rule    string
+	Reason  string
+}
+
+// RiskDenial records a withdrawal that a risk rule denied. The withdrawal
+// itself is never created, so this is the only trace of the decision.
+type RiskDenial struct {
+	ID             uuid.UUID
+	TenantID       string
+	UserID         string
+	IdempotencyKey string
+	Amount         float64
+	Currency       string
+	Network        string
+	Destination    string
+	Rule           string
+	Reason         string
+	CreatedAt      time.Time
+}
+
+var ErrRiskDenied = errors.New("withdrawal denied by risk checks")
+
+// RiskDeniedError carries the rule that denied a withdrawal. It matches
+// ErrRiskDenied with errors.Is; the rule is for operators, not for the caller.
+type RiskDeniedError struct {
+	Rule   string
+	Reason string
+}
+
+func (e *RiskDeniedError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", ErrRiskDenied, e.Rule, e.Reason)
+}
+
+func (e *RiskDeniedError) Is(target error) bool {
+	return target == ErrRiskDenied
+}
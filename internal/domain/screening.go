@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ScreeningEntryType string
+
+const (
+	// ScreeningAddress entries are matched against withdrawal destinations.
+	ScreeningAddress ScreeningEntryType = "address"
+	// ScreeningUser entries are matched against user IDs.
+	ScreeningUser ScreeningEntryType = "user"
+)
+
+// ScreeningEntry is one line of a deny-list. Entity and Reference describe who
+// the entry belongs to and where compliance got it from.
+type ScreeningEntry struct {
+	Type      ScreeningEntryType `json:"type"`
+	Value     string             `json:"value"`
+	Entity    string             `json:"entity,omitempty"`
+	Reference string             `json:"reference,omitempty"`
+}
+
+// ScreeningListVersion identifies the content of a loaded deny-list.
+type ScreeningListVersion struct {
+	Name     string    `json:"name"`
+	Version  string    `json:"version"`
+	Entries  int       `json:"entries"`
+	LoadedAt time.Time `json:"loaded_at"`
+}
+
+// ScreeningHit records which list entry blocked a withdrawal.
+type ScreeningHit struct {
+	ID             uuid.UUID
+	TenantID       string
+	UserID         string
+	IdempotencyKey string
+	Currency       string
+	Network        string
+	Destination    string
+	List           string
+	ListVersion    string
+	Entry          ScreeningEntry
+	CreatedAt      time.Time
+}
+
+var ErrScreeningHit = errors.New("withdrawal blocked by compliance screening")
+
+// ScreeningHitError matches ErrScreeningHit with errors.Is.
+type ScreeningHitError struct {
+	Hit *ScreeningHit
+}
+
+func (e *ScreeningHitError) Error() string {
+	return fmt.Sprintf("%s: %s %q on list %s version %s", ErrScreeningHit, e.Hit.Entry.Type, e.Hit.Entry.Value, e.Hit.List, e.Hit.ListVersion)
+}
+
+func (e *ScreeningHitError) Is(target error) bool {
+	return target == ErrScreeningHit
+}
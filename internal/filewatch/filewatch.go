@@ -0,0 +1,62 @@
+package filewatch
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const DefaultDebounce = 200 * time.Millisecond
+
+// Watch calls onChange after any of paths is written, created, renamed or
+// removed, until ctx is cancelled. The parent directories are watched rather
+// than the files themselves so that atomic replaces (write to a temp file and
+// rename, or the Kubernetes ..data symlink swap) are noticed as well. Bursts of
+// events are collapsed into one call after debounce.
+func Watch(ctx context.Context, paths []string, debounce time.Duration, onChange func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	dirs := make(map[string]bool)
+	for _, p := range paths {
+		dir := filepath.Dir(filepath.Clean(p))
+		if dirs[dir] {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			return err
+		}
+		dirs[dir] = true
+	}
+
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			timer.Reset(debounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		case <-timer.C:
+			onChange()
+		}
+	}
+}
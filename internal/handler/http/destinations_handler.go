@@ -0,0 +1,138 @@
+package http
+
+import (
+	"encoding/json"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// DestinationsHandler serves users' address books under /v1/destinations.
+type DestinationsHandler struct {
+	responder
+	service  port.DestinationService
+	validate *validator.Validate
+}
+
+func NewDestinationsHandler(service port.DestinationService) *DestinationsHandler {
+	return &DestinationsHandler{
+		responder: responder{logger: slog.Default()},
+		service:   service,
+		validate:  newValidator(),
+	}
+}
+
+func (h *DestinationsHandler) WithLogger(logger *slog.Logger) *DestinationsHandler {
+	h.logger = logger
+	return h
+}
+
+// AddDestination serves POST /v1/destinations.
+func (h *DestinationsHandler) AddDestination(w http.ResponseWriter, r *http.Request) {
+	var req domain.DestinationReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidRequestBody, "invalid request body")
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		h.respondInvalid(w, r, err)
+		return
+	}
+
+	destination, err := h.service.AddDestination(r.Context(), &req)
+	if err != nil {
+		h.respondProblem(w, r, err)
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "destination added", "destination_id", destination.ID, "user_id", req.UserID, "usable_at", destination.UsableAt)
+	h.respondJSON(w, newDestinationResponse(destination), http.StatusCreated)
+}
+
+// ListDestinations serves GET /v1/destinations?user_id=.
+func (h *DestinationsHandler) ListDestinations(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidParameter, "user_id is required")
+		return
+	}
+	if domain.IsReservedUserID(userID) {
+		h.respondProblem(w, r, domain.ErrReservedUserID)
+		return
+	}
+
+	destinations, err := h.service.ListDestinations(r.Context(), userID)
+	if err != nil {
+		h.respondProblem(w, r, err)
+		return
+	}
+	h.respondJSON(w, newDestinationListResponse(destinations), http.StatusOK)
+}
+
+// DeleteDestination serves DELETE /v1/destinations/{id}.
+func (h *DestinationsHandler) DeleteDestination(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidParameter, "invalid destination id")
+		return
+	}
+
+	if err := h.service.DeleteDestination(r.Context(), id); err != nil {
+		h.respondProblem(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSettings serves GET /v1/destinations/settings?user_id=.
+func (h *DestinationsHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidParameter, "user_id is required")
+		return
+	}
+	if domain.IsReservedUserID(userID) {
+		h.respondProblem(w, r, domain.ErrReservedUserID)
+		return
+	}
+
+	settings, err := h.service.GetSettings(r.Context(), userID)
+	if err != nil {
+		h.respondProblem(w, r, err)
+		return
+	}
+	h.respondJSON(w, newDestinationSettingsResponse(settings), http.StatusOK)
+}
+
+type destinationSettingsReq struct {
+	UserID        string `json:"user_id" validate:"required,notreserved"`
+	AllowListOnly bool   `json:"allow_list_only"`
+}
+
+// SetSettings serves PUT /v1/destinations/settings.
+func (h *DestinationsHandler) SetSettings(w http.ResponseWriter, r *http.Request) {
+	var req destinationSettingsReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidRequestBody, "invalid request body")
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		h.respondInvalid(w, r, err)
+		return
+	}
+
+	settings, err := h.service.SetAllowListOnly(r.Context(), req.UserID, req.AllowListOnly)
+	if err != nil {
+		h.respondProblem(w, r, err)
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "allow-list mode changed", "user_id", req.UserID, "allow_list_only", req.AllowListOnly)
+	h.respondJSON(w, newDestinationSettingsResponse(settings), http.StatusOK)
+}
@@ -0,0 +1,192 @@
+package http
+
+import (
+	"time"
+
+	"idempot/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+// Response bodies are declared here rather than taken from domain, so that a
+// change to a domain struct cannot silently change the API. Every type is
+// described under the same name in openapi.json.
+
+type withdrawalResponse struct {
+	ID                uuid.UUID `json:"id"`
+	UserID            string    `json:"user_id"`
+	Amount            float64   `json:"amount"`
+	Fee               float64   `json:"fee"`
+	NetAmount         float64   `json:"net_amount"`
+	Currency          string    `json:"currency"`
+	Network           string    `json:"network,omitempty"`
+	Destination       string    `json:"destination"`
+	IdempotencyKey    string    `json:"idempotency_key"`
+	Status            string    `json:"status"`
+	ProviderReference string    `json:"provider_reference,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// newWithdrawalResponse leaves out the tenant and the risk rule that applied:
+// telling the caller the rule would help them get around it.
+func newWithdrawalResponse(w *domain.Withdrawal) withdrawalResponse {
+	return withdrawalResponse{
+		ID:                w.ID,
+		UserID:            w.UserID,
+		Amount:            w.Amount,
+		Fee:               w.Fee,
+		NetAmount:         w.Payout(),
+		Currency:          w.Currency,
+		Network:           w.Network,
+		Destination:       w.Destination,
+		IdempotencyKey:    w.IdempotencyKey,
+		Status:            string(w.Status),
+		ProviderReference: w.ProviderReference,
+		CreatedAt:         w.CreatedAt,
+		UpdatedAt:         w.UpdatedAt,
+	}
+}
+
+type quoteResponse struct {
+	Amount    float64 `json:"amount"`
+	Fee       float64 `json:"fee"`
+	NetAmount float64 `json:"net_amount"`
+	Currency  string  `json:"currency"`
+	Network   string  `json:"network,omitempty"`
+}
+
+func newQuoteResponse(q *domain.FeeQuote) quoteResponse {
+	return quoteResponse{
+		Amount:    q.Amount,
+		Fee:       q.Fee,
+		NetAmount: q.NetAmount,
+		Currency:  q.Currency,
+		Network:   q.Network,
+	}
+}
+
+type approvalProgressResponse struct {
+	WithdrawalID uuid.UUID `json:"withdrawal_id"`
+	Status       string    `json:"status"`
+	Approvals    int       `json:"approvals"`
+	Required     int       `json:"required"`
+}
+
+func newApprovalProgressResponse(p *domain.ApprovalProgress) approvalProgressResponse {
+	return approvalProgressResponse{
+		WithdrawalID: p.WithdrawalID,
+		Status:       string(p.Status),
+		Approvals:    p.Approvals,
+		Required:     p.Required,
+	}
+}
+
+// withdrawalEventResponse is the data of a "status" Server-Sent Event.
+type withdrawalEventResponse struct {
+	ID             uint64    `json:"id"`
+	WithdrawalID   uuid.UUID `json:"withdrawal_id"`
+	UserID         string    `json:"user_id"`
+	Status         string    `json:"status"`
+	PreviousStatus string    `json:"previous_status"`
+	OccurredAt     time.Time `json:"occurred_at"`
+}
+
+func newWithdrawalEventResponse(e domain.WithdrawalEvent) withdrawalEventResponse {
+	return withdrawalEventResponse{
+		ID:             e.ID,
+		WithdrawalID:   e.WithdrawalID,
+		UserID:         e.UserID,
+		Status:         string(e.Status),
+		PreviousStatus: string(e.PreviousStatus),
+		OccurredAt:     e.OccurredAt,
+	}
+}
+
+type destinationResponse struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    string    `json:"user_id"`
+	Currency  string    `json:"currency"`
+	Network   string    `json:"network"`
+	Address   string    `json:"address"`
+	Label     string    `json:"label,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UsableAt  time.Time `json:"usable_at"`
+}
+
+func newDestinationResponse(d *domain.Destination) destinationResponse {
+	return destinationResponse{
+		ID:        d.ID,
+		UserID:    d.UserID,
+		Currency:  d.Currency,
+		Network:   d.Network,
+		Address:   d.Address,
+		Label:     d.Label,
+		CreatedAt: d.CreatedAt,
+		UsableAt:  d.UsableAt,
+	}
+}
+
+func newDestinationListResponse(destinations []*domain.Destination) []destinationResponse {
+	list := make([]destinationResponse, 0, len(destinations))
+	for _, d := range destinations {
+		list = append(list, newDestinationResponse(d))
+	}
+	return list
+}
+
+type destinationSettingsResponse struct {
+	UserID        string     `json:"user_id"`
+	AllowListOnly bool       `json:"allow_list_only"`
+	EnforcedUntil *time.Time `json:"enforced_until,omitempty"`
+}
+
+func newDestinationSettingsResponse(s *domain.DestinationSettings) destinationSettingsResponse {
+	return destinationSettingsResponse{
+		UserID:        s.UserID,
+		AllowListOnly: s.AllowListOnly,
+		EnforcedUntil: s.EnforcedUntil,
+	}
+}
+
+type limitsResponse struct {
+	Currency          string  `json:"currency"`
+	MinPerTransaction float64 `json:"min_per_transaction"`
+	MaxPerTransaction float64 `json:"max_per_transaction"`
+	Daily             float64 `json:"daily"`
+	Monthly           float64 `json:"monthly"`
+}
+
+func newLimitsResponse(l *domain.WithdrawalLimits) limitsResponse {
+	return limitsResponse{
+		Currency:          l.Currency,
+		MinPerTransaction: l.MinPerTransaction,
+		MaxPerTransaction: l.MaxPerTransaction,
+		Daily:             l.Daily,
+		Monthly:           l.Monthly,
+	}
+}
+
+type screeningListResponse struct {
+	Name     string    `json:"name"`
+	Version  string    `json:"version"`
+	Entries  int       `json:"entries"`
+	LoadedAt time.Time `json:"loaded_at"`
+}
+
+func newScreeningListsResponse(versions []domain.ScreeningListVersion) []screeningListResponse {
+	lists := make([]screeningListResponse, 0, len(versions))
+	for _, v := range versions {
+		lists = append(lists, screeningListResponse{
+			Name:     v.Name,
+			Version:  v.Version,
+			Entries:  v.Entries,
+			LoadedAt: v.LoadedAt,
+		})
+	}
+	return lists
+}
+
+type logLevelResponse struct {
+	Level string `json:"level"`
+}
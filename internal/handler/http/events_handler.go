@@ -0,0 +1,175 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const defaultHeartbeatInterval = 15 * time.Second
+
+// EventsHandler streams withdrawal status transitions as Server-Sent Events.
+type EventsHandler struct {
+	responder
+	service   port.WithdrawalService
+	events    port.WithdrawalEventSubscriber
+	heartbeat time.Duration
+}
+
+func NewEventsHandler(service port.WithdrawalService, events port.WithdrawalEventSubscriber, heartbeat time.Duration) *EventsHandler {
+	if heartbeat <= 0 {
+		heartbeat = defaultHeartbeatInterval
+	}
+	return &EventsHandler{
+		responder: responder{logger: slog.Default()},
+		service:   service,
+		events:    events,
+		heartbeat: heartbeat,
+	}
+}
+
+func (h *EventsHandler) WithLogger(logger *slog.Logger) *EventsHandler {
+	h.logger = logger
+	return h
+}
+
+// StreamWithdrawal serves GET /v1/withdrawals/{id}/events.
+func (h *EventsHandler) StreamWithdrawal(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		h.logger.InfoContext(r.Context(), "invalid withdrawal ID for event stream", "withdrawal_id", idStr)
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidParameter, "invalid withdrawal id")
+		return
+	}
+
+	if _, err := h.service.GetWithdrawal(r.Context(), id); err != nil {
+		h.respondProblem(w, r, err)
+		return
+	}
+
+	h.stream(w, r, domain.WithdrawalEventFilter{TenantID: domain.TenantFromContext(r.Context()), WithdrawalID: id})
+}
+
+// StreamUserWithdrawals serves GET /v1/withdrawals/events?user_id=.
+func (h *EventsHandler) StreamUserWithdrawals(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	principal, authenticated := domain.PrincipalFromContext(r.Context())
+	if userID == "" && authenticated && principal.Kind == domain.PrincipalUser {
+		userID = principal.Subject
+	}
+	if userID == "" {
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidParameter, "user_id is required")
+		return
+	}
+	if authenticated && !principal.OwnsUser(userID) {
+		h.respondProblem(w, r, domain.ErrForbidden)
+		return
+	}
+
+	// The same user_id may exist under several tenants
+	h.stream(w, r, domain.WithdrawalEventFilter{TenantID: domain.TenantFromContext(r.Context()), UserID: userID})
+}
+
+func (h *EventsHandler) stream(w http.ResponseWriter, r *http.Request, filter domain.WithdrawalEventFilter) {
+	// An empty tenant would match the events of every tenant
+	if filter.TenantID == "" {
+		h.respondProblem(w, r, domain.ErrMissingTenant)
+		return
+	}
+
+	lastEventID, err := parseLastEventID(r)
+	if err != nil {
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidParameter, "invalid Last-Event-ID")
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// The server WriteTimeout would otherwise cut the stream off
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		h.logger.WarnContext(r.Context(), "failed to clear write deadline", "error", err)
+	}
+
+	replay, resetID, events, cancel := h.events.Subscribe(filter, lastEventID)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", h.heartbeat.Milliseconds()); err != nil {
+		return
+	}
+	if resetID != 0 {
+		// Events are kept in memory, per process: after a restart or on another
+		// replica the ones the client missed are gone, so it must reload
+		h.logger.InfoContext(r.Context(), "event stream cannot resume, sending reset", "last_event_id", lastEventID)
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: reset\ndata: {}\n\n", resetID); err != nil {
+			return
+		}
+	}
+	for _, e := range replay {
+		if err := writeEvent(w, e); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		h.logger.ErrorContext(r.Context(), "streaming not supported", "error", err)
+		return
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e domain.WithdrawalEvent) error {
+	data, err := json.Marshal(newWithdrawalEventResponse(e))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: status\ndata: %s\n\n", e.ID, data)
+	return err
+}
+
+func parseLastEventID(r *http.Request) (uint64, error) {
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = r.URL.Query().Get("last_event_id")
+	}
+	if raw == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(raw, 10, 64)
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"encoding/json"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+// LimitsHandler lets administrators inspect and override per-user withdrawal limits.
+type LimitsHandler struct {
+	responder
+	service  port.LimitService
+	validate *validator.Validate
+}
+
+func NewLimitsHandler(service port.LimitService) *LimitsHandler {
+	return &LimitsHandler{
+		responder: responder{logger: slog.Default()},
+		service:   service,
+		validate:  newValidator(),
+	}
+}
+
+func (h *LimitsHandler) WithLogger(logger *slog.Logger) *LimitsHandler {
+	h.logger = logger
+	return h
+}
+
+type setLimitsReq struct {
+	Currency          string  `json:"currency" validate:"required"`
+	MinPerTransaction float64 `json:"min_per_transaction" validate:"gte=0"`
+	MaxPerTransaction float64 `json:"max_per_transaction" validate:"gte=0"`
+	Daily             float64 `json:"daily" validate:"gte=0"`
+	Monthly           float64 `json:"monthly" validate:"gte=0"`
+}
+
+// GetLimits serves GET /v1/admin/limits/{user_id}?currency=.
+func (h *LimitsHandler) GetLimits(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "user_id")
+	if domain.IsReservedUserID(userID) {
+		h.respondProblem(w, r, domain.ErrReservedUserID)
+		return
+	}
+	currency := r.URL.Query().Get("currency")
+	if currency == "" {
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidParameter, "currency is required")
+		return
+	}
+
+	limits, err := h.service.GetLimits(r.Context(), userID, currency)
+	if err != nil {
+		h.respondProblem(w, r, err)
+		return
+	}
+	h.respondJSON(w, newLimitsResponse(limits), http.StatusOK)
+}
+
+// SetLimits serves PUT /v1/admin/limits/{user_id}.
+func (h *LimitsHandler) SetLimits(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "user_id")
+	if domain.IsReservedUserID(userID) {
+		h.respondProblem(w, r, domain.ErrReservedUserID)
+		return
+	}
+
+	var req setLimitsReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidRequestBody, "invalid request body")
+		return
+	}
+	if err := h.validate.Struct(req); err != nil {
+		h.respondInvalid(w, r, err)
+		return
+	}
+	if req.MinPerTransaction > 0 && req.MaxPerTransaction > 0 && req.MinPerTransaction > req.MaxPerTransaction {
+		h.respondProblem(w, r, domain.ErrInvertedLimits)
+		return
+	}
+
+	limits := &domain.WithdrawalLimits{
+		Currency:          req.Currency,
+		MinPerTransaction: req.MinPerTransaction,
+		MaxPerTransaction: req.MaxPerTransaction,
+		Daily:             req.Daily,
+		Monthly:           req.Monthly,
+	}
+	if err := h.service.SetUserLimits(r.Context(), userID, limits); err != nil {
+		// The change is audited under the user's balance lock, which a withdrawal
+		// may hold, so a lock timeout is answered with 429
+		h.respondProblem(w, r, err)
+		return
+	}
+
+	h.logger.InfoContext(r.Context(), "limits changed", "user_id", userID, "currency", req.Currency)
+	h.respondJSON(w, newLimitsResponse(limits), http.StatusOK)
+}
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"encoding/json"
+	"idempot/internal/logging"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// LogLevelHandler lets administrators change the log level without a restart.
+type LogLevelHandler struct {
+	responder
+	level *slog.LevelVar
+}
+
+func NewLogLevelHandler(level *slog.LevelVar) *LogLevelHandler {
+	return &LogLevelHandler{
+		responder: responder{logger: slog.Default()},
+		level:     level,
+	}
+}
+
+func (h *LogLevelHandler) WithLogger(logger *slog.Logger) *LogLevelHandler {
+	h.logger = logger
+	return h
+}
+
+type logLevelReq struct {
+	Level string `json:"level"`
+}
+
+// GetLevel serves GET /v1/admin/log-level.
+func (h *LogLevelHandler) GetLevel(w http.ResponseWriter, r *http.Request) {
+	h.respondJSON(w, logLevelResponse{Level: strings.ToLower(h.level.Level().String())}, http.StatusOK)
+}
+
+// SetLevel serves PUT /v1/admin/log-level.
+func (h *LogLevelHandler) SetLevel(w http.ResponseWriter, r *http.Request) {
+	var req logLevelReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, r, http.StatusBadRequest, CodeInvalidRequestBody, "invalid request body")
+		return
+	}
+	level, err := logging.ParseLevel(req.Level)
+	if err != nil || req.Level == "" {
+		h.respondError(w, r, http.StatusBadRequest, CodeValidationFailed, "level must be one of debug, info, warn, error")
+		return
+	}
+
+	previous := h.level.Level()
+	h.level.Set(level)
+	h.logger.WarnContext(r.Context(), "log level changed", "from", previous, "to", level)
+	h.respondJSON(w, logLevelResponse{Level: strings.ToLower(level.String())}, http.StatusOK)
+}
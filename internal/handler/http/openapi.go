@@ -0,0 +1,19 @@
+package http
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec documents every /v1 route. Responses are checked against it in
+// openapi_test.go, so a handler change that breaks the contract fails the tests.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// OpenAPI serves GET /openapi.json.
+func OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(openAPISpec)
+}
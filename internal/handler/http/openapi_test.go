@@ -0,0 +1,340 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"idempot/internal/domain"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	knownWithdrawalID  = uuid.MustParse("6f1c2a8e-4b7d-4e1a-9c3f-2d5e8a7b6c10")
+	knownDestinationID = uuid.MustParse("0b9e7d6c-5a4f-4e3d-8c2b-1a0f9e8d7c6b")
+)
+
+type fakeWithdrawalService struct {
+	createErr error
+	decideErr error
+}
+
+func (s *fakeWithdrawalService) withdrawal(id uuid.UUID) *domain.Withdrawal {
+	now := time.Now().UTC()
+	return &domain.Withdrawal{
+		ID: id, TenantID: domain.DefaultTenant, UserID: "user-1",
+		Amount: 100, Fee: 1, NetAmount: 99, Currency: "USDT", Network: "TRON",
+		Destination: "TXYZ", IdempotencyKey: "key-1", Status: domain.StatusPending,
+		RiskDecision: domain.RiskAllow, RiskRule: "velocity",
+		CreatedAt: now, UpdatedAt: now,
+	}
+}
+
+func (s *fakeWithdrawalService) CreateWithdrawal(ctx context.Context, req *domain.WithdrawalReq) (*domain.Withdrawal, error) {
+	if s.createErr != nil {
+		return nil, s.createErr
+	}
+	return s.withdrawal(knownWithdrawalID), nil
+}
+
+func (s *fakeWithdrawalService) GetWithdrawal(ctx context.Context, id uuid.UUID) (*domain.Withdrawal, error) {
+	if id != knownWithdrawalID {
+		return nil, domain.ErrWithdrawalNotFound
+	}
+	return s.withdrawal(id), nil
+}
+
+func (s *fakeWithdrawalService) ConfirmWithdrawal(ctx context.Context, id uuid.UUID) error {
+	return nil
+}
+
+func (s *fakeWithdrawalService) FailWithdrawal(ctx context.Context, id uuid.UUID, reason string) error {
+	return domain.ErrStatusConflict
+}
+
+func (s *fakeWithdrawalService) CancelWithdrawal(ctx context.Context, id uuid.UUID, reason string) error {
+	if id != knownWithdrawalID {
+		return domain.ErrWithdrawalNotFound
+	}
+	return nil
+}
+
+func (s *fakeWithdrawalService) ApproveWithdrawal(ctx context.Context, id uuid.UUID, reason string) (*domain.ApprovalProgress, error) {
+	return &domain.ApprovalProgress{WithdrawalID: id, Status: domain.StatusAwaitingApproval, Approvals: 1, Required: 2}, nil
+}
+
+func (s *fakeWithdrawalService) RejectWithdrawal(ctx context.Context, id uuid.UUID, reason string) (*domain.ApprovalProgress, error) {
+	return nil, s.decideErr
+}
+
+func (s *fakeWithdrawalService) QuoteWithdrawal(ctx context.Context, req *domain.QuoteReq) (*domain.FeeQuote, error) {
+	return &domain.FeeQuote{Amount: req.Amount, Fee: 1, NetAmount: req.Amount - 1, Currency: req.Currency, Network: req.Network}, nil
+}
+
+type fakeDestinationService struct{}
+
+func (fakeDestinationService) Check(ctx context.Context, tenantID, userID, currency, network, address string) error {
+	return nil
+}
+
+func (fakeDestinationService) AddDestination(ctx context.Context, req *domain.DestinationReq) (*domain.Destination, error) {
+	if req.Address == "TDUP" {
+		return nil, domain.ErrDestinationExists
+	}
+	now := time.Now().UTC()
+	return &domain.Destination{
+		ID: knownDestinationID, UserID: req.UserID, Currency: req.Currency, Network: req.Network,
+		Address: req.Address, Label: req.Label, CreatedAt: now, UsableAt: now.Add(24 * time.Hour),
+	}, nil
+}
+
+func (s fakeDestinationService) ListDestinations(ctx context.Context, userID string) ([]*domain.Destination, error) {
+	d, _ := s.AddDestination(ctx, &domain.DestinationReq{UserID: userID, Currency: "USDT", Network: "TRON", Address: "TXYZ"})
+	return []*domain.Destination{d}, nil
+}
+
+func (fakeDestinationService) DeleteDestination(ctx context.Context, id uuid.UUID) error {
+	if id != knownDestinationID {
+		return domain.ErrDestinationNotFound
+	}
+	return nil
+}
+
+func (fakeDestinationService) GetSettings(ctx context.Context, userID string) (*domain.DestinationSettings, error) {
+	return &domain.DestinationSettings{UserID: userID, AllowListOnly: true}, nil
+}
+
+func (fakeDestinationService) SetAllowListOnly(ctx context.Context, userID string, enabled bool) (*domain.DestinationSettings, error) {
+	until := time.Now().UTC().Add(24 * time.Hour)
+	return &domain.DestinationSettings{UserID: userID, AllowListOnly: enabled, EnforcedUntil: &until}, nil
+}
+
+type fakeLimitService struct{}
+
+func (fakeLimitService) Check(ctx context.Context, tenantID, userID, currency string, amount float64) error {
+	return nil
+}
+
+func (fakeLimitService) GetLimits(ctx context.Context, userID, currency string) (*domain.WithdrawalLimits, error) {
+	return &domain.WithdrawalLimits{Currency: currency, MaxPerTransaction: 5000, Daily: 10000}, nil
+}
+
+func (fakeLimitService) SetUserLimits(ctx context.Context, userID string, limits *domain.WithdrawalLimits) error {
+	return nil
+}
+
+type fakeScreener struct{}
+
+func (fakeScreener) Screen(userID, destination string) *domain.ScreeningHit { return nil }
+
+func (fakeScreener) Versions() []domain.ScreeningListVersion {
+	return []domain.ScreeningListVersion{{Name: "ofac", Version: "2026-10-01", Entries: 1200, LoadedAt: time.Now().UTC()}}
+}
+
+// newContractRouter serves the routes of cmd/api, with an admin caller
+// already authenticated.
+func newContractRouter(withdrawals *fakeWithdrawalService) http.Handler {
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	RegisterRoutes(r, Handlers{
+		Withdrawals:  NewWithdrawalHandler(withdrawals, ""),
+		Destinations: NewDestinationsHandler(fakeDestinationService{}),
+		Limits:       NewLimitsHandler(fakeLimitService{}),
+		Screening:    NewScreeningHandler(fakeScreener{}),
+		LogLevel:     NewLogLevelHandler(new(slog.LevelVar)),
+		Events:       NewEventsHandler(withdrawals, nil, time.Second),
+	}, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, withPrincipal(r, &domain.Principal{
+				Subject: "ops", Kind: domain.PrincipalService, TenantID: domain.DefaultTenant,
+				Roles: []domain.Role{domain.RoleAdmin}, Scopes: []domain.Scope{domain.ScopeAdmin},
+			}))
+		})
+	})
+	return r
+}
+
+func loadSpec(t *testing.T) (*openapi3.T, routers.Router) {
+	t.Helper()
+	doc, err := openapi3.NewLoader().LoadFromData(openAPISpec)
+	require.NoError(t, err)
+	require.NoError(t, doc.Validate(context.Background()))
+
+	router, err := gorillamux.NewRouter(doc)
+	require.NoError(t, err)
+	return doc, router
+}
+
+func TestOpenAPI_SpecIsValid(t *testing.T) {
+	loadSpec(t)
+}
+
+func TestOpenAPI_ServesSpec(t *testing.T) {
+	rec := httptest.NewRecorder()
+	OpenAPI(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, string(openAPISpec), rec.Body.String())
+}
+
+// TestOpenAPI_ResponsesMatchSpec sends requests to the real handlers and checks
+// every response, success or problem, against the schema. Every documented
+// operation must be exercised, so a route cannot be added to the spec alone.
+func TestOpenAPI_ResponsesMatchSpec(t *testing.T) {
+	doc, router := loadSpec(t)
+	withdrawals := &fakeWithdrawalService{}
+	handler := newContractRouter(withdrawals)
+
+	id := knownWithdrawalID.String()
+	unknown := uuid.NewString()
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		setup  func()
+		status int
+		// malformed requests are expected to break the spec; only the response is checked
+		malformed bool
+	}{
+		{name: "create", method: http.MethodPost, path: "/v1/withdrawals", status: http.StatusCreated,
+			body: `{"user_id":"user-1","amount":100,"currency":"USDT","network":"TRON","destination":"TXYZ","idempotency_key":"key-1"}`},
+		{name: "create with invalid fields", method: http.MethodPost, path: "/v1/withdrawals", status: http.StatusBadRequest,
+			body: `{"user_id":"user-1","amount":-1}`, malformed: true},
+		{name: "create with malformed body", method: http.MethodPost, path: "/v1/withdrawals", status: http.StatusBadRequest,
+			body: `{"amount":`, malformed: true},
+		{name: "create over balance", method: http.MethodPost, path: "/v1/withdrawals", status: http.StatusConflict,
+			body: `{"user_id":"user-1","amount":100,"currency":"USDT","destination":"TXYZ","idempotency_key":"key-2"}`,
+			setup: func() {
+				withdrawals.createErr = &domain.InsufficientBalanceError{Currency: "USDT", Available: 40, Requested: 100}
+			}},
+		{name: "create over limit", method: http.MethodPost, path: "/v1/withdrawals", status: http.StatusUnprocessableEntity,
+			body: `{"user_id":"user-1","amount":100,"currency":"USDT","destination":"TXYZ","idempotency_key":"key-3"}`,
+			setup: func() {
+				withdrawals.createErr = &domain.LimitExceededError{Kind: domain.LimitDaily, Currency: "USDT", Limit: 50, Remaining: 10}
+			}},
+		{name: "create under lock", method: http.MethodPost, path: "/v1/withdrawals", status: http.StatusTooManyRequests,
+			body:  `{"user_id":"user-1","amount":100,"currency":"USDT","destination":"TXYZ","idempotency_key":"key-4"}`,
+			setup: func() { withdrawals.createErr = domain.ErrLockTimeout }},
+		{name: "quote", method: http.MethodPost, path: "/v1/withdrawals/quote", status: http.StatusOK,
+			body: `{"amount":100,"currency":"USDT","network":"TRON"}`},
+		{name: "get", method: http.MethodGet, path: "/v1/withdrawals/" + id, status: http.StatusOK},
+		{name: "get unknown", method: http.MethodGet, path: "/v1/withdrawals/" + unknown, status: http.StatusNotFound},
+		{name: "get with invalid id", method: http.MethodGet, path: "/v1/withdrawals/not-a-uuid", status: http.StatusBadRequest, malformed: true},
+		{name: "confirm", method: http.MethodPost, path: "/v1/withdrawals/" + id + "/confirm", status: http.StatusOK},
+		{name: "fail a settled withdrawal", method: http.MethodPost, path: "/v1/withdrawals/" + id + "/fail", status: http.StatusConflict,
+			body: `{"reason":"provider rejected"}`},
+		{name: "cancel unknown", method: http.MethodPost, path: "/v1/withdrawals/" + unknown + "/cancel", status: http.StatusNotFound},
+		{name: "approve", method: http.MethodPost, path: "/v1/withdrawals/" + id + "/approve", status: http.StatusOK,
+			body: `{"reason":"checked with the customer"}`},
+		{name: "reject own withdrawal", method: http.MethodPost, path: "/v1/withdrawals/" + id + "/reject", status: http.StatusForbidden,
+			setup: func() { withdrawals.decideErr = domain.ErrSelfApproval }},
+		{name: "user events without user", method: http.MethodGet, path: "/v1/withdrawals/events", status: http.StatusBadRequest},
+		{name: "events of unknown withdrawal", method: http.MethodGet, path: "/v1/withdrawals/" + unknown + "/events", status: http.StatusNotFound},
+		{name: "add destination", method: http.MethodPost, path: "/v1/destinations", status: http.StatusCreated,
+			body: `{"user_id":"user-1","currency":"USDT","network":"TRON","address":"TXYZ","label":"cold wallet"}`},
+		{name: "add known destination", method: http.MethodPost, path: "/v1/destinations", status: http.StatusConflict,
+			body: `{"user_id":"user-1","currency":"USDT","address":"TDUP"}`},
+		{name: "list destinations", method: http.MethodGet, path: "/v1/destinations?user_id=user-1", status: http.StatusOK},
+		{name: "delete destination", method: http.MethodDelete, path: "/v1/destinations/" + knownDestinationID.String(), status: http.StatusNoContent},
+		{name: "delete unknown destination", method: http.MethodDelete, path: "/v1/destinations/" + unknown, status: http.StatusNotFound},
+		{name: "get settings", method: http.MethodGet, path: "/v1/destinations/settings?user_id=user-1", status: http.StatusOK},
+		{name: "set settings", method: http.MethodPut, path: "/v1/destinations/settings", status: http.StatusOK,
+			body: `{"user_id":"user-1","allow_list_only":false}`},
+		{name: "get limits", method: http.MethodGet, path: "/v1/admin/limits/user-1?currency=USDT", status: http.StatusOK},
+		{name: "set limits", method: http.MethodPut, path: "/v1/admin/limits/user-1", status: http.StatusOK,
+			body: `{"currency":"USDT","max_per_transaction":1000,"daily":5000}`},
+		{name: "set inverted limits", method: http.MethodPut, path: "/v1/admin/limits/user-1", status: http.StatusBadRequest,
+			body: `{"currency":"USDT","min_per_transaction":500,"max_per_transaction":100}`},
+		{name: "screening lists", method: http.MethodGet, path: "/v1/admin/screening/lists", status: http.StatusOK},
+		{name: "get log level", method: http.MethodGet, path: "/v1/admin/log-level", status: http.StatusOK},
+		{name: "set log level", method: http.MethodPut, path: "/v1/admin/log-level", status: http.StatusOK, body: `{"level":"debug"}`},
+	}
+
+	exercised := map[string]bool{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withdrawals.createErr, withdrawals.decideErr = nil, nil
+			if tt.setup != nil {
+				tt.setup()
+			}
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			route, pathParams, err := router.FindRoute(req)
+			require.NoError(t, err, "route is not documented")
+			exercised[tt.method+" "+route.Path] = true
+
+			input := &openapi3filter.RequestValidationInput{
+				Request:    req,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+			}
+			if !tt.malformed {
+				require.NoError(t, openapi3filter.ValidateRequest(context.Background(), input), "the test request breaks the spec")
+				req.Body = io.NopCloser(strings.NewReader(tt.body))
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			require.Equal(t, tt.status, rec.Code, rec.Body.String())
+
+			err = openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: input,
+				Status:                 rec.Code,
+				Header:                 rec.Header(),
+				Body:                   io.NopCloser(bytes.NewReader(rec.Body.Bytes())),
+				Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+			})
+			assert.NoError(t, err, rec.Body.String())
+		})
+	}
+
+	var missing []string
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			if !exercised[method+" "+path] {
+				missing = append(missing, method+" "+path)
+			}
+		}
+	}
+	sort.Strings(missing)
+	assert.Empty(t, missing, "documented operations without a contract test")
+}
+
+// TestOpenAPI_WithdrawalHidesInternals guards the fields the DTO leaves out.
+func TestOpenAPI_WithdrawalHidesInternals(t *testing.T) {
+	handler := newContractRouter(&fakeWithdrawalService{})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/withdrawals/"+knownWithdrawalID.String(), nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, knownWithdrawalID.String(), body["id"])
+	assert.Equal(t, "user-1", body["user_id"])
+	for _, hidden := range []string{"tenant_id", "risk_rule", "risk_reason", "TenantID", "RiskRule", "ID"} {
+		assert.NotContains(t, body, hidden)
+	}
+}
@@ -0,0 +1,272 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"idempot/internal/domain"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ProblemContentType is the media type of error responses (RFC 7807).
+const ProblemContentType = "application/problem+json"
+
+// Codes are part of the API: clients branch on them, so they never change
+// once published. Messages in detail may.
+const (
+	CodeInvalidRequestBody     = "invalid_request_body"
+	CodeValidationFailed       = "validation_failed"
+	CodeInvalidParameter       = "invalid_parameter"
+	CodeInternalError          = "internal_error"
+	CodeUnauthorized           = "unauthorized"
+	CodeForbidden              = "forbidden"
+	CodeWithdrawalNotFound     = "withdrawal_not_found"
+	CodeInsufficientBalance    = "insufficient_balance"
+	CodeIdempotencyKeyMismatch = "idempotency_key_mismatch"
+	CodeDuplicateRequest       = "duplicate_request"
+	CodeLockTimeout            = "lock_timeout"
+	CodeStatusConflict         = "status_conflict"
+	CodeInvalidDestination     = "invalid_destination"
+	CodeDestinationNotFound    = "destination_not_found"
+	CodeDestinationExists      = "destination_exists"
+	CodeDestinationNotAllowed  = "destination_not_allowed"
+	CodeScreeningHit           = "screening_hit"
+	CodeSelfApproval           = "self_approval"
+	CodeAlreadyDecided         = "already_decided"
+	CodeFeeExceedsAmount       = "fee_exceeds_amount"
+	CodeRiskDenied             = "risk_denied"
+	CodeLimitExceeded          = "limit_exceeded"
+	CodeReservedUserID         = "reserved_user_id"
+)
+
+// Problem is an RFC 7807 problem details object. Extensions are written as
+// top-level members next to the standard ones.
+type Problem struct {
+	Type       string         `json:"type"`
+	Title      string         `json:"title"`
+	Status     int            `json:"status"`
+	Detail     string         `json:"detail,omitempty"`
+	Instance   string         `json:"instance,omitempty"`
+	Code       string         `json:"code"`
+	RequestID  string         `json:"request_id,omitempty"`
+	Errors     []FieldError   `json:"errors,omitempty"`
+	Extensions map[string]any `json:"-"`
+}
+
+// FieldError describes one invalid field of a request body.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	type members Problem
+	body, err := json.Marshal(members(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return body, err
+	}
+
+	merged := make(map[string]json.RawMessage, len(p.Extensions)+8)
+	for name, value := range p.Extensions {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		merged[name] = raw
+	}
+	// Standard members win over an extension of the same name
+	var standard map[string]json.RawMessage
+	if err := json.Unmarshal(body, &standard); err != nil {
+		return nil, err
+	}
+	for name, raw := range standard {
+		merged[name] = raw
+	}
+	return json.Marshal(merged)
+}
+
+// errorMapping ties a domain error to its status and code.
+type errorMapping struct {
+	err    error
+	status int
+	code   string
+	// detail replaces the error's message when that must not reach the caller
+	detail string
+}
+
+// errorMappings is the one place that decides how a domain error is
+// answered. Entries are matched with errors.Is, in order.
+var errorMappings = []errorMapping{
+	{err: domain.ErrUnauthorized, status: http.StatusUnauthorized, code: CodeUnauthorized},
+	{err: domain.ErrForbidden, status: http.StatusForbidden, code: CodeForbidden},
+	{err: domain.ErrAPIKeyNotFound, status: http.StatusUnauthorized, code: CodeUnauthorized, detail: domain.ErrUnauthorized.Error()},
+	// Authenticated requests always carry a tenant, so this is a bug on our side
+	{err: domain.ErrMissingTenant, status: http.StatusInternalServerError, code: CodeInternalError, detail: "internal server error"},
+	{err: domain.ErrWithdrawalNotFound, status: http.StatusNotFound, code: CodeWithdrawalNotFound},
+	{err: domain.ErrInsufficientBalance, status: http.StatusConflict, code: CodeInsufficientBalance},
+	{err: domain.ErrIdempotencyKeyMismatch, status: http.StatusUnprocessableEntity, code: CodeIdempotencyKeyMismatch},
+	{err: domain.ErrDuplicateRequest, status: http.StatusConflict, code: CodeDuplicateRequest},
+	{err: domain.ErrLockTimeout, status: http.StatusTooManyRequests, code: CodeLockTimeout, detail: "too many concurrent requests"},
+	{err: domain.ErrStatusConflict, status: http.StatusConflict, code: CodeStatusConflict},
+	{err: domain.ErrInvalidDestination, status: http.StatusBadRequest, code: CodeInvalidDestination},
+	{err: domain.ErrDestinationNotFound, status: http.StatusNotFound, code: CodeDestinationNotFound},
+	{err: domain.ErrDestinationExists, status: http.StatusConflict, code: CodeDestinationExists},
+	{err: domain.ErrDestinationNotAllowed, status: http.StatusUnprocessableEntity, code: CodeDestinationNotAllowed},
+	// Details are in screening_hits; the caller only learns that it was blocked
+	{err: domain.ErrScreeningHit, status: http.StatusUnprocessableEntity, code: CodeScreeningHit, detail: domain.ErrScreeningHit.Error()},
+	{err: domain.ErrSelfApproval, status: http.StatusForbidden, code: CodeSelfApproval},
+	{err: domain.ErrAlreadyDecided, status: http.StatusConflict, code: CodeAlreadyDecided},
+	{err: domain.ErrFeeExceedsAmount, status: http.StatusUnprocessableEntity, code: CodeFeeExceedsAmount},
+	// The rule stays in the log: telling the caller would help them get around it
+	{err: domain.ErrRiskDenied, status: http.StatusUnprocessableEntity, code: CodeRiskDenied, detail: domain.ErrRiskDenied.Error()},
+	{err: domain.ErrLimitExceeded, status: http.StatusUnprocessableEntity, code: CodeLimitExceeded},
+	{err: domain.ErrInvertedLimits, status: http.StatusBadRequest, code: CodeValidationFailed},
+	{err: domain.ErrReservedUserID, status: http.StatusBadRequest, code: CodeReservedUserID},
+}
+
+// problemFor maps err to the problem answered for it. Unknown errors become
+// a 500 whose detail reveals nothing.
+func problemFor(err error) *Problem {
+	for _, m := range errorMappings {
+		if !errors.Is(err, m.err) {
+			continue
+		}
+		detail := m.detail
+		if detail == "" {
+			detail = matchedMessage(err, m.err)
+		}
+		return &Problem{Status: m.status, Code: m.code, Detail: detail, Extensions: problemExtensions(err)}
+	}
+	return &Problem{Status: http.StatusInternalServerError, Code: CodeInternalError, Detail: "internal server error"}
+}
+
+// matchedMessage returns the message of the error in err's chain that matched
+// target: the typed error's own, or the sentinel's. The context wrapped around
+// it on the way up names internals such as lock keys, which callers must not see.
+func matchedMessage(err, target error) string {
+	for err != target {
+		var next error
+		switch wrapper := err.(type) {
+		case interface{ Unwrap() error }:
+			next = wrapper.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, e := range wrapper.Unwrap() {
+				if errors.Is(e, target) {
+					next = e
+					break
+				}
+			}
+		}
+		if next == nil || !errors.Is(next, target) {
+			// err matches target by its own Is method
+			return err.Error()
+		}
+		err = next
+	}
+	return target.Error()
+}
+
+// problemExtensions exposes the fields of typed errors that callers act on.
+func problemExtensions(err error) map[string]any {
+	var balanceErr *domain.InsufficientBalanceError
+	if errors.As(err, &balanceErr) {
+		return map[string]any{
+			"currency":  balanceErr.Currency,
+			"available": balanceErr.Available,
+			"requested": balanceErr.Requested,
+		}
+	}
+
+	var mismatchErr *domain.IdempotencyKeyMismatchError
+	if errors.As(err, &mismatchErr) {
+		return map[string]any{"conflicting_fields": mismatchErr.Fields}
+	}
+
+	var limitErr *domain.LimitExceededError
+	if errors.As(err, &limitErr) {
+		return map[string]any{
+			"limit":     limitErr.Kind,
+			"currency":  limitErr.Currency,
+			"remaining": limitErr.Remaining,
+		}
+	}
+
+	var allowErr *domain.DestinationNotAllowedError
+	if errors.As(err, &allowErr) {
+		return map[string]any{
+			"address":   allowErr.Address,
+			"usable_at": allowErr.UsableAt,
+		}
+	}
+	return nil
+}
+
+// newValidator reports fields under their JSON names, as the caller sent them,
+// and knows the "notreserved" rule for user IDs.
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterValidation("notreserved", func(fl validator.FieldLevel) bool {
+		return !domain.IsReservedUserID(fl.Field().String())
+	})
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
+// fieldErrors turns a validation failure into one entry per field, with a
+// message written for API clients rather than the validator's Go-centric one.
+func fieldErrors(err error) []FieldError {
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return nil
+	}
+
+	fields := make([]FieldError, 0, len(invalid))
+	for _, fe := range invalid {
+		fields = append(fields, FieldError{
+			Field:   fieldPath(fe),
+			Rule:    fe.Tag(),
+			Message: fieldMessage(fe),
+		})
+	}
+	return fields
+}
+
+// fieldPath drops the Go type name from the namespace: "WithdrawalReq.amount" becomes "amount".
+func fieldPath(fe validator.FieldError) string {
+	if _, path, ok := strings.Cut(fe.Namespace(), "."); ok {
+		return path
+	}
+	return fe.Field()
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be at least " + fe.Param()
+	case "notreserved":
+		return "is reserved"
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters long", fe.Param())
+		}
+		return "must be at most " + fe.Param()
+	default:
+		return fmt.Sprintf("failed the %q rule", fe.Tag())
+	}
+}
@@ -0,0 +1,134 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"idempot/internal/domain"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveProblem(t *testing.T, handler http.HandlerFunc) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	middleware.RequestID(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/withdrawals", nil))
+	assert.Equal(t, ProblemContentType, rec.Header().Get("Content-Type"))
+
+	var body map[string]any
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	return rec, body
+}
+
+func TestRespondProblem_MapsWrappedDomainErrors(t *testing.T) {
+	h := &responder{logger: slog.Default()}
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{fmt.Errorf("create: %w", domain.ErrInsufficientBalance), http.StatusConflict, CodeInsufficientBalance},
+		{domain.ErrLockTimeout, http.StatusTooManyRequests, CodeLockTimeout},
+		{&domain.RiskDeniedError{Rule: "velocity", Reason: "10 withdrawals in an hour"}, http.StatusUnprocessableEntity, CodeRiskDenied},
+		{fmt.Errorf("connection reset"), http.StatusInternalServerError, CodeInternalError},
+	}
+
+	for _, tt := range tests {
+		rec, body := serveProblem(t, func(w http.ResponseWriter, r *http.Request) {
+			h.respondProblem(w, r, tt.err)
+		})
+		assert.Equal(t, tt.status, rec.Code, tt.code)
+		assert.Equal(t, float64(tt.status), body["status"])
+		assert.Equal(t, tt.code, body["code"])
+		assert.Equal(t, "/v1/withdrawals", body["instance"])
+		assert.NotEmpty(t, body["request_id"])
+	}
+
+	// The risk rule does not reach the caller
+	_, body := serveProblem(t, func(w http.ResponseWriter, r *http.Request) {
+		h.respondProblem(w, r, &domain.RiskDeniedError{Rule: "velocity", Reason: "10 withdrawals in an hour"})
+	})
+	assert.Equal(t, domain.ErrRiskDenied.Error(), body["detail"])
+}
+
+func TestRespondProblem_LimitExtensions(t *testing.T) {
+	h := &responder{logger: slog.Default()}
+	_, body := serveProblem(t, func(w http.ResponseWriter, r *http.Request) {
+		h.respondProblem(w, r, &domain.LimitExceededError{Kind: "daily", Currency: "USDT", Limit: 1000, Remaining: 250})
+	})
+
+	assert.Equal(t, CodeLimitExceeded, body["code"])
+	assert.Equal(t, "daily", body["limit"])
+	assert.Equal(t, "USDT", body["currency"])
+	assert.Equal(t, 250.0, body["remaining"])
+}
+
+func TestRespondProblem_TypedErrorsThroughWrapping(t *testing.T) {
+	h := &responder{logger: slog.Default()}
+	wrapped := fmt.Errorf("lock user-1: %w", &domain.InsufficientBalanceError{Currency: "USDT", Available: 40, Requested: 100})
+	rec, body := serveProblem(t, func(w http.ResponseWriter, r *http.Request) {
+		h.respondProblem(w, r, wrapped)
+	})
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, CodeInsufficientBalance, body["code"])
+	assert.Equal(t, 40.0, body["available"])
+	assert.Equal(t, 100.0, body["requested"])
+	// The typed error's own message, without the context it was wrapped in
+	assert.Equal(t, "insufficient balance: 40 USDT available, 100 requested", body["detail"])
+
+	_, body = serveProblem(t, func(w http.ResponseWriter, r *http.Request) {
+		h.respondProblem(w, r, fmt.Errorf("debit wallet 42: %w", domain.ErrInsufficientBalance))
+	})
+	assert.Equal(t, domain.ErrInsufficientBalance.Error(), body["detail"])
+
+	rec, body = serveProblem(t, func(w http.ResponseWriter, r *http.Request) {
+		h.respondProblem(w, r, &domain.IdempotencyKeyMismatchError{Fields: []string{"amount", "destination"}})
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, CodeIdempotencyKeyMismatch, body["code"])
+	assert.Equal(t, []any{"amount", "destination"}, body["conflicting_fields"])
+}
+
+func TestRespondInvalid_ReportsJSONFieldNames(t *testing.T) {
+	h := &responder{logger: slog.Default()}
+	err := newValidator().Struct(domain.WithdrawalReq{UserID: "user-1", Amount: -5, Currency: "USDT"})
+	require.Error(t, err)
+
+	rec, body := serveProblem(t, func(w http.ResponseWriter, r *http.Request) {
+		h.respondInvalid(w, r, err)
+	})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, CodeValidationFailed, body["code"])
+
+	fields := map[string]string{}
+	for _, e := range body["errors"].([]any) {
+		fe := e.(map[string]any)
+		fields[fe["field"].(string)] = fe["message"].(string)
+	}
+	assert.Equal(t, "must be greater than 0", fields["amount"])
+	assert.Equal(t, "is required", fields["idempotency_key"])
+	assert.Equal(t, "is required", fields["destination"])
+	for field := range fields {
+		assert.False(t, strings.ContainsAny(field[:1], "ABCDEFGHIJKLMNOPQRSTUVWXYZ"), "field %q is not a JSON name", field)
+	}
+}
+
+func TestValidator_RejectsReservedUserIDs(t *testing.T) {
+	err := newValidator().Struct(domain.WithdrawalReq{
+		UserID: "__house__", Amount: 10, Currency: "USDT", Destination: "0x123", IdempotencyKey: "key-1",
+	})
+	fields := fieldErrors(err)
+	require.Len(t, fields, 1)
+	assert.Equal(t, FieldError{Field: "user_id", Rule: "notreserved", Message: "is reserved"}, fields[0])
+
+	assert.NoError(t, newValidator().Struct(domain.DestinationReq{
+		UserID: "user-1", Currency: "USDT", Address: "0x123",
+	}))
+}
@@ -0,0 +1,29 @@
+package http
+
+import (
+	"idempot/internal/domain"
+	"net"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestMeta records the client IP and request ID for the audit log. It must
+// run after middleware.RealIP and middleware.RequestID.
+func RequestMeta(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := domain.WithRequestMeta(r.Context(), domain.RequestMeta{
+			ClientIP:  clientIP(r.RemoteAddr),
+			RequestID: middleware.GetReqID(r.Context()),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// clientIP drops the port RemoteAddr has unless RealIP replaced it.
+func clientIP(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return remoteAddr
+}
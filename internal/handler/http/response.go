@@ -0,0 +1,60 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+type responder struct {
+	logger *slog.Logger
+}
+
+func (h *responder) respondJSON(w http.ResponseWriter, data interface{}, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("error encoding response", "error", err)
+	}
+}
+
+// respondError answers with a problem the handler detected itself, such as a
+// malformed body or path parameter.
+func (h *responder) respondError(w http.ResponseWriter, r *http.Request, status int, code string, detail string) {
+	h.writeProblem(w, r, &Problem{Status: status, Code: code, Detail: detail})
+}
+
+// respondProblem answers with the status and code mapped to err. Errors
+// without a mapping are logged and answered with a 500 that does not reveal them.
+func (h *responder) respondProblem(w http.ResponseWriter, r *http.Request, err error) {
+	p := problemFor(err)
+	if p.Status >= http.StatusInternalServerError {
+		h.logger.ErrorContext(r.Context(), "request failed", "error", err)
+	}
+	h.writeProblem(w, r, p)
+}
+
+// respondInvalid answers a failed validation with one entry per invalid field.
+func (h *responder) respondInvalid(w http.ResponseWriter, r *http.Request, err error) {
+	h.writeProblem(w, r, &Problem{
+		Status: http.StatusBadRequest,
+		Code:   CodeValidationFailed,
+		Detail: "the request has invalid fields",
+		Errors: fieldErrors(err),
+	})
+}
+
+func (h *responder) writeProblem(w http.ResponseWriter, r *http.Request, p *Problem) {
+	p.Type = "about:blank"
+	p.Title = http.StatusText(p.Status)
+	p.Instance = r.URL.Path
+	p.RequestID = middleware.GetReqID(r.Context())
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		h.logger.ErrorContext(r.Context(), "error encoding problem", "error", err)
+	}
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"idempot/internal/domain"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// requestTimeout bounds every API request except the event streams.
+const requestTimeout = 30 * time.Second
+
+// Handlers are the handlers the API is served by.
+type Handlers struct {
+	Withdrawals  *WithdrawalHandler
+	Destinations *DestinationsHandler
+	Limits       *LimitsHandler
+	Screening    *ScreeningHandler
+	LogLevel     *LogLevelHandler
+	Events       *EventsHandler
+}
+
+// RegisterRoutes mounts the API on r behind authenticate, which puts the
+// caller's principal in the request context. Each route then checks the scope
+// it needs. cmd/api and the OpenAPI contract test both serve these routes, so
+// the spec is checked against what is deployed.
+func RegisterRoutes(r chi.Router, h Handlers, authenticate func(http.Handler) http.Handler) {
+	requireScope := h.Withdrawals.RequireScope
+
+	r.Group(func(r chi.Router) {
+		r.Use(authenticate)
+
+		r.Route("/v1/withdrawals", func(r chi.Router) {
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.Timeout(requestTimeout))
+
+				r.With(requireScope(domain.ScopeWithdrawalsCreate)).Post("/", h.Withdrawals.CreateWithdrawal)
+				r.With(requireScope(domain.ScopeWithdrawalsCreate)).Post("/quote", h.Withdrawals.QuoteWithdrawal)
+				r.With(requireScope(domain.ScopeWithdrawalsRead)).Get("/{id}", h.Withdrawals.GetWithdrawal)
+				r.With(requireScope(domain.ScopeWithdrawalsConfirm)).Post("/{id}/confirm", h.Withdrawals.ConfirmWithdrawal)
+				r.With(requireScope(domain.ScopeWithdrawalsConfirm)).Post("/{id}/fail", h.Withdrawals.FailWithdrawal)
+				r.With(requireScope(domain.ScopeWithdrawalsCancel)).Post("/{id}/cancel", h.Withdrawals.CancelWithdrawal)
+				r.With(requireScope(domain.ScopeWithdrawalsConfirm)).Post("/{id}/approve", h.Withdrawals.ApproveWithdrawal)
+				r.With(requireScope(domain.ScopeWithdrawalsConfirm)).Post("/{id}/reject", h.Withdrawals.RejectWithdrawal)
+			})
+
+			// SSE streams are long-lived, so they stay outside the request timeout
+			r.Group(func(r chi.Router) {
+				r.Use(requireScope(domain.ScopeWithdrawalsRead))
+
+				r.Get("/events", h.Events.StreamUserWithdrawals)
+				r.Get("/{id}/events", h.Events.StreamWithdrawal)
+			})
+		})
+
+		r.Route("/v1/destinations", func(r chi.Router) {
+			r.Use(middleware.Timeout(requestTimeout))
+
+			r.With(requireScope(domain.ScopeWithdrawalsCreate)).Post("/", h.Destinations.AddDestination)
+			r.With(requireScope(domain.ScopeWithdrawalsRead)).Get("/", h.Destinations.ListDestinations)
+			r.With(requireScope(domain.ScopeWithdrawalsCreate)).Delete("/{id}", h.Destinations.DeleteDestination)
+			r.With(requireScope(domain.ScopeWithdrawalsRead)).Get("/settings", h.Destinations.GetSettings)
+			r.With(requireScope(domain.ScopeWithdrawalsCreate)).Put("/settings", h.Destinations.SetSettings)
+		})
+
+		r.Route("/v1/admin", func(r chi.Router) {
+			r.Use(middleware.Timeout(requestTimeout))
+			r.Use(requireScope(domain.ScopeAdmin))
+
+			r.Get("/limits/{user_id}", h.Limits.GetLimits)
+			r.Put("/limits/{user_id}", h.Limits.SetLimits)
+			r.Get("/screening/lists", h.Screening.ListVersions)
+			r.Get("/log-level", h.LogLevel.GetLevel)
+			r.Put("/log-level", h.LogLevel.SetLevel)
+		})
+	})
+}
@@ -0,0 +1,30 @@
+package http
+
+import (
+	"idempot/internal/port"
+	"log/slog"
+	"net/http"
+)
+
+// ScreeningHandler shows administrators which deny-list versions are in force.
+type ScreeningHandler struct {
+	responder
+	screener port.Screener
+}
+
+func NewScreeningHandler(screener port.Screener) *ScreeningHandler {
+	return &ScreeningHandler{
+		responder: responder{logger: slog.Default()},
+		screener:  screener,
+	}
+}
+
+func (h *ScreeningHandler) WithLogger(logger *slog.Logger) *ScreeningHandler {
+	h.logger = logger
+	return h
+}
+
+// ListVersions serves GET /v1/admin/screening/lists.
+func (h *ScreeningHandler) ListVersions(w http.ResponseWriter, r *http.Request) {
+	h.respondJSON(w, newScreeningListsResponse(h.screener.Versions()), http.StatusOK)
+}
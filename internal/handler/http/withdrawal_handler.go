@@ -1,10 +1,17 @@
 package http
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
+	"idempot/internal/auth"
 	"idempot/internal/domain"
+	"idempot/internal/mtls"
 	"idempot/internal/port"
-	"log"
+	"idempot/internal/logging"
+	"io"
+	"log/slog"
 	"net/http"
 	"strings"
 
@@ -15,141 +22,355 @@ import (
 
 
 type WithdrawalHandler struct {
+    responder
     service   port.WithdrawalService
     validate  *validator.Validate
     authToken string
-    logger    *log.Logger
+    jwt       *auth.JWTVerifier
+    apiKeys   *auth.APIKeyAuthenticator
+    signature *auth.SignatureVerifier
+    certs     *mtls.IdentityMapper
 }
 
 func NewWithdrawalHandler(service port.WithdrawalService, authToken string) *WithdrawalHandler {
     return &WithdrawalHandler{
+        responder: responder{logger: slog.Default()},
         service:   service,
-        validate:  validator.New(),
+        validate:  newValidator(),
         authToken: authToken,
-        logger:    log.Default(),
     }
 }
 
-func (h *WithdrawalHandler) WithLogger(logger *log.Logger) *WithdrawalHandler {
+func (h *WithdrawalHandler) WithLogger(logger *slog.Logger) *WithdrawalHandler {
     h.logger = logger
     return h
 }
 
+// WithJWTVerifier accepts end-user JWTs next to the static service token.
+func (h *WithdrawalHandler) WithJWTVerifier(verifier *auth.JWTVerifier) *WithdrawalHandler {
+    h.jwt = verifier
+    return h
+}
+
+// WithAPIKeys accepts per-client API keys next to the other schemes.
+func (h *WithdrawalHandler) WithAPIKeys(authenticator *auth.APIKeyAuthenticator) *WithdrawalHandler {
+    h.apiKeys = authenticator
+    return h
+}
+
+// WithSignatureVerifier accepts HMAC-signed requests as an alternative to bearer tokens.
+func (h *WithdrawalHandler) WithSignatureVerifier(verifier *auth.SignatureVerifier) *WithdrawalHandler {
+    h.signature = verifier
+    return h
+}
+
+// WithClientCertificates accepts callers whose verified TLS client certificate maps to a known identity.
+func (h *WithdrawalHandler) WithClientCertificates(mapper *mtls.IdentityMapper) *WithdrawalHandler {
+    h.certs = mapper
+    return h
+}
+
 func (h *WithdrawalHandler) AuthMiddleware(next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        auth := r.Header.Get("Authorization")
-        if !strings.HasPrefix(auth, "Bearer ") {
-            h.logger.Printf("Unauthorized access attempt from %s", r.RemoteAddr)
-            h.respondError(w, domain.ErrUnauthorized.Error(), http.StatusUnauthorized)
+        // A certificate without a mapping falls through to the other schemes
+        if h.certs != nil {
+            if principal, ok := h.certs.FromRequest(r); ok {
+                next.ServeHTTP(w, withPrincipal(r, principal))
+                return
+            }
+        }
+
+        if h.signature != nil && auth.HasSignature(r) {
+            principal, err := h.signature.Verify(r)
+            if err != nil {
+                h.logger.WarnContext(r.Context(), "invalid request signature", "remote_addr", r.RemoteAddr, "error", err)
+                h.respondProblem(w, r, domain.ErrUnauthorized)
+                return
+            }
+            next.ServeHTTP(w, withPrincipal(r, principal))
+            return
+        }
+
+        authHeader := r.Header.Get("Authorization")
+        if !strings.HasPrefix(authHeader, "Bearer ") {
+            h.logger.WarnContext(r.Context(), "unauthorized access attempt", "remote_addr", r.RemoteAddr)
+            h.respondProblem(w, r, domain.ErrUnauthorized)
+            return
+        }
+
+        token := strings.TrimPrefix(authHeader, "Bearer ")
+        if h.jwt != nil && auth.IsJWT(token) {
+            principal, err := h.jwt.Principal(token)
+            if err != nil {
+                h.logger.WarnContext(r.Context(), "invalid JWT", "remote_addr", r.RemoteAddr, "error", err)
+                h.respondProblem(w, r, domain.ErrUnauthorized)
+                return
+            }
+            next.ServeHTTP(w, withPrincipal(r, principal))
+            return
+        }
+
+        if h.apiKeys != nil && auth.IsAPIKey(token) {
+            principal, err := h.apiKeys.Authenticate(r.Context(), token)
+            if err != nil {
+                h.logger.WarnContext(r.Context(), "invalid API key", "remote_addr", r.RemoteAddr, "error", err)
+                h.respondProblem(w, r, domain.ErrUnauthorized)
+                return
+            }
+            next.ServeHTTP(w, withPrincipal(r, principal))
             return
         }
 
-        token := strings.TrimPrefix(auth, "Bearer ")
-        if token != h.authToken {
-            h.logger.Printf("Invalid token attempt from %s", r.RemoteAddr)
-            h.respondError(w, domain.ErrUnauthorized.Error(), http.StatusUnauthorized)
+        // The shared token is opt-in: with none configured, no bearer string matches
+        if h.authToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.authToken)) != 1 {
+            h.logger.WarnContext(r.Context(), "invalid token attempt", "remote_addr", r.RemoteAddr)
+            h.respondProblem(w, r, domain.ErrUnauthorized)
             return
         }
 
-        next.ServeHTTP(w, r)
+        next.ServeHTTP(w, withPrincipal(r, legacyTokenPrincipal()))
     })
 }
 
+// legacyTokenPrincipal is the caller behind the shared static token. It keeps
+// the scopes the token had before RBAC and no role, so it can neither reach
+// the admin routes nor approve or reject held withdrawals, which takes the
+// operator or admin role.
+func legacyTokenPrincipal() *domain.Principal {
+    return &domain.Principal{
+        Subject:  "static-token",
+        Kind:     domain.PrincipalService,
+        TenantID: domain.DefaultTenant,
+        Scopes: []domain.Scope{
+            domain.ScopeWithdrawalsCreate,
+            domain.ScopeWithdrawalsRead,
+            domain.ScopeWithdrawalsConfirm,
+            domain.ScopeWithdrawalsCancel,
+        },
+    }
+}
+
+// RequireScope rejects authenticated callers that lack scope.
+func (h *WithdrawalHandler) RequireScope(scope domain.Scope) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            principal, ok := domain.PrincipalFromContext(r.Context())
+            if !ok || !principal.HasScope(scope) {
+                h.logger.WarnContext(r.Context(), "missing scope", "scope", scope, "method", r.Method, "path", r.URL.Path)
+                h.respondProblem(w, r, domain.ErrForbidden)
+                return
+            }
+            next.ServeHTTP(w, r)
+        })
+    }
+}
+
 func (h *WithdrawalHandler) CreateWithdrawal(w http.ResponseWriter, r *http.Request) {
     var req domain.WithdrawalReq
     
     if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        h.logger.Printf("Invalid request body: %v", err)
-        h.respondError(w, "invalid request body", http.StatusBadRequest)
+        h.logger.InfoContext(r.Context(), "invalid request body", "error", err)
+        h.respondError(w, r, http.StatusBadRequest, CodeInvalidRequestBody, "invalid request body")
         return
     }
 
     if err := h.validate.Struct(req); err != nil {
-        h.logger.Printf("Validation failed: %v", err)
-        h.respondError(w, err.Error(), http.StatusBadRequest)
+        h.logger.InfoContext(r.Context(), "validation failed", "error", err)
+        h.respondInvalid(w, r, err)
         return
     }
 
-    h.logger.Printf("Creating withdrawal for user %s, amount %f %s", 
-        req.UserID, req.Amount, req.Currency)
+    ctx := logging.WithUserID(r.Context(), req.UserID)
+    h.logger.InfoContext(ctx, "creating withdrawal", "amount", req.Amount, "currency", req.Currency)
 
-    withdrawal, err := h.service.CreateWithdrawal(r.Context(), &req)
+    withdrawal, err := h.service.CreateWithdrawal(ctx, &req)
     if err != nil {
-        switch err {
-        case domain.ErrInsufficientBalance:
-            h.logger.Printf("Insufficient balance for user %s", req.UserID)
-            h.respondError(w, err.Error(), http.StatusConflict)
-        case domain.ErrIdempotencyKeyMismatch:
-            h.logger.Printf("Idempotency key mismatch for key %s", req.IdempotencyKey)
-            h.respondError(w, err.Error(), http.StatusUnprocessableEntity)
-        case domain.ErrDuplicateRequest:
-            h.logger.Printf("Duplicate request with key %s", req.IdempotencyKey)
-            h.respondError(w, err.Error(), http.StatusConflict)
-        case domain.ErrLockTimeout:
-            h.logger.Printf("Lock timeout for user %s", req.UserID)
-            h.respondError(w, "too many concurrent requests", http.StatusTooManyRequests)
-        default:
-            h.logger.Printf("Internal error creating withdrawal: %v", err)
-            h.respondError(w, "internal server error", http.StatusInternalServerError)
+        var screeningErr *domain.ScreeningHitError
+        var riskErr *domain.RiskDeniedError
+        var limitErr *domain.LimitExceededError
+        var mismatchErr *domain.IdempotencyKeyMismatchError
+        switch {
+        case errors.As(err, &screeningErr):
+            h.logger.WarnContext(ctx, "withdrawal blocked by screening hit",
+                "hit_id", screeningErr.Hit.ID, "list", screeningErr.Hit.List)
+        case errors.As(err, &riskErr):
+            h.logger.WarnContext(ctx, "withdrawal denied by risk rule", "rule", riskErr.Rule, "reason", riskErr.Reason)
+        case errors.As(err, &limitErr):
+            h.logger.InfoContext(ctx, "withdrawal limit exceeded", "limit", limitErr.Kind)
+        case errors.Is(err, domain.ErrInsufficientBalance):
+            h.logger.InfoContext(ctx, "insufficient balance")
+        case errors.As(err, &mismatchErr):
+            h.logger.InfoContext(ctx, "idempotency key mismatch", "idempotency_key", req.IdempotencyKey, "fields", mismatchErr.Fields)
+        case errors.Is(err, domain.ErrDuplicateRequest):
+            h.logger.InfoContext(ctx, "duplicate request", "idempotency_key", req.IdempotencyKey)
+        case errors.Is(err, domain.ErrLockTimeout):
+            h.logger.WarnContext(ctx, "lock timeout")
+        case errors.Is(err, domain.ErrForbidden):
+            h.logger.WarnContext(ctx, "caller may not withdraw for user")
         }
+        h.respondProblem(w, r.WithContext(ctx), err)
+        return
+    }
+
+    h.logger.InfoContext(ctx, "withdrawal created", "withdrawal_id", withdrawal.ID, "status", withdrawal.Status)
+    h.respondJSON(w, newWithdrawalResponse(withdrawal), http.StatusCreated)
+}
+
+// QuoteWithdrawal serves POST /v1/withdrawals/quote.
+func (h *WithdrawalHandler) QuoteWithdrawal(w http.ResponseWriter, r *http.Request) {
+    var req domain.QuoteReq
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        h.respondError(w, r, http.StatusBadRequest, CodeInvalidRequestBody, "invalid request body")
+        return
+    }
+    if err := h.validate.Struct(req); err != nil {
+        h.respondInvalid(w, r, err)
         return
     }
 
-    h.logger.Printf("Withdrawal created successfully: %s", withdrawal.ID)
-    h.respondJSON(w, withdrawal, http.StatusCreated)
+    quote, err := h.service.QuoteWithdrawal(r.Context(), &req)
+    if err != nil {
+        h.respondProblem(w, r, err)
+        return
+    }
+
+    h.respondJSON(w, newQuoteResponse(quote), http.StatusOK)
 }
 
 func (h *WithdrawalHandler) GetWithdrawal(w http.ResponseWriter, r *http.Request) {
     idStr := chi.URLParam(r, "id")
     id, err := uuid.Parse(idStr)
     if err != nil {
-        h.logger.Printf("Invalid withdrawal ID: %s", idStr)
-        h.respondError(w, "invalid withdrawal id", http.StatusBadRequest)
+        h.logger.InfoContext(r.Context(), "invalid withdrawal ID", "withdrawal_id", idStr)
+        h.respondError(w, r, http.StatusBadRequest, CodeInvalidParameter, "invalid withdrawal id")
         return
     }
 
     withdrawal, err := h.service.GetWithdrawal(r.Context(), id)
     if err != nil {
-        if err == domain.ErrWithdrawalNotFound {
-            h.logger.Printf("Withdrawal not found: %s", id)
-            h.respondError(w, err.Error(), http.StatusNotFound)
-        } else {
-            h.logger.Printf("Error getting withdrawal %s: %v", id, err)
-            h.respondError(w, "internal server error", http.StatusInternalServerError)
-        }
+        h.respondProblem(w, r, err)
         return
     }
 
-    h.respondJSON(w, withdrawal, http.StatusOK)
+    h.respondJSON(w, newWithdrawalResponse(withdrawal), http.StatusOK)
 }
 
 func (h *WithdrawalHandler) ConfirmWithdrawal(w http.ResponseWriter, r *http.Request) {
+    h.changeStatus(w, r, "confirm", func(id uuid.UUID, _ string) error {
+        return h.service.ConfirmWithdrawal(r.Context(), id)
+    })
+}
+
+func (h *WithdrawalHandler) FailWithdrawal(w http.ResponseWriter, r *http.Request) {
+    h.changeStatus(w, r, "fail", func(id uuid.UUID, reason string) error {
+        return h.service.FailWithdrawal(r.Context(), id, reason)
+    })
+}
+
+func (h *WithdrawalHandler) CancelWithdrawal(w http.ResponseWriter, r *http.Request) {
+    h.changeStatus(w, r, "cancel", func(id uuid.UUID, reason string) error {
+        return h.service.CancelWithdrawal(r.Context(), id, reason)
+    })
+}
+
+func (h *WithdrawalHandler) ApproveWithdrawal(w http.ResponseWriter, r *http.Request) {
+    h.decide(w, r, "approve", h.service.ApproveWithdrawal)
+}
+
+func (h *WithdrawalHandler) RejectWithdrawal(w http.ResponseWriter, r *http.Request) {
+    h.decide(w, r, "reject", h.service.RejectWithdrawal)
+}
+
+func (h *WithdrawalHandler) decide(
+    w http.ResponseWriter,
+    r *http.Request,
+    action string,
+    apply func(ctx context.Context, id uuid.UUID, reason string) (*domain.ApprovalProgress, error),
+) {
     idStr := chi.URLParam(r, "id")
     id, err := uuid.Parse(idStr)
     if err != nil {
-        h.logger.Printf("Invalid withdrawal ID for confirmation: %s", idStr)
-        h.respondError(w, "invalid withdrawal id", http.StatusBadRequest)
+        h.respondError(w, r, http.StatusBadRequest, CodeInvalidParameter, "invalid withdrawal id")
         return
     }
 
-    if err := h.service.ConfirmWithdrawal(r.Context(), id); err != nil {
-        h.logger.Printf("Error confirming withdrawal %s: %v", id, err)
-        h.respondError(w, "internal server error", http.StatusInternalServerError)
+    var req statusChangeReq
+    if r.ContentLength != 0 {
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+            h.respondError(w, r, http.StatusBadRequest, CodeInvalidRequestBody, "invalid request body")
+            return
+        }
+        if err := h.validate.Struct(req); err != nil {
+            h.respondInvalid(w, r, err)
+            return
+        }
+    }
+
+    progress, err := apply(r.Context(), id, req.Reason)
+    if errors.Is(err, domain.ErrStatusConflict) {
+        h.respondError(w, r, http.StatusConflict, CodeStatusConflict, "withdrawal is not awaiting approval")
+        return
+    }
+    if err != nil {
+        h.respondProblem(w, r, err)
         return
     }
 
-    h.logger.Printf("Withdrawal confirmed: %s", id)
+    h.logger.InfoContext(r.Context(), "withdrawal decision recorded", "action", action, "withdrawal_id", id,
+        "approvals", progress.Approvals, "required", progress.Required, "status", progress.Status)
+    h.respondJSON(w, newApprovalProgressResponse(progress), http.StatusOK)
+}
+
+type statusChangeReq struct {
+    Reason string `json:"reason" validate:"max=1000"`
+}
+
+func (h *WithdrawalHandler) changeStatus(w http.ResponseWriter, r *http.Request, action string, apply func(id uuid.UUID, reason string) error) {
+    idStr := chi.URLParam(r, "id")
+    id, err := uuid.Parse(idStr)
+    if err != nil {
+        h.logger.InfoContext(r.Context(), "invalid withdrawal ID", "action", action, "withdrawal_id", idStr)
+        h.respondError(w, r, http.StatusBadRequest, CodeInvalidParameter, "invalid withdrawal id")
+        return
+    }
+
+    // The body is optional and only carries a reason
+    var req statusChangeReq
+    if r.ContentLength != 0 {
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+            h.respondError(w, r, http.StatusBadRequest, CodeInvalidRequestBody, "invalid request body")
+            return
+        }
+        if err := h.validate.Struct(req); err != nil {
+            h.respondInvalid(w, r, err)
+            return
+        }
+    }
+
+    if err := apply(id, req.Reason); err != nil {
+        if errors.Is(err, domain.ErrStatusConflict) {
+            h.logger.InfoContext(r.Context(), "withdrawal is not pending", "action", action, "withdrawal_id", id)
+        }
+        h.respondProblem(w, r, err)
+        return
+    }
+
+    h.logger.InfoContext(r.Context(), "withdrawal status changed", "action", action, "withdrawal_id", id)
     w.WriteHeader(http.StatusOK)
 }
 
-func (h *WithdrawalHandler) respondJSON(w http.ResponseWriter, data interface{}, status int) {
-    w.Header().Set("Content-Type", "application/json")
-    w.WriteHeader(status)
-    if err := json.NewEncoder(w).Encode(data); err != nil {
-        h.logger.Printf("Error encoding response: %v", err)
+// withPrincipal stores the authenticated caller for handlers and log lines.
+func withPrincipal(r *http.Request, principal *domain.Principal) *http.Request {
+    ctx := logging.WithPrincipal(domain.WithPrincipal(r.Context(), principal), principalName(principal))
+    if principal.Kind == domain.PrincipalUser {
+        ctx = logging.WithUserID(ctx, principal.Subject)
     }
+    return r.WithContext(ctx)
 }
 
-func (h *WithdrawalHandler) respondError(w http.ResponseWriter, message string, status int) {
-    h.respondJSON(w, map[string]string{"error": message}, status)
-}
\ No newline at end of file
+func principalName(p *domain.Principal) string {
+    if p == nil {
+        return "anonymous"
+    }
+    return string(p.Kind) + ":" + p.Subject
+}
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"idempot/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func authenticate(h *WithdrawalHandler, token string) (int, *domain.Principal) {
+	var principal *domain.Principal
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = domain.PrincipalFromContext(r.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/withdrawals/events", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	h.AuthMiddleware(next).ServeHTTP(rec, req)
+	return rec.Code, principal
+}
+
+func TestAuthMiddleware_LegacyTokenIsNotAdmin(t *testing.T) {
+	h := NewWithdrawalHandler(&fakeWithdrawalService{}, "shared-s3cret")
+
+	code, principal := authenticate(h, "shared-s3cret")
+	assert.Equal(t, http.StatusOK, code)
+	if assert.NotNil(t, principal) {
+		assert.True(t, principal.HasScope(domain.ScopeWithdrawalsCreate))
+		assert.True(t, principal.HasScope(domain.ScopeWithdrawalsConfirm))
+		assert.False(t, principal.HasScope(domain.ScopeAdmin))
+		assert.False(t, principal.HasRole(domain.RoleAdmin))
+		assert.False(t, principal.HasRole(domain.RoleOperator))
+	}
+
+	code, _ = authenticate(h, "test-token")
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestAuthMiddleware_LegacyTokenDisabledWhenEmpty(t *testing.T) {
+	h := NewWithdrawalHandler(&fakeWithdrawalService{}, "")
+
+	for _, token := range []string{"", "test-token"} {
+		code, principal := authenticate(h, token)
+		assert.Equal(t, http.StatusUnauthorized, code)
+		assert.Nil(t, principal)
+	}
+}
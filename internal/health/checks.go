@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Database pings db, warning when the round trip takes longer than slow.
+func Database(db *sql.DB, slow time.Duration) Check {
+	return func(ctx context.Context) Result {
+		start := time.Now()
+		if err := db.PingContext(ctx); err != nil {
+			return FailWith("database unreachable", err)
+		}
+		latency := time.Since(start)
+		if slow > 0 && latency > slow {
+			return Warn(fmt.Sprintf("ping took %s", latency.Round(time.Millisecond)))
+		}
+		return Pass("")
+	}
+}
+
+// Pool warns when the share of connections in use reaches saturation, or
+// when requests had to wait for a connection since the previous check.
+// A busy pool slows the service down but does not stop it, so it never fails.
+func Pool(db *sql.DB, saturation float64) Check {
+	var lastWaits atomic.Int64
+	return func(ctx context.Context) Result {
+		stats := db.Stats()
+		waits := stats.WaitCount - lastWaits.Swap(stats.WaitCount)
+		message := fmt.Sprintf("%d of %d connections in use", stats.InUse, stats.MaxOpenConnections)
+
+		if stats.MaxOpenConnections > 0 && float64(stats.InUse) >= saturation*float64(stats.MaxOpenConnections) {
+			return Warn(message)
+		}
+		if waits > 0 {
+			return Warn(fmt.Sprintf("%s, %d waits for a connection", message, waits))
+		}
+		return Pass(message)
+	}
+}
+
+// SchemaVersion fails until the database schema is at least want, as read by
+// current. A lower version means the migrations were not applied.
+func SchemaVersion(current func(ctx context.Context) (int, error), want int) Check {
+	return func(ctx context.Context) Result {
+		version, err := current(ctx)
+		if err != nil {
+			return FailWith("schema version unavailable", err)
+		}
+		if version < want {
+			return Fail(fmt.Sprintf("schema version %d, want %d", version, want))
+		}
+		return Pass(fmt.Sprintf("schema version %d", version))
+	}
+}
+
+// Backlog warns when count, the work a background worker has left overdue,
+// exceeds limit. Like a stalled worker, a backlog leaves requests served.
+func Backlog(count func(ctx context.Context) (int, error), limit int) Check {
+	return func(ctx context.Context) Result {
+		n, err := count(ctx)
+		if err != nil {
+			return FailWith("backlog unavailable", err)
+		}
+		message := fmt.Sprintf("%d overdue", n)
+		if n > limit {
+			return Warn(message)
+		}
+		return Pass(message)
+	}
+}
+
+// Heartbeat tracks a background worker that beats once per iteration.
+type Heartbeat struct {
+	last atomic.Int64
+}
+
+func NewHeartbeat() *Heartbeat {
+	h := &Heartbeat{}
+	// Give the worker its first interval before it counts as stalled
+	h.Beat()
+	return h
+}
+
+func (h *Heartbeat) Beat() {
+	h.last.Store(time.Now().UnixNano())
+}
+
+// Check warns once the worker has not beaten for maxAge. A stalled worker
+// leaves its work undone, but requests are still served.
+func (h *Heartbeat) Check(maxAge time.Duration) Check {
+	return func(ctx context.Context) Result {
+		age := time.Since(time.Unix(0, h.last.Load()))
+		if age > maxAge {
+			return Warn(fmt.Sprintf("last beat %s ago", age.Round(time.Second)))
+		}
+		return Pass("")
+	}
+}
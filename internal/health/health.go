@@ -0,0 +1,197 @@
+// Package health runs named readiness checks and serves the /livez and
+// /readyz probes.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	// StatusWarn reports a degraded dependency that does not stop the service from serving.
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// defaultTimeout bounds each check, so that a hanging dependency fails its
+// check instead of the probe.
+const defaultTimeout = 2 * time.Second
+
+// Result is the outcome of one check.
+type Result struct {
+	Status  Status `json:"status"`
+	Message string `json:"message,omitempty"`
+	// Duration is how long the check took, in milliseconds
+	Duration float64 `json:"duration_ms"`
+	// err is logged rather than reported: /readyz is unauthenticated
+	err error
+}
+
+func Pass(message string) Result { return Result{Status: StatusPass, Message: message} }
+func Warn(message string) Result { return Result{Status: StatusWarn, Message: message} }
+func Fail(message string) Result { return Result{Status: StatusFail, Message: message} }
+
+// FailWith fails with a fixed message for the report. err, which may name
+// hosts or carry driver messages, only goes to the checker's log.
+func FailWith(message string, err error) Result {
+	return Result{Status: StatusFail, Message: message, err: err}
+}
+
+// Check inspects one dependency. It must return once ctx is done.
+type Check func(ctx context.Context) Result
+
+// Report is the body of /readyz: the worst status of all checks, and each check's result.
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs the registered checks for readiness. The service is ready when
+// no check fails and it is not shutting down.
+type Checker struct {
+	mu           sync.RWMutex
+	checks       []namedCheck
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+	logger       *slog.Logger
+}
+
+func NewChecker() *Checker {
+	return &Checker{timeout: defaultTimeout, logger: slog.Default()}
+}
+
+func (c *Checker) WithTimeout(timeout time.Duration) *Checker {
+	if timeout > 0 {
+		c.timeout = timeout
+	}
+	return c
+}
+
+func (c *Checker) WithLogger(logger *slog.Logger) *Checker {
+	c.logger = logger
+	return c
+}
+
+// Register adds a check reported under name.
+func (c *Checker) Register(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Shutdown makes readiness fail from now on, so that load balancers stop
+// sending traffic while open connections are drained.
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+}
+
+// Run runs every check concurrently, each under the checker's timeout.
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := append([]namedCheck(nil), c.checks...)
+	c.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, nc := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, nc.check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusPass, Checks: make(map[string]Result, len(checks))}
+	if c.shuttingDown.Load() {
+		report.Checks["shutdown"] = Fail("shutting down")
+	}
+	for i, nc := range checks {
+		report.Checks[nc.name] = results[i]
+		if results[i].err != nil {
+			c.logger.WarnContext(ctx, "health check failed", "check", nc.name, "error", results[i].err)
+		}
+	}
+	for _, r := range report.Checks {
+		report.Status = worse(report.Status, r.Status)
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan Result, 1)
+	go func() { done <- check(ctx) }()
+
+	var result Result
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		result = Fail("timed out after " + c.timeout.String())
+	}
+	result.Duration = float64(time.Since(start).Microseconds()) / 1000
+	return result
+}
+
+func worse(a, b Status) Status {
+	rank := map[Status]int{StatusPass: 0, StatusWarn: 1, StatusFail: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// Livez answers whether the process is alive and able to serve HTTP. It runs
+// no checks: a database outage must not make Kubernetes restart every pod.
+func (c *Checker) Livez(w http.ResponseWriter, r *http.Request) {
+	c.respond(w, Report{Status: StatusPass}, http.StatusOK)
+}
+
+// Readyz answers 200 while no check fails and 503 otherwise, including
+// during shutdown.
+func (c *Checker) Readyz(w http.ResponseWriter, r *http.Request) {
+	report := c.Run(r.Context())
+	status := http.StatusOK
+	if report.Status == StatusFail {
+		status = http.StatusServiceUnavailable
+		c.logger.WarnContext(r.Context(), "not ready", "failing", failing(report))
+	}
+	c.respond(w, report, status)
+}
+
+func failing(report Report) []string {
+	var names []string
+	for name, r := range report.Checks {
+		if r.Status == StatusFail {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *Checker) respond(w http.ResponseWriter, report Report, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		c.logger.Error("error encoding health report", "error", err)
+	}
+}
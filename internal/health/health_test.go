@@ -0,0 +1,146 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func probe(t *testing.T, handler http.HandlerFunc) (int, Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var report Report
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+	return rec.Code, report
+}
+
+func constant(r Result) Check {
+	return func(context.Context) Result { return r }
+}
+
+func TestReadyz_WorstStatusWins(t *testing.T) {
+	c := NewChecker()
+	c.Register("database", constant(Pass("")))
+	c.Register("db_pool", constant(Warn("14 of 15 connections in use")))
+
+	code, report := probe(t, c.Readyz)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusWarn, report.Status)
+	assert.Equal(t, StatusPass, report.Checks["database"].Status)
+	assert.Equal(t, "14 of 15 connections in use", report.Checks["db_pool"].Message)
+
+	c.Register("migrations", constant(Fail("schema version 0, want 1")))
+	code, report = probe(t, c.Readyz)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Len(t, report.Checks, 3)
+}
+
+func TestReadyz_KeepsErrorsInTheLog(t *testing.T) {
+	var logs bytes.Buffer
+	c := NewChecker().WithLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	driverErr := errors.New("dial tcp 10.0.3.7:5432: connect: connection refused")
+	c.Register("database", constant(FailWith("database unreachable", driverErr)))
+
+	rec := httptest.NewRecorder()
+	c.Readyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), "database unreachable")
+	assert.NotContains(t, rec.Body.String(), "10.0.3.7")
+	assert.Contains(t, logs.String(), "10.0.3.7")
+}
+
+func TestReadyz_TimesOutHangingCheck(t *testing.T) {
+	c := NewChecker().WithTimeout(20 * time.Millisecond)
+	c.Register("database", func(ctx context.Context) Result {
+		time.Sleep(time.Second)
+		return Pass("")
+	})
+
+	start := time.Now()
+	code, report := probe(t, c.Readyz)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, report.Checks["database"].Message, "timed out")
+}
+
+func TestShutdown_FailsReadinessButNotLiveness(t *testing.T) {
+	c := NewChecker()
+	c.Register("database", constant(Pass("")))
+	code, _ := probe(t, c.Readyz)
+	require.Equal(t, http.StatusOK, code)
+
+	c.Shutdown()
+
+	code, report := probe(t, c.Readyz)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "shutting down", report.Checks["shutdown"].Message)
+
+	code, report = probe(t, c.Livez)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusPass, report.Status)
+}
+
+func TestSchemaVersion(t *testing.T) {
+	ctx := context.Background()
+	version := func(v int, err error) func(context.Context) (int, error) {
+		return func(context.Context) (int, error) { return v, err }
+	}
+
+	assert.Equal(t, StatusPass, SchemaVersion(version(2, nil), 2)(ctx).Status)
+	assert.Equal(t, StatusPass, SchemaVersion(version(3, nil), 2)(ctx).Status)
+	assert.Equal(t, StatusFail, SchemaVersion(version(0, nil), 2)(ctx).Status)
+	assert.Equal(t, StatusFail, SchemaVersion(version(0, errors.New("connection refused")), 2)(ctx).Status)
+}
+
+func TestHeartbeat(t *testing.T) {
+	h := NewHeartbeat()
+	check := h.Check(time.Minute)
+	assert.Equal(t, StatusPass, check(context.Background()).Status)
+
+	h.last.Store(time.Now().Add(-5 * time.Minute).UnixNano())
+	result := check(context.Background())
+	assert.Equal(t, StatusWarn, result.Status)
+	assert.Contains(t, result.Message, "last beat")
+
+	h.Beat()
+	assert.Equal(t, StatusPass, check(context.Background()).Status)
+}
+
+func TestBacklog(t *testing.T) {
+	overdue := 0
+	check := Backlog(func(ctx context.Context) (int, error) { return overdue, nil }, 0)
+	assert.Equal(t, Result{Status: StatusPass, Message: "0 overdue"}, check(context.Background()))
+
+	overdue = 3
+	assert.Equal(t, Result{Status: StatusWarn, Message: "3 overdue"}, check(context.Background()))
+
+	failing := Backlog(func(ctx context.Context) (int, error) { return 0, errors.New("connection refused") }, 0)
+	assert.Equal(t, Result{Status: StatusFail, Message: "backlog unavailable", err: errors.New("connection refused")}, failing(context.Background()))
+}
+
+func TestPool(t *testing.T) {
+	// sql.Open does not connect, so the pool is idle
+	db, err := sql.Open("postgres", "postgres://localhost/idempot")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(15)
+
+	result := Pool(db, 0.9)(context.Background())
+	assert.Equal(t, StatusPass, result.Status)
+	assert.Equal(t, "0 of 15 connections in use", result.Message)
+}
@@ -0,0 +1,150 @@
+// Package logging builds the service's structured logger and carries
+// per-request fields, such as the request and user IDs, through the context so
+// that every log line of a request can be correlated.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// ParseLevel accepts debug, info, warn and error in any case; empty means info.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if strings.TrimSpace(s) == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", s)
+	}
+	return level, nil
+}
+
+// Options configure New. Level and PII may be empty for info and hash.
+type Options struct {
+	Format  string
+	Level   string
+	PII     string
+	PIISalt string
+}
+
+// New returns a logger writing to w, together with the LevelVar that controls
+// it so the level can be changed at runtime. An empty format means JSON.
+// Secret attributes are always redacted and user IDs and destinations are
+// masked according to the PII policy.
+func New(w io.Writer, opts Options) (*slog.Logger, *slog.LevelVar, error) {
+	parsed, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+	redact, err := newRedactor(opts.PII, opts.PIISalt)
+	if err != nil {
+		return nil, nil, err
+	}
+	levelVar := new(slog.LevelVar)
+	levelVar.Set(parsed)
+
+	handlerOpts := &slog.HandlerOptions{Level: levelVar, ReplaceAttr: redact.replaceAttr}
+	var handler slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case "", FormatJSON:
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	case FormatText:
+		handler = slog.NewTextHandler(w, handlerOpts)
+	default:
+		return nil, nil, fmt.Errorf("unknown log format %q", opts.Format)
+	}
+	return slog.New(NewContextHandler(handler)), levelVar, nil
+}
+
+// fields are filled in while a request travels down the middleware chain.
+// They are shared by pointer so that the access log written by Middleware
+// also sees a user ID that only became known in a later handler.
+type fields struct {
+	mu        sync.Mutex
+	userID    string
+	principal string
+}
+
+type fieldsKey struct{}
+
+func fieldsFromContext(ctx context.Context) *fields {
+	f, _ := ctx.Value(fieldsKey{}).(*fields)
+	return f
+}
+
+func withFields(ctx context.Context) (context.Context, *fields) {
+	if f := fieldsFromContext(ctx); f != nil {
+		return ctx, f
+	}
+	f := &fields{}
+	return context.WithValue(ctx, fieldsKey{}, f), f
+}
+
+// WithUserID attaches the user a request or job acts on to ctx's log lines.
+func WithUserID(ctx context.Context, userID string) context.Context {
+	ctx, f := withFields(ctx)
+	f.mu.Lock()
+	f.userID = userID
+	f.mu.Unlock()
+	return ctx
+}
+
+// WithPrincipal attaches the authenticated caller's subject to ctx's log lines.
+func WithPrincipal(ctx context.Context, subject string) context.Context {
+	ctx, f := withFields(ctx)
+	f.mu.Lock()
+	f.principal = subject
+	f.mu.Unlock()
+	return ctx
+}
+
+// ContextHandler adds request_id, user_id, principal and the trace and span
+// IDs from the context to every record logged with one of the *Context methods.
+type ContextHandler struct {
+	slog.Handler
+}
+
+func NewContextHandler(next slog.Handler) *ContextHandler {
+	return &ContextHandler{Handler: next}
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := middleware.GetReqID(ctx); requestID != "" {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		record.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	if f := fieldsFromContext(ctx); f != nil {
+		f.mu.Lock()
+		if f.userID != "" {
+			record.AddAttrs(slog.String("user_id", f.userID))
+		}
+		if f.principal != "" {
+			record.AddAttrs(slog.String("principal", f.principal))
+		}
+		f.mu.Unlock()
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{Handler: h.Handler.WithGroup(name)}
+}
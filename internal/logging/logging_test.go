@@ -0,0 +1,139 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func TestParseLevel(t *testing.T) {
+	for in, want := range map[string]slog.Level{
+		"":      slog.LevelInfo,
+		"debug": slog.LevelDebug,
+		"INFO":  slog.LevelInfo,
+		"warn":  slog.LevelWarn,
+		"error": slog.LevelError,
+	} {
+		got, err := ParseLevel(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	_, err := ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestNew_Formats(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _, err := New(&buf, Options{Format: FormatText, Level: "info"})
+	require.NoError(t, err)
+	logger.Info("hello", "key", "value")
+	assert.Contains(t, buf.String(), "msg=hello key=value")
+
+	_, _, err = New(&buf, Options{Format: "xml", Level: "info"})
+	assert.Error(t, err)
+}
+
+func TestNew_LevelChangesAtRuntime(t *testing.T) {
+	var buf bytes.Buffer
+	logger, level, err := New(&buf, Options{Format: FormatJSON, Level: "info", PII: PIIPlain})
+	require.NoError(t, err)
+
+	logger.Debug("hidden")
+	assert.Empty(t, buf.String())
+
+	level.Set(slog.LevelDebug)
+	logger.Debug("shown")
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "shown", lines[0]["msg"])
+}
+
+func TestContextHandler_AddsRequestAndUser(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _, err := New(&buf, Options{Format: FormatJSON, Level: "debug", PII: PIIPlain})
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "req-1")
+	ctx = WithPrincipal(ctx, "user:alice")
+	ctx = WithUserID(ctx, "alice")
+	logger.InfoContext(ctx, "with context")
+	logger.Info("without context")
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "req-1", lines[0]["request_id"])
+	assert.Equal(t, "alice", lines[0]["user_id"])
+	assert.Equal(t, "user:alice", lines[0]["principal"])
+	assert.NotContains(t, lines[1], "request_id")
+	assert.NotContains(t, lines[1], "user_id")
+}
+
+func TestMiddleware_AccessLogSeesUserSetDownstream(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _, err := New(&buf, Options{Format: FormatJSON, Level: "info", PII: PIIPlain})
+	require.NoError(t, err)
+
+	handler := middleware.RequestID(Middleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A handler learns the user only after decoding the body
+		ctx := WithUserID(r.Context(), "bob")
+		logger.InfoContext(ctx, "in handler")
+		w.WriteHeader(http.StatusCreated)
+	})))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/withdrawals", nil))
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "in handler", lines[0]["msg"])
+	assert.Equal(t, "request completed", lines[1]["msg"])
+	assert.Equal(t, float64(http.StatusCreated), lines[1]["status"])
+	for _, line := range lines {
+		assert.Equal(t, "bob", line["user_id"])
+		assert.NotEmpty(t, line["request_id"])
+	}
+	assert.Equal(t, lines[0]["request_id"], lines[1]["request_id"])
+}
+
+func TestContextHandler_AddsTraceIDs(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _, err := New(&buf, Options{})
+	require.NoError(t, err)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled,
+	}))
+	logger.InfoContext(ctx, "traced")
+
+	lines := decodeLines(t, &buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", lines[0]["trace_id"])
+	assert.Equal(t, "00f067aa0ba902b7", lines[0]["span_id"])
+}
@@ -0,0 +1,109 @@
+package logging
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Redacted replaces secret values in log lines and config dumps.
+const Redacted = "[REDACTED]"
+
+// PII policies for user IDs and destination addresses in log lines.
+const (
+	// PIIPlain logs the values unchanged; meant for local development only.
+	PIIPlain = "plain"
+	// PIITruncate keeps a few characters at each end, enough to tell values apart by eye.
+	PIITruncate = "truncate"
+	// PIIHash replaces values with a keyed hash, so lines about the same user
+	// can still be correlated without revealing who it is.
+	PIIHash = "hash"
+)
+
+var secretKeyParts = []string{"password", "secret", "token", "authorization", "apikey", "api_key", "privatekey", "private_key", "salt"}
+
+// IsSecretKey reports whether a log attribute or setting named key holds a
+// secret that must never be written out.
+func IsSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, part := range secretKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// piiKeys are the attributes that carry a user ID or a destination address.
+var piiKeys = map[string]bool{
+	"user_id":     true,
+	"destination": true,
+	"address":     true,
+}
+
+type redactor struct {
+	policy string
+	salt   []byte
+}
+
+func newRedactor(policy string, salt string) (*redactor, error) {
+	switch strings.ToLower(policy) {
+	case "":
+		policy = PIIHash
+	case PIIPlain, PIITruncate, PIIHash:
+		policy = strings.ToLower(policy)
+	default:
+		return nil, fmt.Errorf("unknown PII policy %q", policy)
+	}
+	return &redactor{policy: policy, salt: []byte(salt)}, nil
+}
+
+// replaceAttr is the slog ReplaceAttr hook: secrets are dropped and PII is
+// masked whichever code path logged them.
+func (r *redactor) replaceAttr(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	switch {
+	case IsSecretKey(key):
+		return slog.String(a.Key, Redacted)
+	case piiKeys[key]:
+		return slog.String(a.Key, r.mask(a.Value.String()))
+	case key == "principal":
+		// User principals are named after the user ID; service clients are not PII
+		if subject, ok := strings.CutPrefix(a.Value.String(), "user:"); ok {
+			return slog.String(a.Key, "user:"+r.mask(subject))
+		}
+	}
+	return a
+}
+
+func (r *redactor) mask(value string) string {
+	if value == "" {
+		return value
+	}
+	switch r.policy {
+	case PIIPlain:
+		return value
+	case PIITruncate:
+		return truncate(value)
+	default:
+		mac := hmac.New(sha256.New, r.salt)
+		mac.Write([]byte(value))
+		return "h:" + hex.EncodeToString(mac.Sum(nil))[:16]
+	}
+}
+
+// truncate keeps the first and last four characters of long values, such as
+// addresses, and only the first two of short ones.
+func truncate(value string) string {
+	runes := []rune(value)
+	if len(runes) > 12 {
+		return string(runes[:4]) + "..." + string(runes[len(runes)-4:])
+	}
+	if len(runes) > 2 {
+		return string(runes[:2]) + "***"
+	}
+	return "***"
+}
@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testUser        = "customer-42"
+	testDestination = "0x52908400098527886E0F7030069857D2E4169EE7"
+)
+
+func TestRedaction_SecretsNeverLogged(t *testing.T) {
+	for _, policy := range []string{PIIPlain, PIITruncate, PIIHash} {
+		var buf bytes.Buffer
+		logger, _, err := New(&buf, Options{Format: FormatJSON, PII: policy})
+		require.NoError(t, err)
+
+		logger.Info("auth", "token", "t0ken", "client_secret", "s3cret", "password", "pa55", "Authorization", "Bearer abc")
+		logger.With("api_key", "k3y").Info("with attrs")
+
+		out := buf.String()
+		for _, secret := range []string{"t0ken", "s3cret", "pa55", "Bearer abc", "k3y"} {
+			assert.NotContains(t, out, secret, policy)
+		}
+		assert.Contains(t, out, Redacted, policy)
+	}
+}
+
+func TestRedaction_PIIPolicies(t *testing.T) {
+	log := func(opts Options) []map[string]any {
+		var buf bytes.Buffer
+		logger, _, err := New(&buf, opts)
+		require.NoError(t, err)
+		ctx := WithPrincipal(WithUserID(context.Background(), testUser), "user:"+testUser)
+		logger.InfoContext(ctx, "created", "destination", testDestination)
+		logger.InfoContext(ctx, "created", "destination", testDestination)
+		return decodeLines(t, &buf)
+	}
+
+	hashed := log(Options{})
+	require.Len(t, hashed, 2)
+	for _, line := range hashed {
+		assert.NotContains(t, line["user_id"], testUser)
+		assert.NotContains(t, line["principal"], testUser)
+		assert.NotContains(t, line["destination"], testDestination)
+	}
+	// Hashes are stable, so lines about the same user can still be correlated
+	assert.Equal(t, hashed[0]["user_id"], hashed[1]["user_id"])
+	assert.Equal(t, "user:"+hashed[0]["user_id"].(string), hashed[0]["principal"])
+
+	salted := log(Options{PIISalt: "pepper"})
+	assert.NotEqual(t, hashed[0]["user_id"], salted[0]["user_id"])
+
+	truncated := log(Options{PII: PIITruncate})
+	assert.Equal(t, "0x52...9EE7", truncated[0]["destination"])
+	assert.Equal(t, "cu***", truncated[0]["user_id"])
+
+	plain := log(Options{PII: PIIPlain})
+	assert.Equal(t, testUser, plain[0]["user_id"])
+	assert.Equal(t, testDestination, plain[0]["destination"])
+
+	_, _, err := New(&bytes.Buffer{}, Options{PII: "rot13"})
+	assert.Error(t, err)
+}
+
+func TestRedaction_ServiceErrorsKeepTheirMessage(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _, err := New(&buf, Options{})
+	require.NoError(t, err)
+
+	logger.Error("failed", "error", errors.New("connection refused"))
+	assert.Contains(t, buf.String(), "connection refused")
+}
+
+func TestMiddleware_LogsRouteInsteadOfPath(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _, err := New(&buf, Options{})
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(Middleware(logger))
+	r.Get("/v1/admin/limits/{user_id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/admin/limits/"+testUser+"?currency=USDT", nil))
+
+	assert.Contains(t, buf.String(), `"route":"/v1/admin/limits/{user_id}"`)
+	assert.NotContains(t, buf.String(), testUser)
+}
@@ -0,0 +1,223 @@
+// Package metrics exposes Prometheus metrics for the HTTP API, the withdrawal
+// pipeline and the database pool. Labels only take values from fixed sets,
+// route patterns and status codes, never user IDs or addresses.
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"idempot/internal/domain"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "idempot"
+
+// Creation outcomes; anything not listed counts as OutcomeError.
+const (
+	OutcomeSuccess               = "success"
+	OutcomeInsufficientBalance   = "insufficient_balance"
+	OutcomeIdempotencyMismatch   = "idempotency_mismatch"
+	OutcomeDuplicate             = "duplicate"
+	OutcomeLockTimeout           = "lock_timeout"
+	OutcomeLimitExceeded         = "limit_exceeded"
+	OutcomeFeeExceedsAmount      = "fee_exceeds_amount"
+	OutcomeInvalidDestination    = "invalid_destination"
+	OutcomeDestinationNotAllowed = "destination_not_allowed"
+	OutcomeScreeningHit          = "screening_hit"
+	OutcomeRiskDenied            = "risk_denied"
+	OutcomeForbidden             = "forbidden"
+	OutcomeError                 = "error"
+)
+
+var outcomeErrors = []struct {
+	err     error
+	outcome string
+}{
+	{domain.ErrInsufficientBalance, OutcomeInsufficientBalance},
+	{domain.ErrIdempotencyKeyMismatch, OutcomeIdempotencyMismatch},
+	{domain.ErrDuplicateRequest, OutcomeDuplicate},
+	{domain.ErrLockTimeout, OutcomeLockTimeout},
+	{domain.ErrLimitExceeded, OutcomeLimitExceeded},
+	{domain.ErrFeeExceedsAmount, OutcomeFeeExceedsAmount},
+	{domain.ErrInvalidDestination, OutcomeInvalidDestination},
+	{domain.ErrDestinationNotAllowed, OutcomeDestinationNotAllowed},
+	{domain.ErrScreeningHit, OutcomeScreeningHit},
+	{domain.ErrRiskDenied, OutcomeRiskDenied},
+	{domain.ErrForbidden, OutcomeForbidden},
+}
+
+// Outcome classifies the error returned by CreateWithdrawal.
+func Outcome(err error) string {
+	if err == nil {
+		return OutcomeSuccess
+	}
+	for _, o := range outcomeErrors {
+		if errors.Is(err, o.err) {
+			return o.outcome
+		}
+	}
+	return OutcomeError
+}
+
+// lockResult classifies the error of a WithLock phase.
+func lockResult(err error, ok string) string {
+	switch {
+	case err == nil:
+		return ok
+	case errors.Is(err, domain.ErrLockTimeout):
+		return "busy"
+	default:
+		return "error"
+	}
+}
+
+// Metrics owns a registry and implements port.WithdrawalMetrics and port.LockObserver.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	creations *prometheus.CounterVec
+	replays   prometheus.Counter
+
+	lockWait             *prometheus.HistogramVec
+	lockHold             *prometheus.HistogramVec
+	serializationRetries prometheus.Counter
+}
+
+// New registers every metric, the Go runtime and process collectors and, if
+// db is not nil, the connection pool statistics.
+func New(db *sql.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by method, route pattern and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		creations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "withdrawal",
+			Name:      "creations_total",
+			Help:      "Withdrawal creation attempts by outcome, idempotent replays excluded.",
+		}, []string{"outcome"}),
+		replays: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "withdrawal",
+			Name:      "idempotent_replays_total",
+			Help:      "Creation requests answered with the withdrawal of an earlier request with the same idempotency key.",
+		}),
+		lockWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "balance_lock",
+			Name:      "wait_seconds",
+			Help:      "Time from the start of a WithLock attempt until the balance row is locked (acquired) or the attempt gives up (busy, error).",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"result"}),
+		lockHold: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "balance_lock",
+			Name:      "hold_seconds",
+			Help:      "Time the balance row stays locked, by whether the transaction committed.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"result"}),
+		serializationRetries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "balance_lock",
+			Name:      "serialization_retries_total",
+			Help:      "WithLock transactions rerun after a serialization failure.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration,
+		m.creations, m.replays,
+		m.lockWait, m.lockHold, m.serializationRetries,
+	)
+	if db != nil {
+		m.registry.MustRegister(collectors.NewDBStatsCollector(db, namespace))
+	}
+	return m
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Middleware counts and times requests by route pattern. Requests that match
+// no route share the "unmatched" label so that scanners cannot inflate the
+// number of series.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		labels := prometheus.Labels{"method": method(r.Method), "route": route, "status": strconv.Itoa(status)}
+		m.httpRequests.With(labels).Inc()
+		m.httpDuration.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
+
+// method folds non-standard HTTP methods into one label value.
+func method(m string) string {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return m
+	}
+	return "other"
+}
+
+func (m *Metrics) WithdrawalCreated(err error) {
+	m.creations.WithLabelValues(Outcome(err)).Inc()
+}
+
+func (m *Metrics) IdempotentReplay() {
+	m.replays.Inc()
+}
+
+func (m *Metrics) LockWaited(wait time.Duration, err error) {
+	m.lockWait.WithLabelValues(lockResult(err, "acquired")).Observe(wait.Seconds())
+}
+
+func (m *Metrics) LockHeld(hold time.Duration, err error) {
+	result := "committed"
+	if err != nil {
+		result = "rolled_back"
+	}
+	m.lockHold.WithLabelValues(result).Observe(hold.Seconds())
+}
+
+func (m *Metrics) SerializationRetry() {
+	m.serializationRetries.Inc()
+}
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"idempot/internal/domain"
+
+	"github.com/go-chi/chi/v5"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestOutcome(t *testing.T) {
+	assert.Equal(t, OutcomeSuccess, Outcome(nil))
+	assert.Equal(t, OutcomeInsufficientBalance, Outcome(domain.ErrInsufficientBalance))
+	assert.Equal(t, OutcomeIdempotencyMismatch, Outcome(domain.ErrIdempotencyKeyMismatch))
+	assert.Equal(t, OutcomeLockTimeout, Outcome(fmt.Errorf("create: %w", domain.ErrLockTimeout)))
+	assert.Equal(t, OutcomeLimitExceeded, Outcome(&domain.LimitExceededError{Kind: domain.LimitDaily}))
+	assert.Equal(t, OutcomeRiskDenied, Outcome(&domain.RiskDeniedError{Rule: "velocity"}))
+	assert.Equal(t, OutcomeError, Outcome(errors.New("connection refused")))
+}
+
+func TestMiddleware_LabelsByRoutePattern(t *testing.T) {
+	m := New(nil)
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Get("/v1/admin/limits/{user_id}", func(w http.ResponseWriter, r *http.Request) {})
+	r.Post("/v1/withdrawals", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	})
+
+	for _, user := range []string{"alice", "bob", "carol"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/admin/limits/"+user, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/withdrawals", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/wp-login.php", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PROPFIND", "/v1/withdrawals", nil))
+
+	out := scrape(t, m)
+	assert.Contains(t, out, `idempot_http_requests_total{method="GET",route="/v1/admin/limits/{user_id}",status="200"} 3`)
+	assert.Contains(t, out, `idempot_http_requests_total{method="POST",route="/v1/withdrawals",status="409"} 1`)
+	assert.Contains(t, out, `idempot_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, out, `method="other"`)
+	assert.Contains(t, out, `idempot_http_request_duration_seconds_count{method="GET",route="/v1/admin/limits/{user_id}",status="200"} 3`)
+	for _, user := range []string{"alice", "bob", "carol", "wp-login"} {
+		assert.NotContains(t, out, user)
+	}
+}
+
+func TestWithdrawalAndLockMetrics(t *testing.T) {
+	m := New(nil)
+
+	m.WithdrawalCreated(nil)
+	m.WithdrawalCreated(nil)
+	m.WithdrawalCreated(domain.ErrInsufficientBalance)
+	m.IdempotentReplay()
+	m.LockWaited(time.Millisecond, nil)
+	m.LockWaited(time.Millisecond, domain.ErrLockTimeout)
+	m.LockHeld(10*time.Millisecond, nil)
+	m.LockHeld(time.Millisecond, domain.ErrInsufficientBalance)
+	m.SerializationRetry()
+
+	out := scrape(t, m)
+	assert.Contains(t, out, `idempot_withdrawal_creations_total{outcome="success"} 2`)
+	assert.Contains(t, out, `idempot_withdrawal_creations_total{outcome="insufficient_balance"} 1`)
+	assert.Contains(t, out, `idempot_withdrawal_idempotent_replays_total 1`)
+	assert.Contains(t, out, `idempot_balance_lock_wait_seconds_count{result="acquired"} 1`)
+	assert.Contains(t, out, `idempot_balance_lock_wait_seconds_count{result="busy"} 1`)
+	assert.Contains(t, out, `idempot_balance_lock_hold_seconds_count{result="committed"} 1`)
+	assert.Contains(t, out, `idempot_balance_lock_hold_seconds_count{result="rolled_back"} 1`)
+	assert.Contains(t, out, `idempot_balance_lock_serialization_retries_total 1`)
+}
+
+func TestPoolStats(t *testing.T) {
+	// sql.Open does not connect, so the pool stats are there without a database
+	db, err := sql.Open("postgres", "postgres://localhost/idempot")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(15)
+
+	out := scrape(t, New(db))
+	assert.Contains(t, out, `go_sql_max_open_connections{db_name="idempot"} 15`)
+	assert.Contains(t, out, `go_sql_in_use_connections{db_name="idempot"} 0`)
+}
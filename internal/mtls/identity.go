@@ -0,0 +1,92 @@
+package mtls
+
+import (
+	"crypto/x509"
+	"fmt"
+	"idempot/internal/domain"
+	"net/http"
+	"strings"
+)
+
+// ClientIdentity maps a certificate identity to a client. Match has the form
+// "cn:<common name>", "dns:<name>", "uri:<uri>" or "email:<address>".
+type ClientIdentity struct {
+	Match    string
+	ClientID string
+	TenantID string
+	Roles    []domain.Role
+	Scopes   []domain.Scope
+}
+
+type IdentityMapper struct {
+	identities map[string]ClientIdentity
+}
+
+func NewIdentityMapper(identities []ClientIdentity) (*IdentityMapper, error) {
+	byMatch := make(map[string]ClientIdentity, len(identities))
+	for _, id := range identities {
+		kind, value, ok := strings.Cut(id.Match, ":")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid certificate match %q", id.Match)
+		}
+		switch kind {
+		case "cn", "dns", "uri", "email":
+		default:
+			return nil, fmt.Errorf("invalid certificate match kind %q", kind)
+		}
+		if id.ClientID == "" {
+			id.ClientID = value
+		}
+		if id.TenantID == "" {
+			id.TenantID = domain.DefaultTenant
+		}
+		byMatch[id.Match] = id
+	}
+	return &IdentityMapper{identities: byMatch}, nil
+}
+
+// candidates lists the identities of cert, SANs first.
+func candidates(cert *x509.Certificate) []string {
+	var out []string
+	for _, u := range cert.URIs {
+		out = append(out, "uri:"+u.String())
+	}
+	for _, d := range cert.DNSNames {
+		out = append(out, "dns:"+d)
+	}
+	for _, e := range cert.EmailAddresses {
+		out = append(out, "email:"+e)
+	}
+	if cert.Subject.CommonName != "" {
+		out = append(out, "cn:"+cert.Subject.CommonName)
+	}
+	return out
+}
+
+// Principal maps a verified client certificate to a principal.
+func (m *IdentityMapper) Principal(cert *x509.Certificate) (*domain.Principal, bool) {
+	for _, c := range candidates(cert) {
+		id, ok := m.identities[c]
+		if !ok {
+			continue
+		}
+		scopes := append(domain.ScopesForRoles(id.Roles), id.Scopes...)
+		return &domain.Principal{
+			Subject:  id.ClientID,
+			Kind:     domain.PrincipalService,
+			TenantID: id.TenantID,
+			Roles:    id.Roles,
+			Scopes:   scopes,
+		}, true
+	}
+	return nil, false
+}
+
+// FromRequest returns the principal of a request whose client certificate was
+// verified during the handshake. Unverified certificates are ignored.
+func (m *IdentityMapper) FromRequest(r *http.Request) (*domain.Principal, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+	return m.Principal(r.TLS.VerifiedChains[0][0])
+}
@@ -0,0 +1,260 @@
+package mtls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"idempot/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns PEM-encoded certificate and key signed by the CA.
+func (ca *testCA) issue(t *testing.T, tmpl *x509.Certificate) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	tmpl.SerialNumber = serial
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func serverTemplate() *x509.Certificate {
+	return &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "localhost"},
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+	}
+}
+
+func clientTemplate(cn string) *x509.Certificate {
+	return &x509.Certificate{
+		Subject:     pkix.Name{CommonName: cn},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+	}
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	// Write and rename so the watcher never sees a half-written file
+	tmp := path + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, data, 0o600))
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+func parseCert(t *testing.T, certPEM []byte) *x509.Certificate {
+	t.Helper()
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestIdentityMapper(t *testing.T) {
+	mapper, err := NewIdentityMapper([]ClientIdentity{
+		{Match: "cn:ledger", Roles: []domain.Role{domain.RoleOperator}},
+		{Match: "uri:spiffe://payments/payouts", ClientID: "payouts", Scopes: []domain.Scope{domain.ScopeWithdrawalsCreate}},
+	})
+	require.NoError(t, err)
+
+	ca := newTestCA(t, "test-ca")
+
+	certPEM, _ := ca.issue(t, clientTemplate("ledger"))
+	principal, ok := mapper.Principal(parseCert(t, certPEM))
+	require.True(t, ok)
+	assert.Equal(t, "ledger", principal.Subject)
+	assert.Equal(t, domain.PrincipalService, principal.Kind)
+	assert.True(t, principal.HasRole(domain.RoleOperator))
+	assert.True(t, principal.HasScope(domain.ScopeWithdrawalsConfirm))
+
+	spiffe, _ := url.Parse("spiffe://payments/payouts")
+	tmpl := clientTemplate("ignored")
+	tmpl.URIs = []*url.URL{spiffe}
+	certPEM, _ = ca.issue(t, tmpl)
+	principal, ok = mapper.Principal(parseCert(t, certPEM))
+	require.True(t, ok)
+	assert.Equal(t, "payouts", principal.Subject)
+	assert.True(t, principal.HasScope(domain.ScopeWithdrawalsCreate))
+	assert.False(t, principal.HasScope(domain.ScopeWithdrawalsConfirm))
+
+	certPEM, _ = ca.issue(t, clientTemplate("stranger"))
+	_, ok = mapper.Principal(parseCert(t, certPEM))
+	assert.False(t, ok)
+
+	// Only certificates verified during the handshake count
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{parseCert(t, certPEM)}}
+	_, ok = mapper.FromRequest(r)
+	assert.False(t, ok)
+
+	_, err = NewIdentityMapper([]ClientIdentity{{Match: "serial:42"}})
+	assert.Error(t, err)
+}
+
+func TestParseOptions(t *testing.T) {
+	v, err := ParseMinVersion("1.3")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), v)
+	_, err = ParseMinVersion("1.0")
+	assert.Error(t, err)
+
+	auth, err := ParseClientAuth("require")
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, auth)
+	_, err = ParseClientAuth("maybe")
+	assert.Error(t, err)
+}
+
+func TestReloader_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+	caFile := filepath.Join(dir, "clients.crt")
+
+	serverCA := newTestCA(t, "server-ca")
+	clientCA := newTestCA(t, "client-ca")
+
+	certPEM, keyPEM := serverCA.issue(t, serverTemplate())
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	writeFile(t, caFile, clientCA.pem)
+
+	reloader, err := NewReloader(certFile, keyFile, caFile)
+	require.NoError(t, err)
+
+	mapper, err := NewIdentityMapper([]ClientIdentity{{Match: "cn:ledger", Roles: []domain.Role{domain.RoleOperator}}})
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := mapper.FromRequest(r)
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(principal.Subject))
+	}))
+	srv.TLS = reloader.TLSConfig(tls.VersionTLS12, tls.RequireAndVerifyClientCert)
+	srv.StartTLS()
+	defer srv.Close()
+
+	clientFor := func(roots []byte, certPEM, keyPEM []byte) *http.Client {
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(roots)
+		cfg := &tls.Config{RootCAs: pool}
+		if certPEM != nil {
+			pair, err := tls.X509KeyPair(certPEM, keyPEM)
+			require.NoError(t, err)
+			cfg.Certificates = []tls.Certificate{pair}
+		}
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}, Timeout: 5 * time.Second}
+	}
+
+	ledgerCert, ledgerKey := clientCA.issue(t, clientTemplate("ledger"))
+
+	resp, err := clientFor(serverCA.pem, ledgerCert, ledgerKey).Get(srv.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	// No client certificate: the handshake itself fails
+	_, err = clientFor(serverCA.pem, nil, nil).Get(srv.URL)
+	assert.Error(t, err)
+
+	// A certificate from an untrusted CA is rejected as well
+	rogueCert, rogueKey := newTestCA(t, "rogue-ca").issue(t, clientTemplate("ledger"))
+	_, err = clientFor(serverCA.pem, rogueCert, rogueKey).Get(srv.URL)
+	assert.Error(t, err)
+
+	// Rotate the server certificate to a new CA and wait for the watcher
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = reloader.Watch(ctx) }()
+	time.Sleep(100 * time.Millisecond)
+
+	rotatedCA := newTestCA(t, "rotated-ca")
+	certPEM, keyPEM = rotatedCA.issue(t, serverTemplate())
+	writeFile(t, keyFile, keyPEM)
+	writeFile(t, certFile, certPEM)
+
+	require.Eventually(t, func() bool {
+		resp, err := clientFor(rotatedCA.pem, ledgerCert, ledgerKey).Get(srv.URL)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestReloader_KeepsPreviousOnError(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+
+	certPEM, keyPEM := newTestCA(t, "ca").issue(t, serverTemplate())
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	reloader, err := NewReloader(certFile, keyFile, "")
+	require.NoError(t, err)
+	before, _ := reloader.current()
+
+	writeFile(t, certFile, []byte("not a certificate"))
+	assert.Error(t, reloader.Reload())
+
+	after, _ := reloader.current()
+	assert.Same(t, before, after)
+}
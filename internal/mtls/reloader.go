@@ -0,0 +1,137 @@
+package mtls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"idempot/internal/filewatch"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// Reloader serves the current server certificate and client CA bundle and
+// reloads both when the files change, so certificates can be rotated without a
+// restart. A failed reload keeps the previous material.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+
+	logger *slog.Logger
+}
+
+func NewReloader(certFile, keyFile, clientCAFile string) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   clientCAFile,
+		logger:   slog.Default(),
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Reloader) WithLogger(logger *slog.Logger) *Reloader {
+	r.logger = logger
+	return r
+}
+
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load server certificate: %w", err)
+	}
+
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("read client CA bundle: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("client CA bundle contains no certificates")
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCAs = pool
+	r.mu.Unlock()
+	return nil
+}
+
+// Watch reloads on file changes until ctx is cancelled.
+func (r *Reloader) Watch(ctx context.Context) error {
+	paths := []string{r.certFile, r.keyFile}
+	if r.caFile != "" {
+		paths = append(paths, r.caFile)
+	}
+
+	return filewatch.Watch(ctx, paths, filewatch.DefaultDebounce, func() {
+		if err := r.Reload(); err != nil {
+			r.logger.ErrorContext(ctx, "TLS reload failed, keeping previous certificates", "error", err)
+			return
+		}
+		r.logger.InfoContext(ctx, "TLS certificates reloaded")
+	})
+}
+
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, r.clientCAs
+}
+
+// TLSConfig returns a server config that picks up the latest certificates on
+// every handshake.
+func (r *Reloader) TLSConfig(minVersion uint16, clientAuth tls.ClientAuthType) *tls.Config {
+	return &tls.Config{
+		MinVersion: minVersion,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, pool := r.current()
+			return &tls.Config{
+				MinVersion:   minVersion,
+				Certificates: []tls.Certificate{*cert},
+				ClientCAs:    pool,
+				ClientAuth:   clientAuth,
+				NextProtos:   []string{"h2", "http/1.1"},
+			}, nil
+		},
+	}
+}
+
+// ParseMinVersion accepts "1.2" and "1.3"; empty means TLS 1.2.
+func ParseMinVersion(v string) (uint16, error) {
+	switch v {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS min version %q", v)
+	}
+}
+
+// ParseClientAuth accepts "none", "optional" and "require"; empty means none.
+func ParseClientAuth(v string) (tls.ClientAuthType, error) {
+	switch v {
+	case "", "none":
+		return tls.NoClientCert, nil
+	case "optional":
+		return tls.VerifyClientCertIfGiven, nil
+	case "require":
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return 0, fmt.Errorf("unsupported client auth mode %q", v)
+	}
+}
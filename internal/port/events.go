@@ -0,0 +1,20 @@
+package port
+
+import (
+	"context"
+	"idempot/internal/domain"
+)
+
+type WithdrawalEventPublisher interface {
+	Publish(ctx context.Context, event domain.WithdrawalEvent)
+}
+
+type WithdrawalEventSubscriber interface {
+	// Subscribe returns the buffered events newer than lastEventID that match the
+	// filter, followed by a channel of live events. If the events after
+	// lastEventID are not known, say after a restart, replay is empty and resetID
+	// is the id to resume from once the caller has reloaded its state. The
+	// channel is closed when the subscriber falls behind or the broadcaster
+	// shuts down; cancel must be called once the caller is done.
+	Subscribe(filter domain.WithdrawalEventFilter, lastEventID uint64) (replay []domain.WithdrawalEvent, resetID uint64, events <-chan domain.WithdrawalEvent, cancel func())
+}
@@ -0,0 +1,20 @@
+package port
+
+import "time"
+
+// WithdrawalMetrics counts what happens to withdrawal requests. err is the
+// error CreateWithdrawal returned, nil for a new withdrawal.
+type WithdrawalMetrics interface {
+	WithdrawalCreated(err error)
+	IdempotentReplay()
+}
+
+// LockObserver times BalanceRepository.WithLock. wait runs from the start of
+// an attempt until the balance row is locked, or the attempt gives up with
+// err; hold runs from then until the commit or rollback, with err the reason
+// for a rollback.
+type LockObserver interface {
+	LockWaited(wait time.Duration, err error)
+	LockHeld(hold time.Duration, err error)
+	SerializationRetry()
+}
@@ -3,19 +3,127 @@ package port
 import (
 	"context"
 	"idempot/internal/domain"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// Every WithdrawalRepository and BalanceRepository method is scoped to a tenant:
+// rows of other tenants are neither returned nor modified.
 type WithdrawalRepository interface {
-	Create(ctx context.Context, w *domain.Withdrawal) error
-	GetByID(ctx context.Context, id uuid.UUID) (*domain.Withdrawal, error)
-	GetByIdempotencyKey(ctx context.Context, key string) (*domain.Withdrawal, error)
-	UpdateStatus(ctx context.Context, id uuid.UUID, status domain.WithdrawalStatus) error
+	Create(ctx context.Context, tenantID string, w *domain.Withdrawal) error
+	GetByID(ctx context.Context, tenantID string, id uuid.UUID) (*domain.Withdrawal, error)
+	GetByIdempotencyKey(ctx context.Context, tenantID string, key string) (*domain.Withdrawal, error)
+	UpdateStatus(ctx context.Context, tenantID string, id uuid.UUID, status domain.WithdrawalStatus) error
+	// TransitionStatus moves the withdrawal to status only if it is currently in from,
+	// returning domain.ErrStatusConflict otherwise.
+	TransitionStatus(ctx context.Context, tenantID string, id uuid.UUID, from, status domain.WithdrawalStatus) error
+	// ListInStatusBefore returns up to limit withdrawals that entered status before
+	// the given time, ordered by (status_since, id) and starting after the cursor.
+	ListInStatusBefore(ctx context.Context, tenantID string, status domain.WithdrawalStatus, before time.Time, after domain.PageCursor, limit int) ([]*domain.Withdrawal, error)
+	ListCreatedBetween(ctx context.Context, tenantID string, from, to time.Time) ([]*domain.Withdrawal, error)
+	GetByProviderReference(ctx context.Context, tenantID string, reference string) (*domain.Withdrawal, error)
+	// SumCreatedSince adds up the user's withdrawals in currency created at or after
+	// since, leaving out those whose amount was refunded.
+	SumCreatedSince(ctx context.Context, tenantID string, userID string, currency string, since time.Time) (float64, error)
+	// ListTenants returns every tenant that has withdrawals. It is meant for
+	// background jobs, which then work tenant by tenant.
+	ListTenants(ctx context.Context) ([]string, error)
+	// CountPendingBeforeAllTenants counts the withdrawals that became pending
+	// before the given time, across every tenant on purpose: the readiness
+	// backlog is the process's, not a tenant's. Like ListTenants it takes no
+	// tenant, so its result must never reach a caller.
+	CountPendingBeforeAllTenants(ctx context.Context, before time.Time) (int, error)
+}
+
+// WithdrawalHistory answers the questions risk rules ask about a user's past withdrawals.
+type WithdrawalHistory interface {
+	// CountCreatedSince counts the user's withdrawals in any status created at or after since.
+	CountCreatedSince(ctx context.Context, tenantID string, userID string, since time.Time) (int, error)
+	// RecentConfirmedAmounts returns the amounts of the user's last confirmed withdrawals in currency, newest first.
+	RecentConfirmedAmounts(ctx context.Context, tenantID string, userID string, currency string, limit int) ([]float64, error)
+	// HasConfirmedTo tells whether a withdrawal of the user to destination was ever confirmed.
+	HasConfirmedTo(ctx context.Context, tenantID string, userID string, currency string, network string, destination string) (bool, error)
 }
 
 type BalanceRepository interface {
-	GetBalance(ctx context.Context, userID string, currency string) (*domain.Balance, error)
-	WithLock(ctx context.Context, userID string, fn func(ctx context.Context) error) error
-	UpdateBalance(ctx context.Context, userID string, currency string, amount float64) error
-}
\ No newline at end of file
+	GetBalance(ctx context.Context, tenantID string, userID string, currency string) (*domain.Balance, error)
+	WithLock(ctx context.Context, tenantID string, userID string, fn func(ctx context.Context) error) error
+	UpdateBalance(ctx context.Context, tenantID string, userID string, currency string, amount float64) error
+}
+
+type WithdrawalLimitRepository interface {
+	// GetUserLimits returns the user's override for currency, or nil if there is none.
+	GetUserLimits(ctx context.Context, tenantID string, userID string, currency string) (*domain.WithdrawalLimits, error)
+	SetUserLimits(ctx context.Context, tenantID string, userID string, limits *domain.WithdrawalLimits) error
+}
+
+type DestinationRepository interface {
+	// Create returns domain.ErrDestinationExists if the user already has the address.
+	Create(ctx context.Context, tenantID string, d *domain.Destination) error
+	// GetByID returns domain.ErrDestinationNotFound if there is no such destination.
+	GetByID(ctx context.Context, tenantID string, id uuid.UUID) (*domain.Destination, error)
+	// Find returns the user's destination with the exact address, or nil if there is none.
+	Find(ctx context.Context, tenantID string, userID string, currency string, network string, address string) (*domain.Destination, error)
+	ListByUser(ctx context.Context, tenantID string, userID string) ([]*domain.Destination, error)
+	Delete(ctx context.Context, tenantID string, id uuid.UUID) error
+	// GetSettings returns the zero settings for users who never changed them.
+	GetSettings(ctx context.Context, tenantID string, userID string) (*domain.DestinationSettings, error)
+	SetSettings(ctx context.Context, tenantID string, settings *domain.DestinationSettings) error
+}
+
+type ApprovalRepository interface {
+	// Record stores the decision in the transaction carried by ctx, returning
+	// domain.ErrAlreadyDecided if the actor has already decided on the withdrawal.
+	Record(ctx context.Context, tenantID string, approval *domain.Approval) error
+	CountApprovals(ctx context.Context, tenantID string, withdrawalID uuid.UUID) (int, error)
+}
+
+type ScreeningHitRepository interface {
+	Record(ctx context.Context, hit *domain.ScreeningHit) error
+}
+
+type RiskDenialRepository interface {
+	Record(ctx context.Context, denial *domain.RiskDenial) error
+}
+
+// FeeLedger books withdrawal fees as append-only entries instead of updating
+// a shared balance, so fee-bearing withdrawals do not conflict with each other.
+type FeeLedger interface {
+	// Record stores the entry in the transaction carried by ctx, if any.
+	Record(ctx context.Context, entry *domain.FeeEntry) error
+}
+
+type WithdrawalActionRepository interface {
+	// Record stores the action in the transaction carried by ctx, if any.
+	Record(ctx context.Context, action *domain.WithdrawalActionRecord) error
+}
+
+// AuditLog appends to the tenants' hash-chained audit logs.
+type AuditLog interface {
+	// Append sets the event's Seq, PrevHash and Hash, chaining it to the last
+	// entry of its tenant, and stores it in the transaction carried by ctx.
+	Append(ctx context.Context, event *domain.AuditEvent) error
+}
+
+type AuditRepository interface {
+	AuditLog
+	// ListChains returns every chain of every tenant's log.
+	ListChains(ctx context.Context) ([]domain.AuditChain, error)
+	// List returns up to limit entries of the chain with Seq greater than afterSeq, in order.
+	List(ctx context.Context, chain domain.AuditChain, afterSeq int64, limit int) ([]*domain.AuditEvent, error)
+	// GetHead returns the Seq and Hash of the last entry appended to the chain.
+	GetHead(ctx context.Context, chain domain.AuditChain) (int64, string, error)
+}
+
+type ReconciliationRepository interface {
+	SaveRun(ctx context.Context, run *domain.ReconciliationRun) error
+}
+
+type APIClientRepository interface {
+	Create(ctx context.Context, key *domain.APIKey) error
+	GetByKeyID(ctx context.Context, keyID string) (*domain.APIKey, error)
+	ListByClient(ctx context.Context, clientName string) ([]*domain.APIKey, error)
+	// Revoke makes the key unusable from at onwards; a future time leaves a grace period for rotation.
+	Revoke(ctx context.Context, keyID string, at time.Time) error
+}
@@ -10,4 +10,63 @@ type WithdrawalService interface {
     CreateWithdrawal(ctx context.Context, req *domain.WithdrawalReq) (*domain.Withdrawal, error)
     GetWithdrawal(ctx context.Context, id uuid.UUID) (*domain.Withdrawal, error)
     ConfirmWithdrawal(ctx context.Context, id uuid.UUID) error
-}
\ No newline at end of file
+    FailWithdrawal(ctx context.Context, id uuid.UUID, reason string) error
+    CancelWithdrawal(ctx context.Context, id uuid.UUID, reason string) error
+    // ApproveWithdrawal records the caller's approval; the last one required makes the withdrawal pending.
+    ApproveWithdrawal(ctx context.Context, id uuid.UUID, reason string) (*domain.ApprovalProgress, error)
+    // RejectWithdrawal refunds a withdrawal awaiting approval on a single rejection.
+    RejectWithdrawal(ctx context.Context, id uuid.UUID, reason string) (*domain.ApprovalProgress, error)
+    // QuoteWithdrawal returns the fee CreateWithdrawal would charge, without creating anything.
+    QuoteWithdrawal(ctx context.Context, req *domain.QuoteReq) (*domain.FeeQuote, error)
+}
+
+// AddressValidator rejects destinations that cannot receive currency on network,
+// returning a *domain.InvalidDestinationError.
+type AddressValidator interface {
+    Validate(currency string, network string, destination string) error
+}
+
+type FeeCalculator interface {
+    Quote(currency string, network string, amount float64) (*domain.FeeQuote, error)
+}
+
+// LimitChecker rejects withdrawals that break a limit. Check must run inside the
+// user's WithLock transaction so that concurrent withdrawals see each other.
+type LimitChecker interface {
+    Check(ctx context.Context, tenantID string, userID string, currency string, amount float64) error
+}
+
+type LimitService interface {
+    LimitChecker
+    // GetLimits returns the limits in effect for the user: the global defaults, with each cap the override sets in their place.
+    GetLimits(ctx context.Context, userID string, currency string) (*domain.WithdrawalLimits, error)
+    SetUserLimits(ctx context.Context, userID string, limits *domain.WithdrawalLimits) error
+}
+
+// DestinationChecker rejects withdrawals outside the user's address book when
+// the user is in allow-list mode, returning a *domain.DestinationNotAllowedError.
+type DestinationChecker interface {
+    Check(ctx context.Context, tenantID string, userID string, currency string, network string, address string) error
+}
+
+type DestinationService interface {
+    DestinationChecker
+    AddDestination(ctx context.Context, req *domain.DestinationReq) (*domain.Destination, error)
+    ListDestinations(ctx context.Context, userID string) ([]*domain.Destination, error)
+    DeleteDestination(ctx context.Context, id uuid.UUID) error
+    GetSettings(ctx context.Context, userID string) (*domain.DestinationSettings, error)
+    SetAllowListOnly(ctx context.Context, userID string, enabled bool) (*domain.DestinationSettings, error)
+}
+
+// RiskEvaluator assesses a withdrawal before the balance is debited. It runs
+// inside the user's WithLock transaction, so history it reads is consistent.
+type RiskEvaluator interface {
+    Evaluate(ctx context.Context, in *domain.RiskInput) (*domain.RiskDecision, error)
+}
+
+// Screener checks destinations, and possibly users, against compliance deny-lists.
+type Screener interface {
+    // Screen returns the matching list entry, or nil if nothing matched.
+    Screen(userID string, destination string) *domain.ScreeningHit
+    Versions() []domain.ScreeningListVersion
+}
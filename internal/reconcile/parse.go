@@ -0,0 +1,163 @@
+package reconcile
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"idempot/internal/domain"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// providerStatuses maps provider status vocabulary onto withdrawal statuses.
+var providerStatuses = map[string]domain.WithdrawalStatus{
+	"pending":    domain.StatusPending,
+	"processing": domain.StatusPending,
+	"settled":    domain.StatusConfirmed,
+	"completed":  domain.StatusConfirmed,
+	"confirmed":  domain.StatusConfirmed,
+	"success":    domain.StatusConfirmed,
+	"failed":     domain.StatusFailed,
+	"rejected":   domain.StatusFailed,
+	"returned":   domain.StatusFailed,
+}
+
+func parseStatus(raw string) (domain.WithdrawalStatus, error) {
+	status, ok := providerStatuses[strings.ToLower(strings.TrimSpace(raw))]
+	if !ok {
+		return "", fmt.Errorf("unknown settlement status %q", raw)
+	}
+	return status, nil
+}
+
+// ParseFile reads a settlement report, choosing the format by file extension.
+func ParseFile(path string) ([]domain.SettlementRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return ParseCSV(f)
+	case ".json":
+		return ParseJSON(f)
+	default:
+		return nil, fmt.Errorf("unsupported settlement file format: %s", path)
+	}
+}
+
+// ParseCSV reads a settlement report with a header row. Recognised columns are
+// withdrawal_id, provider_reference, amount, currency and status, in any order.
+func ParseCSV(r io.Reader) ([]domain.SettlementRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read csv header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"amount", "currency", "status"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("csv is missing %q column", required)
+		}
+	}
+	_, hasID := columns["withdrawal_id"]
+	_, hasRef := columns["provider_reference"]
+	if !hasID && !hasRef {
+		return nil, fmt.Errorf("csv needs a withdrawal_id or provider_reference column")
+	}
+
+	field := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var records []domain.SettlementRecord
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		amount, err := strconv.ParseFloat(field(row, "amount"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid amount: %w", line, err)
+		}
+		status, err := parseStatus(field(row, "status"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		record := domain.SettlementRecord{
+			WithdrawalID:      field(row, "withdrawal_id"),
+			ProviderReference: field(row, "provider_reference"),
+			Amount:            amount,
+			Currency:          field(row, "currency"),
+			Status:            status,
+		}
+		if err := validateRecord(record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// ParseJSON reads a settlement report that is a JSON array of records.
+func ParseJSON(r io.Reader) ([]domain.SettlementRecord, error) {
+	var raw []struct {
+		WithdrawalID      string  `json:"withdrawal_id"`
+		ProviderReference string  `json:"provider_reference"`
+		Amount            float64 `json:"amount"`
+		Currency          string  `json:"currency"`
+		Status            string  `json:"status"`
+	}
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("decode json: %w", err)
+	}
+
+	records := make([]domain.SettlementRecord, 0, len(raw))
+	for i, item := range raw {
+		status, err := parseStatus(item.Status)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", i, err)
+		}
+
+		record := domain.SettlementRecord{
+			WithdrawalID:      strings.TrimSpace(item.WithdrawalID),
+			ProviderReference: strings.TrimSpace(item.ProviderReference),
+			Amount:            item.Amount,
+			Currency:          item.Currency,
+			Status:            status,
+		}
+		if err := validateRecord(record); err != nil {
+			return nil, fmt.Errorf("record %d: %w", i, err)
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func validateRecord(r domain.SettlementRecord) error {
+	if r.WithdrawalID == "" && r.ProviderReference == "" {
+		return fmt.Errorf("record has neither withdrawal_id nor provider_reference")
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package reconcile
+
+import (
+	"strings"
+	"testing"
+
+	"idempot/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCSV(t *testing.T) {
+	input := "provider_reference,withdrawal_id,amount,currency,status\n" +
+		"ref-1,7b0f5d6e-4c38-4a3f-9a55-0f3ad9a3e0c1,100.5,USDT,settled\n" +
+		"ref-2,,20,USDT,returned\n"
+
+	records, err := ParseCSV(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "ref-1", records[0].ProviderReference)
+	assert.Equal(t, 100.5, records[0].Amount)
+	assert.Equal(t, domain.StatusConfirmed, records[0].Status)
+	assert.Equal(t, domain.StatusFailed, records[1].Status)
+}
+
+func TestParseCSV_RejectsUnknownStatus(t *testing.T) {
+	input := "withdrawal_id,amount,currency,status\nabc,1,USDT,lost\n"
+
+	_, err := ParseCSV(strings.NewReader(input))
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestParseJSON(t *testing.T) {
+	input := `[{"provider_reference":"ref-1","amount":5,"currency":"USDT","status":"completed"}]`
+
+	records, err := ParseJSON(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, domain.StatusConfirmed, records[0].Status)
+}
+
+func TestCompare(t *testing.T) {
+	matched := &domain.Withdrawal{ID: uuid.New(), Amount: 100, Currency: "USDT", Status: domain.StatusConfirmed}
+	byRef := &domain.Withdrawal{ID: uuid.New(), Amount: 50, Currency: "USDT", Status: domain.StatusConfirmed, ProviderReference: "ref-2"}
+	wrongAmount := &domain.Withdrawal{ID: uuid.New(), Amount: 30, Currency: "USDT", Status: domain.StatusConfirmed}
+	wrongStatus := &domain.Withdrawal{ID: uuid.New(), Amount: 10, Currency: "USDT", Status: domain.StatusPending}
+	missing := &domain.Withdrawal{ID: uuid.New(), Amount: 1, Currency: "USDT", Status: domain.StatusConfirmed}
+	expired := &domain.Withdrawal{ID: uuid.New(), Amount: 1, Currency: "USDT", Status: domain.StatusExpired}
+
+	records := []domain.SettlementRecord{
+		{WithdrawalID: matched.ID.String(), Amount: 100, Currency: "USDT", Status: domain.StatusConfirmed},
+		{ProviderReference: "ref-2", Amount: 50, Currency: "USDT", Status: domain.StatusConfirmed},
+		{WithdrawalID: wrongAmount.ID.String(), Amount: 31, Currency: "USDT", Status: domain.StatusConfirmed},
+		{WithdrawalID: wrongStatus.ID.String(), Amount: 10, Currency: "USDT", Status: domain.StatusConfirmed},
+		{ProviderReference: "unknown", Amount: 7, Currency: "USDT", Status: domain.StatusConfirmed},
+	}
+
+	run, err := Compare(
+		[]*domain.Withdrawal{matched, byRef, wrongAmount, wrongStatus, missing, expired},
+		records,
+		func(domain.SettlementRecord) (*domain.Withdrawal, error) { return nil, nil },
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, run.Matched)
+	assert.Equal(t, 1, run.AmountMismatched)
+	assert.Equal(t, 1, run.StatusMismatched)
+	assert.Equal(t, 1, run.Extra)
+	// Expired withdrawals were refunded and are not expected in the settlement
+	assert.Equal(t, 1, run.Missing)
+	assert.Len(t, run.Discrepancies, 4)
+}
@@ -0,0 +1,297 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+	"idempot/internal/service"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// amountTolerance matches the DECIMAL(20,8) precision of withdrawals.amount.
+const amountTolerance = 1e-8
+
+type Options struct {
+	// TenantID selects whose withdrawals are reconciled; settlement files are per brand
+	TenantID   string
+	SourceFile string
+	From       time.Time
+	To         time.Time
+	// Fix applies the provider status where that is safe to do automatically
+	Fix bool
+}
+
+type Reconciler struct {
+	withdrawalRepo port.WithdrawalRepository
+	balanceRepo    port.BalanceRepository
+	feeLedger      port.FeeLedger
+	runRepo        port.ReconciliationRepository
+	audit          port.AuditLog
+	actions        port.WithdrawalActionRepository
+	logger         *slog.Logger
+}
+
+func NewReconciler(
+	withdrawalRepo port.WithdrawalRepository,
+	balanceRepo port.BalanceRepository,
+	feeLedger port.FeeLedger,
+	runRepo port.ReconciliationRepository,
+) *Reconciler {
+	return &Reconciler{
+		withdrawalRepo: withdrawalRepo,
+		balanceRepo:    balanceRepo,
+		feeLedger:      feeLedger,
+		runRepo:        runRepo,
+		logger:         slog.Default(),
+	}
+}
+
+func (r *Reconciler) WithLogger(logger *slog.Logger) *Reconciler {
+	r.logger = logger
+	return r
+}
+
+// WithAuditLog records every fix in audit, attributed to the "reconciler" system principal.
+func (r *Reconciler) WithAuditLog(audit port.AuditLog) *Reconciler {
+	r.audit = audit
+	return r
+}
+
+// WithActionRepository records every fix in actions, taken by the "reconciler"
+// system principal.
+func (r *Reconciler) WithActionRepository(actions port.WithdrawalActionRepository) *Reconciler {
+	r.actions = actions
+	return r
+}
+
+// Run reconciles the settlement records against withdrawals created in
+// [From, To), optionally fixes statuses, and stores the result.
+func (r *Reconciler) Run(ctx context.Context, records []domain.SettlementRecord, opts Options) (*domain.ReconciliationRun, error) {
+	startedAt := time.Now()
+	if opts.TenantID == "" {
+		opts.TenantID = domain.DefaultTenant
+	}
+
+	withdrawals, err := r.withdrawalRepo.ListCreatedBetween(ctx, opts.TenantID, opts.From, opts.To)
+	if err != nil {
+		return nil, fmt.Errorf("list withdrawals: %w", err)
+	}
+
+	run, err := Compare(withdrawals, records, func(rec domain.SettlementRecord) (*domain.Withdrawal, error) {
+		return r.lookup(ctx, opts.TenantID, rec)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	run.ID = uuid.New()
+	run.TenantID = opts.TenantID
+	run.SourceFile = opts.SourceFile
+	run.PeriodStart = opts.From
+	run.PeriodEnd = opts.To
+	run.StartedAt = startedAt
+
+	if opts.Fix {
+		for i := range run.Discrepancies {
+			d := &run.Discrepancies[i]
+			if d.Kind != domain.DiscrepancyStatusMismatch {
+				continue
+			}
+			if err := r.fix(ctx, opts.TenantID, run, d); err != nil {
+				d.FixError = err.Error()
+				r.logger.ErrorContext(ctx, "could not fix withdrawal", "withdrawal_id", d.WithdrawalID, "error", err)
+				continue
+			}
+			d.Fixed = true
+			run.Fixed++
+		}
+	}
+
+	run.FinishedAt = time.Now()
+	if err := r.runRepo.SaveRun(ctx, run); err != nil {
+		return nil, fmt.Errorf("save reconciliation run: %w", err)
+	}
+
+	return run, nil
+}
+
+// lookup finds a withdrawal created outside the reconciled period.
+func (r *Reconciler) lookup(ctx context.Context, tenantID string, rec domain.SettlementRecord) (*domain.Withdrawal, error) {
+	var (
+		w   *domain.Withdrawal
+		err error
+	)
+	if id, parseErr := uuid.Parse(rec.WithdrawalID); parseErr == nil {
+		w, err = r.withdrawalRepo.GetByID(ctx, tenantID, id)
+	} else if rec.ProviderReference != "" {
+		w, err = r.withdrawalRepo.GetByProviderReference(ctx, tenantID, rec.ProviderReference)
+	} else {
+		return nil, nil
+	}
+
+	if errors.Is(err, domain.ErrWithdrawalNotFound) {
+		return nil, nil
+	}
+	return w, err
+}
+
+func (r *Reconciler) fix(ctx context.Context, tenantID string, run *domain.ReconciliationRun, d *domain.Discrepancy) error {
+	for _, other := range run.Discrepancies {
+		if other.Kind == domain.DiscrepancyAmountMismatch && other.WithdrawalID == d.WithdrawalID {
+			return fmt.Errorf("amount mismatch requires manual review")
+		}
+	}
+
+	w := d.Withdrawal
+	ctx = domain.WithPrincipal(ctx, domain.SystemPrincipal("reconciler", tenantID))
+	switch {
+	case d.SettledStatus == domain.StatusConfirmed && w.Status == domain.StatusPending:
+		return r.balanceRepo.WithLock(ctx, tenantID, w.UserID, func(txCtx context.Context) error {
+			if err := r.withdrawalRepo.TransitionStatus(txCtx, tenantID, w.ID, domain.StatusPending, domain.StatusConfirmed); err != nil {
+				return err
+			}
+			return r.recordFix(txCtx, run, w, domain.ActionConfirm, domain.StatusConfirmed)
+		})
+
+	case d.SettledStatus == domain.StatusFailed && (w.Status == domain.StatusPending || w.Status == domain.StatusConfirmed):
+		// The provider did not pay out, so the debited amount goes back to the user
+		return r.balanceRepo.WithLock(ctx, tenantID, w.UserID, func(txCtx context.Context) error {
+			if err := r.withdrawalRepo.TransitionStatus(txCtx, tenantID, w.ID, w.Status, domain.StatusFailed); err != nil {
+				return err
+			}
+			if err := service.RefundWithdrawal(txCtx, r.balanceRepo, r.feeLedger, tenantID, w); err != nil {
+				return err
+			}
+			return r.recordFix(txCtx, run, w, domain.ActionFail, domain.StatusFailed)
+		})
+
+	default:
+		return fmt.Errorf("transition %s -> %s requires manual review", w.Status, d.SettledStatus)
+	}
+}
+
+// recordFix records who moved w to status and why, in the transaction of the fix.
+func (r *Reconciler) recordFix(
+	ctx context.Context,
+	run *domain.ReconciliationRun,
+	w *domain.Withdrawal,
+	action domain.WithdrawalAction,
+	status domain.WithdrawalStatus,
+) error {
+	if r.actions != nil {
+		reason := fmt.Sprintf("settled as %s in reconciliation run %s", status, run.ID)
+		if err := r.actions.Record(ctx, service.NewActionRecord(ctx, w, action, status, reason)); err != nil {
+			return err
+		}
+	}
+	if r.audit == nil {
+		return nil
+	}
+	return service.AuditTransition(ctx, r.audit, w, status)
+}
+
+// Compare matches settlement records to withdrawals by withdrawal ID or, failing
+// that, provider reference. Records that match nothing in withdrawals are passed
+// to lookup, which may be nil. Pending and confirmed withdrawals that have no
+// settlement record are reported as missing.
+func Compare(
+	withdrawals []*domain.Withdrawal,
+	records []domain.SettlementRecord,
+	lookup func(domain.SettlementRecord) (*domain.Withdrawal, error),
+) (*domain.ReconciliationRun, error) {
+	byID := make(map[uuid.UUID]*domain.Withdrawal, len(withdrawals))
+	byRef := make(map[string]*domain.Withdrawal, len(withdrawals))
+	for _, w := range withdrawals {
+		byID[w.ID] = w
+		if w.ProviderReference != "" {
+			byRef[w.ProviderReference] = w
+		}
+	}
+
+	run := &domain.ReconciliationRun{Discrepancies: []domain.Discrepancy{}}
+	seen := make(map[uuid.UUID]bool, len(records))
+
+	for i := range records {
+		rec := records[i]
+
+		var w *domain.Withdrawal
+		if id, err := uuid.Parse(rec.WithdrawalID); err == nil {
+			w = byID[id]
+		}
+		if w == nil && rec.ProviderReference != "" {
+			w = byRef[rec.ProviderReference]
+		}
+		if w == nil && lookup != nil {
+			found, err := lookup(rec)
+			if err != nil {
+				return nil, fmt.Errorf("look up settlement %s/%s: %w", rec.WithdrawalID, rec.ProviderReference, err)
+			}
+			w = found
+		}
+
+		if w == nil || seen[w.ID] {
+			run.Extra++
+			run.Discrepancies = append(run.Discrepancies, domain.Discrepancy{
+				Kind:              domain.DiscrepancyExtra,
+				ProviderReference: rec.ProviderReference,
+				Settlement:        &rec,
+				SettledAmount:     rec.Amount,
+				SettledStatus:     rec.Status,
+			})
+			continue
+		}
+		seen[w.ID] = true
+
+		clean := true
+		// Providers settle the payout, not the fee the user was charged on top
+		if math.Abs(w.Payout()-rec.Amount) > amountTolerance || (rec.Currency != "" && rec.Currency != w.Currency) {
+			clean = false
+			run.AmountMismatched++
+			run.Discrepancies = append(run.Discrepancies, discrepancy(domain.DiscrepancyAmountMismatch, w, &rec))
+		}
+		if w.Status != rec.Status {
+			clean = false
+			run.StatusMismatched++
+			run.Discrepancies = append(run.Discrepancies, discrepancy(domain.DiscrepancyStatusMismatch, w, &rec))
+		}
+		if clean {
+			run.Matched++
+		}
+	}
+
+	for _, w := range withdrawals {
+		if seen[w.ID] || (w.Status != domain.StatusPending && w.Status != domain.StatusConfirmed) {
+			continue
+		}
+		run.Missing++
+		run.Discrepancies = append(run.Discrepancies, discrepancy(domain.DiscrepancyMissing, w, nil))
+	}
+
+	return run, nil
+}
+
+func discrepancy(kind domain.DiscrepancyKind, w *domain.Withdrawal, rec *domain.SettlementRecord) domain.Discrepancy {
+	d := domain.Discrepancy{
+		Kind:              kind,
+		WithdrawalID:      w.ID,
+		ProviderReference: w.ProviderReference,
+		Withdrawal:        w,
+		Settlement:        rec,
+		ExpectedAmount:    w.Payout(),
+		ExpectedStatus:    w.Status,
+	}
+	if rec != nil {
+		d.SettledAmount = rec.Amount
+		d.SettledStatus = rec.Status
+		if d.ProviderReference == "" {
+			d.ProviderReference = rec.ProviderReference
+		}
+	}
+	return d
+}
@@ -1,9 +1,14 @@
 DO $$ BEGIN
-    CREATE TYPE withdrawal_status AS ENUM ('pending', 'confirmed', 'failed');
+    CREATE TYPE withdrawal_status AS ENUM ('pending', 'confirmed', 'failed', 'expired', 'cancelled');
 EXCEPTION
     WHEN duplicate_object THEN null;
 END $$;
 
+ALTER TYPE withdrawal_status ADD VALUE IF NOT EXISTS 'expired';
+ALTER TYPE withdrawal_status ADD VALUE IF NOT EXISTS 'cancelled';
+ALTER TYPE withdrawal_status ADD VALUE IF NOT EXISTS 'awaiting_approval';
+ALTER TYPE withdrawal_status ADD VALUE IF NOT EXISTS 'rejected';
+
 -- Create withdrawals table
 CREATE TABLE IF NOT EXISTS withdrawals (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -27,14 +32,291 @@ CREATE TABLE IF NOT EXISTS balances (
     UNIQUE(user_id, currency)
 );
 
+-- Reference assigned by the payout provider, used by reconciliation
+ALTER TABLE withdrawals ADD COLUMN IF NOT EXISTS provider_reference VARCHAR(255);
+
+-- Results of reconciliation runs against provider settlement files
+CREATE TABLE IF NOT EXISTS reconciliation_runs (
+    id UUID PRIMARY KEY,
+    source_file TEXT NOT NULL,
+    period_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    period_end TIMESTAMP WITH TIME ZONE NOT NULL,
+    matched INT NOT NULL DEFAULT 0,
+    missing INT NOT NULL DEFAULT 0,
+    extra INT NOT NULL DEFAULT 0,
+    amount_mismatched INT NOT NULL DEFAULT 0,
+    status_mismatched INT NOT NULL DEFAULT 0,
+    fixed INT NOT NULL DEFAULT 0,
+    items JSONB NOT NULL DEFAULT '[]',
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Who changed the status of a withdrawal: a caller, an operator or a background job
+CREATE TABLE IF NOT EXISTS withdrawal_actions (
+    id BIGSERIAL PRIMARY KEY,
+    withdrawal_id UUID NOT NULL REFERENCES withdrawals(id),
+    action VARCHAR(32) NOT NULL,
+    from_status withdrawal_status NOT NULL,
+    to_status withdrawal_status NOT NULL,
+    actor_subject VARCHAR(255) NOT NULL,
+    actor_kind VARCHAR(32) NOT NULL,
+    actor_roles TEXT[] NOT NULL DEFAULT '{}',
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Per-client API keys; only the SHA-256 of the secret part is stored
+CREATE TABLE IF NOT EXISTS api_clients (
+    key_id VARCHAR(32) PRIMARY KEY,
+    client_name VARCHAR(255) NOT NULL,
+    key_hash CHAR(64) NOT NULL,
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Tenancy: every brand has its own withdrawals, balances and idempotency keys.
+-- Rows that predate tenancy belong to the default tenant.
+ALTER TABLE withdrawals ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE balances ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE api_clients ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE reconciliation_runs ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+-- Uniqueness is per tenant now
+ALTER TABLE withdrawals DROP CONSTRAINT IF EXISTS withdrawals_idempotency_key_key;
+ALTER TABLE balances DROP CONSTRAINT IF EXISTS balances_user_id_currency_key;
+DROP INDEX IF EXISTS idx_withdrawals_provider_reference;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_withdrawals_tenant_idempotency_key ON withdrawals(tenant_id, idempotency_key);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_balances_tenant_user_currency ON balances(tenant_id, user_id, currency);
+
+-- Fee charged on top of the payout: amount = fee + net_amount
+ALTER TABLE withdrawals ADD COLUMN IF NOT EXISTS fee DECIMAL(20,8) NOT NULL DEFAULT 0 CHECK (fee >= 0);
+ALTER TABLE withdrawals ADD COLUMN IF NOT EXISTS net_amount DECIMAL(20,8);
+
+-- Network the destination belongs to; '' for withdrawals made before networks
+ALTER TABLE withdrawals ADD COLUMN IF NOT EXISTS network VARCHAR(32) NOT NULL DEFAULT '';
+
+-- Per-user overrides of the global withdrawal limits; 0 keeps the global limit
+CREATE TABLE IF NOT EXISTS withdrawal_limits (
+    tenant_id VARCHAR(64) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    min_per_transaction DECIMAL(20,8) NOT NULL DEFAULT 0 CHECK (min_per_transaction >= 0),
+    max_per_transaction DECIMAL(20,8) NOT NULL DEFAULT 0 CHECK (max_per_transaction >= 0),
+    daily_limit DECIMAL(20,8) NOT NULL DEFAULT 0 CHECK (daily_limit >= 0),
+    monthly_limit DECIMAL(20,8) NOT NULL DEFAULT 0 CHECK (monthly_limit >= 0),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, user_id, currency)
+);
+
+-- Address books; a destination cannot be withdrawn to before usable_at
+CREATE TABLE IF NOT EXISTS destinations (
+    id UUID PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    network VARCHAR(32) NOT NULL DEFAULT '',
+    address VARCHAR(255) NOT NULL,
+    label VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    usable_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_destinations_tenant_user_address ON destinations(tenant_id, user_id, currency, network, address);
+
+-- Allow-list mode stays enforced until enforced_until after being switched off
+CREATE TABLE IF NOT EXISTS destination_settings (
+    tenant_id VARCHAR(64) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    allow_list_only BOOLEAN NOT NULL DEFAULT FALSE,
+    enforced_until TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, user_id)
+);
+
+-- Subject of the principal that created the withdrawal; it may not approve it
+ALTER TABLE withdrawals ADD COLUMN IF NOT EXISTS requested_by VARCHAR(255) NOT NULL DEFAULT '';
+
+-- Operator decisions on withdrawals awaiting approval, one per operator
+CREATE TABLE IF NOT EXISTS withdrawal_approvals (
+    withdrawal_id UUID NOT NULL REFERENCES withdrawals(id),
+    tenant_id VARCHAR(64) NOT NULL,
+    actor_subject VARCHAR(255) NOT NULL,
+    actor_kind VARCHAR(32) NOT NULL,
+    decision VARCHAR(16) NOT NULL CHECK (decision IN ('approve', 'reject')),
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (withdrawal_id, actor_subject)
+);
+
+-- Outcome of the risk checks and the rule that decided it, if any
+ALTER TABLE withdrawals ADD COLUMN IF NOT EXISTS risk_decision VARCHAR(16) NOT NULL DEFAULT 'allow';
+ALTER TABLE withdrawals ADD COLUMN IF NOT EXISTS risk_rule VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE withdrawals ADD COLUMN IF NOT EXISTS risk_reason TEXT NOT NULL DEFAULT '';
+
+-- Withdrawals blocked by compliance screening and the list entry that matched
+CREATE TABLE IF NOT EXISTS screening_hits (
+    id UUID PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    network VARCHAR(32) NOT NULL DEFAULT '',
+    destination TEXT NOT NULL,
+    list_name VARCHAR(255) NOT NULL,
+    list_version VARCHAR(255) NOT NULL,
+    entry_type VARCHAR(16) NOT NULL,
+    entry_value TEXT NOT NULL,
+    entity TEXT NOT NULL DEFAULT '',
+    reference TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_screening_hits_tenant_created_at ON screening_hits(tenant_id, created_at);
+
+-- Withdrawals denied by a risk rule; the withdrawal row is rolled back, this one is not
+CREATE TABLE IF NOT EXISTS risk_denials (
+    id UUID PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    amount DECIMAL(20,8) NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    network VARCHAR(32) NOT NULL DEFAULT '',
+    destination TEXT NOT NULL,
+    rule VARCHAR(64) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_risk_denials_tenant_created_at ON risk_denials(tenant_id, created_at);
+
+-- Append-only audit log of state changes, hash-chained.
+-- Snapshots are JSON, not JSONB, so that they keep the exact text that was hashed.
+CREATE TABLE IF NOT EXISTS audit_events (
+    tenant_id VARCHAR(64) NOT NULL,
+    seq BIGINT NOT NULL CHECK (seq > 0),
+    action VARCHAR(64) NOT NULL,
+    entity_type VARCHAR(32) NOT NULL,
+    entity_id VARCHAR(255) NOT NULL,
+    actor_subject VARCHAR(255) NOT NULL DEFAULT '',
+    actor_kind VARCHAR(32) NOT NULL DEFAULT '',
+    client_ip VARCHAR(64) NOT NULL DEFAULT '',
+    request_id VARCHAR(255) NOT NULL DEFAULT '',
+    before_state JSON,
+    after_state JSON,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    prev_hash VARCHAR(64) NOT NULL,
+    hash CHAR(64) NOT NULL,
+    PRIMARY KEY (tenant_id, seq)
+);
+
+-- Last entry of each chain; appends lock it to take turns
+CREATE TABLE IF NOT EXISTS audit_chain_heads (
+    tenant_id VARCHAR(64) PRIMARY KEY,
+    seq BIGINT NOT NULL,
+    hash VARCHAR(64) NOT NULL
+);
+
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_events_no_update ON audit_events;
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+DROP TRIGGER IF EXISTS audit_events_no_truncate ON audit_events;
+CREATE TRIGGER audit_events_no_truncate BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
+
+-- One chain per user instead of per tenant, so that appends of different users
+-- do not queue for one head row. Entries from before keep user_id '' and stay
+-- the tenant-wide chain they were written in.
+ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS user_id VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE audit_events DROP CONSTRAINT IF EXISTS audit_events_pkey;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_events_tenant_user_seq ON audit_events(tenant_id, user_id, seq);
+ALTER TABLE audit_chain_heads ADD COLUMN IF NOT EXISTS user_id VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE audit_chain_heads DROP CONSTRAINT IF EXISTS audit_chain_heads_pkey;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_audit_chain_heads_tenant_user ON audit_chain_heads(tenant_id, user_id);
+
+-- Withdrawal fees, one row per charge or refund. Rows are only ever inserted,
+-- so concurrent withdrawals never contend for a shared balance row.
+CREATE TABLE IF NOT EXISTS fee_ledger (
+    id UUID PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    withdrawal_id UUID,
+    currency VARCHAR(10) NOT NULL,
+    -- Negative for refunds
+    amount DECIMAL(20,8) NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE OR REPLACE FUNCTION fee_ledger_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'fee_ledger is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS fee_ledger_no_update ON fee_ledger;
+CREATE TRIGGER fee_ledger_no_update BEFORE UPDATE OR DELETE ON fee_ledger
+    FOR EACH ROW EXECUTE FUNCTION fee_ledger_append_only();
+DROP TRIGGER IF EXISTS fee_ledger_no_truncate ON fee_ledger;
+CREATE TRIGGER fee_ledger_no_truncate BEFORE TRUNCATE ON fee_ledger
+    FOR EACH STATEMENT EXECUTE FUNCTION fee_ledger_append_only();
+
+CREATE INDEX IF NOT EXISTS idx_fee_ledger_tenant_currency ON fee_ledger(tenant_id, currency);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_fee_ledger_opening ON fee_ledger(tenant_id, currency) WHERE kind = 'opening';
+
+-- Fees used to be booked to a '__house__' balance row; carry those totals over
+-- as opening entries and drop the rows so that no user can spend them.
+INSERT INTO fee_ledger (id, tenant_id, currency, amount, kind)
+SELECT md5(tenant_id || '/' || currency)::uuid, tenant_id, currency, amount, 'opening'
+FROM balances WHERE user_id = '__house__'
+ON CONFLICT DO NOTHING;
+DELETE FROM balances WHERE user_id = '__house__';
+
+-- When the withdrawal entered its current status: expiry counts a pending
+-- withdrawal's age from its approval, not its creation. Before this column
+-- only status changes touched updated_at.
+ALTER TABLE withdrawals ADD COLUMN IF NOT EXISTS status_since TIMESTAMP WITH TIME ZONE;
+UPDATE withdrawals SET status_since = updated_at WHERE status_since IS NULL;
+ALTER TABLE withdrawals ALTER COLUMN status_since SET DEFAULT NOW();
+ALTER TABLE withdrawals ALTER COLUMN status_since SET NOT NULL;
+
+-- Approvals are distinct per actor kind and subject: a service client and an
+-- operator may share a subject, but are not the same approver.
+ALTER TABLE withdrawal_approvals DROP CONSTRAINT IF EXISTS withdrawal_approvals_pkey;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_withdrawal_approvals_actor ON withdrawal_approvals(withdrawal_id, actor_kind, actor_subject);
+
 -- Create indexes for performance
 CREATE INDEX IF NOT EXISTS idx_withdrawals_user_id ON withdrawals(user_id);
 CREATE INDEX IF NOT EXISTS idx_withdrawals_status ON withdrawals(status);
 CREATE INDEX IF NOT EXISTS idx_withdrawals_created_at ON withdrawals(created_at);
 CREATE INDEX IF NOT EXISTS idx_withdrawals_idempotency_key ON withdrawals(idempotency_key);
 CREATE INDEX IF NOT EXISTS idx_balances_user_id ON balances(user_id);
+CREATE INDEX IF NOT EXISTS idx_withdrawal_actions_withdrawal_id ON withdrawal_actions(withdrawal_id);
+CREATE INDEX IF NOT EXISTS idx_api_clients_client_name ON api_clients(client_name);
+CREATE INDEX IF NOT EXISTS idx_withdrawals_tenant_created_at ON withdrawals(tenant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_withdrawals_tenant_user_currency_created_at ON withdrawals(tenant_id, user_id, currency, created_at);
+CREATE INDEX IF NOT EXISTS idx_withdrawals_tenant_status_since ON withdrawals(tenant_id, status, status_since);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_withdrawals_tenant_provider_reference ON withdrawals(tenant_id, provider_reference) WHERE provider_reference IS NOT NULL;
+
+-- Version checked by /readyz, written after everything above; bump it together with migration.SchemaVersion
+CREATE TABLE IF NOT EXISTS schema_version (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    version INT NOT NULL,
+    applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO schema_version (id, version) VALUES (TRUE, 6)
+ON CONFLICT (id) DO UPDATE SET version = EXCLUDED.version, applied_at = NOW();
 
 -- Insert test data
-INSERT INTO balances (user_id, currency, amount) 
-VALUES ('user-123', 'USDT', 1000.00)
-ON CONFLICT (user_id, currency) DO UPDATE SET amount = EXCLUDED.amount;
\ No newline at end of file
+INSERT INTO balances (tenant_id, user_id, currency, amount) 
+VALUES ('default', 'user-123', 'USDT', 1000.00)
+ON CONFLICT (tenant_id, user_id, currency) DO UPDATE SET amount = EXCLUDED.amount;
\ No newline at end of file
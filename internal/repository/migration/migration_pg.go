@@ -3,13 +3,13 @@ package migration
 import (
 	"database/sql"
 	"io/ioutil"
-	"log"
+	"log/slog"
 )
 
 func RunMigrations(db *sql.DB) error {
 	content, err := ioutil.ReadFile("internal/repository/migration/init.sql")
 	if err != nil {
-		log.Printf("Warning: Could not read migration file: %v", err)
+		slog.Warn("could not read migration file", "error", err)
 		return nil
 	}
 
@@ -17,6 +17,6 @@ func RunMigrations(db *sql.DB) error {
 		return err
 	}
 
-	log.Println("Migrations completed")
+	slog.Info("migrations completed")
 	return nil
 }
\ No newline at end of file
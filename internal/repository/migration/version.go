@@ -0,0 +1,28 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// SchemaVersion is the version init.sql writes to schema_version. Bump both
+// together whenever the schema changes in a way this binary depends on.
+const SchemaVersion = 6
+
+const undefinedTable pq.ErrorCode = "42P01"
+
+// CurrentVersion returns the schema version recorded in the database, or 0 if
+// init.sql was never applied.
+func CurrentVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRowContext(ctx, `SELECT version FROM schema_version`).Scan(&version)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == undefinedTable {
+		return 0, nil
+	}
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return version, err
+}
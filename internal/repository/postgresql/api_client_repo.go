@@ -0,0 +1,103 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type apiClientRepository struct {
+	db *sql.DB
+}
+
+func NewAPIClientRepository(db *sql.DB) port.APIClientRepository {
+	return &apiClientRepository{db: db}
+}
+
+const apiKeyColumns = `key_id, client_name, tenant_id, key_hash, scopes, created_at, expires_at, revoked_at`
+
+func scanAPIKey(row rowScanner) (*domain.APIKey, error) {
+	var (
+		k       domain.APIKey
+		scopes  pq.StringArray
+		expires sql.NullTime
+		revoked sql.NullTime
+	)
+	if err := row.Scan(&k.KeyID, &k.ClientName, &k.TenantID, &k.KeyHash, &scopes, &k.CreatedAt, &expires, &revoked); err != nil {
+		return nil, err
+	}
+
+	for _, s := range scopes {
+		k.Scopes = append(k.Scopes, domain.Scope(s))
+	}
+	if expires.Valid {
+		k.ExpiresAt = &expires.Time
+	}
+	if revoked.Valid {
+		k.RevokedAt = &revoked.Time
+	}
+	return &k, nil
+}
+
+func (r *apiClientRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	const query = `INSERT INTO api_clients (` + apiKeyColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	scopes := make(pq.StringArray, 0, len(key.Scopes))
+	for _, s := range key.Scopes {
+		scopes = append(scopes, string(s))
+	}
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, key.KeyID, key.ClientName, key.TenantID, key.KeyHash, scopes,
+		key.CreatedAt, key.ExpiresAt, key.RevokedAt)
+	return err
+}
+
+func (r *apiClientRepository) GetByKeyID(ctx context.Context, keyID string) (*domain.APIKey, error) {
+	const query = `SELECT ` + apiKeyColumns + ` FROM api_clients WHERE key_id = $1`
+
+	k, err := scanAPIKey(conn(ctx, r.db).QueryRowContext(ctx, query, keyID))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+	return k, err
+}
+
+func (r *apiClientRepository) ListByClient(ctx context.Context, clientName string) ([]*domain.APIKey, error) {
+	const query = `SELECT ` + apiKeyColumns + ` FROM api_clients WHERE client_name = $1 ORDER BY created_at`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, clientName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*domain.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func (r *apiClientRepository) Revoke(ctx context.Context, keyID string, at time.Time) error {
+	// An earlier revocation is never pushed back
+	const query = `UPDATE api_clients SET revoked_at = LEAST(COALESCE(revoked_at, $2), $2) WHERE key_id = $1`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, keyID, at)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return domain.ErrAPIKeyNotFound
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type approvalRepository struct {
+	db *sql.DB
+}
+
+func NewApprovalRepository(db *sql.DB) port.ApprovalRepository {
+	return &approvalRepository{db: db}
+}
+
+func (r *approvalRepository) Record(ctx context.Context, tenantID string, a *domain.Approval) error {
+	const query = `INSERT INTO withdrawal_approvals (withdrawal_id, tenant_id, actor_subject, actor_kind, decision, reason, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	if err := requireTenant(tenantID); err != nil {
+		return err
+	}
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, a.WithdrawalID, tenantID, a.ActorSubject, a.ActorKind, a.Decision, a.Reason, a.CreatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueConstraint {
+		return domain.ErrAlreadyDecided
+	}
+	return err
+}
+
+func (r *approvalRepository) CountApprovals(ctx context.Context, tenantID string, withdrawalID uuid.UUID) (int, error) {
+	const query = `SELECT COUNT(*) FROM withdrawal_approvals WHERE tenant_id = $1 AND withdrawal_id = $2 AND decision = 'approve'`
+
+	if err := requireTenant(tenantID); err != nil {
+		return 0, err
+	}
+
+	var n int
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, withdrawalID).Scan(&n)
+	return n, err
+}
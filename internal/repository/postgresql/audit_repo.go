@@ -0,0 +1,148 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+)
+
+type auditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) port.AuditRepository {
+	return &auditRepository{db: db}
+}
+
+// Append runs in the caller's transaction if ctx carries one, in its own otherwise.
+func (r *auditRepository) Append(ctx context.Context, e *domain.AuditEvent) error {
+	if err := requireTenant(e.TenantID); err != nil {
+		return err
+	}
+	if tr, ok := getTr(ctx); ok {
+		return appendAuditEvent(ctx, tr, e)
+	}
+
+	tr, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := appendAuditEvent(ctx, tr, e); err != nil {
+		tr.Rollback()
+		return err
+	}
+	return tr.Commit()
+}
+
+// appendAuditEvent locks the head of the user's chain until the transaction
+// ends, so that concurrent appends to it take turns. Inside WithLock only the
+// same user's transactions ever touch that head, and they already queue for
+// the user's balance row, so the lock adds no contention between users.
+func appendAuditEvent(ctx context.Context, q querier, e *domain.AuditEvent) error {
+	const (
+		ensureHead = `INSERT INTO audit_chain_heads (tenant_id, user_id, seq, hash) VALUES ($1, $2, 0, '')
+		ON CONFLICT (tenant_id, user_id) DO NOTHING`
+		lockHead = `SELECT seq, hash FROM audit_chain_heads WHERE tenant_id = $1 AND user_id = $2 FOR UPDATE`
+		insert   = `INSERT INTO audit_events (tenant_id, user_id, seq, action, entity_type, entity_id,
+		actor_subject, actor_kind, client_ip, request_id, before_state, after_state, created_at, prev_hash, hash)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`
+		moveHead = `UPDATE audit_chain_heads SET seq = $3, hash = $4 WHERE tenant_id = $1 AND user_id = $2`
+	)
+
+	if _, err := q.ExecContext(ctx, ensureHead, e.TenantID, e.UserID); err != nil {
+		return err
+	}
+	var seq int64
+	var prevHash string
+	if err := q.QueryRowContext(ctx, lockHead, e.TenantID, e.UserID).Scan(&seq, &prevHash); err != nil {
+		return err
+	}
+
+	e.Seq = seq + 1
+	e.PrevHash = prevHash
+	e.Hash = e.ComputeHash()
+
+	if _, err := q.ExecContext(ctx, insert, e.TenantID, e.UserID, e.Seq, e.Action, e.EntityType, e.EntityID,
+		e.ActorSubject, e.ActorKind, e.ClientIP, e.RequestID, jsonOrNull(e.Before), jsonOrNull(e.After),
+		e.CreatedAt, e.PrevHash, e.Hash); err != nil {
+		return err
+	}
+	_, err := q.ExecContext(ctx, moveHead, e.TenantID, e.UserID, e.Seq, e.Hash)
+	return err
+}
+
+// jsonOrNull stores an empty snapshot as SQL NULL, which reads back as empty.
+func jsonOrNull(raw []byte) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
+
+func (r *auditRepository) ListChains(ctx context.Context) ([]domain.AuditChain, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT tenant_id, user_id FROM audit_chain_heads ORDER BY tenant_id, user_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chains []domain.AuditChain
+	for rows.Next() {
+		var chain domain.AuditChain
+		if err := rows.Scan(&chain.TenantID, &chain.UserID); err != nil {
+			return nil, err
+		}
+		chains = append(chains, chain)
+	}
+	return chains, rows.Err()
+}
+
+func (r *auditRepository) List(ctx context.Context, chain domain.AuditChain, afterSeq int64, limit int) ([]*domain.AuditEvent, error) {
+	const query = `SELECT tenant_id, user_id, seq, action, entity_type, entity_id, actor_subject, actor_kind,
+		client_ip, request_id, before_state, after_state, created_at, prev_hash, hash
+	FROM audit_events WHERE tenant_id = $1 AND user_id = $2 AND seq > $3 ORDER BY seq LIMIT $4`
+
+	if err := requireTenant(chain.TenantID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, chain.TenantID, chain.UserID, afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*domain.AuditEvent
+	for rows.Next() {
+		var e domain.AuditEvent
+		var before, after sql.NullString
+		if err := rows.Scan(&e.TenantID, &e.UserID, &e.Seq, &e.Action, &e.EntityType, &e.EntityID, &e.ActorSubject, &e.ActorKind,
+			&e.ClientIP, &e.RequestID, &before, &after, &e.CreatedAt, &e.PrevHash, &e.Hash); err != nil {
+			return nil, err
+		}
+		if before.Valid {
+			e.Before = []byte(before.String)
+		}
+		if after.Valid {
+			e.After = []byte(after.String)
+		}
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
+
+func (r *auditRepository) GetHead(ctx context.Context, chain domain.AuditChain) (int64, string, error) {
+	if err := requireTenant(chain.TenantID); err != nil {
+		return 0, "", err
+	}
+
+	var seq int64
+	var hash string
+	err := r.db.QueryRowContext(ctx, `SELECT seq, hash FROM audit_chain_heads WHERE tenant_id = $1 AND user_id = $2`,
+		chain.TenantID, chain.UserID).Scan(&seq, &hash)
+	if err == sql.ErrNoRows {
+		return 0, "", nil
+	}
+	return seq, hash, err
+}
@@ -0,0 +1,107 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"idempot/internal/audit"
+	"idempot/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditRepository_ChainIsAppendOnly(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewAuditRepository(db)
+	balances := NewBalanceRepository(db)
+
+	tenantID := "audit-" + uuid.NewString()[:8]
+	ctx := domain.WithPrincipal(context.Background(), &domain.Principal{Subject: "admin-1", Kind: domain.PrincipalUser, TenantID: tenantID})
+	ctx = domain.WithRequestMeta(ctx, domain.RequestMeta{ClientIP: "203.0.113.7", RequestID: "req-1"})
+
+	for i, daily := range []float64{100, 250.5} {
+		err := balances.WithLock(ctx, tenantID, "user-1", func(txCtx context.Context) error {
+			e, err := domain.NewAuditEvent(txCtx, domain.AuditLimitsChanged, domain.AuditEntityLimits, "user-1", "user-1",
+				nil, &domain.WithdrawalLimits{Currency: "USDT", Daily: daily})
+			if err != nil {
+				return err
+			}
+			if err := repo.Append(txCtx, e); err != nil {
+				return err
+			}
+			assert.Equal(t, int64(i+1), e.Seq)
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	result, err := audit.NewVerifier(repo).Verify(context.Background(), domain.AuditChain{TenantID: tenantID, UserID: "user-1"})
+	require.NoError(t, err)
+	assert.True(t, result.OK(), "%+v", result.Break)
+	assert.Equal(t, int64(2), result.Entries)
+
+	_, err = db.Exec(`UPDATE audit_events SET actor_subject = 'someone-else' WHERE tenant_id = $1`, tenantID)
+	assert.ErrorContains(t, err, "append-only")
+	_, err = db.Exec(`DELETE FROM audit_events WHERE tenant_id = $1`, tenantID)
+	assert.ErrorContains(t, err, "append-only")
+}
+
+type retryCounter struct {
+	retries atomic.Int64
+}
+
+func (c *retryCounter) LockWaited(time.Duration, error) {}
+func (c *retryCounter) LockHeld(time.Duration, error)   {}
+func (c *retryCounter) SerializationRetry()             { c.retries.Add(1) }
+
+// Users of one tenant append to chains of their own, so their transactions
+// do not queue for a shared chain head.
+func TestAuditRepository_UsersDoNotContend(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewAuditRepository(db)
+	counter := &retryCounter{}
+	balances := NewBalanceRepository(db, WithLockObserver(counter))
+
+	tenantID := "audit-" + uuid.NewString()[:8]
+	ctx := domain.WithPrincipal(context.Background(), &domain.Principal{Subject: "admin-1", Kind: domain.PrincipalUser, TenantID: tenantID})
+
+	const users = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, users)
+	for i := 0; i < users; i++ {
+		wg.Add(1)
+		go func(userID string) {
+			defer wg.Done()
+			errs <- balances.WithLock(ctx, tenantID, userID, func(txCtx context.Context) error {
+				e, err := domain.NewAuditEvent(txCtx, domain.AuditLimitsChanged, domain.AuditEntityLimits, userID, userID,
+					nil, &domain.WithdrawalLimits{Currency: "USDT", Daily: 100})
+				if err != nil {
+					return err
+				}
+				return repo.Append(txCtx, e)
+			})
+		}(fmt.Sprintf("user-%d", i))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	// A shared head row would make these appends lose to each other; what is
+	// left are the false positives of page-level predicate locks
+	t.Logf("%d appends, %d serialization retries", users, counter.retries.Load())
+
+	results, err := audit.NewVerifier(repo).VerifyTenant(context.Background(), tenantID)
+	require.NoError(t, err)
+	require.Len(t, results, users)
+	for _, r := range results {
+		assert.True(t, r.OK(), "%s: %+v", r.UserID, r.Break)
+		assert.Equal(t, int64(1), r.Entries)
+	}
+}
@@ -0,0 +1,163 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type destinationRepository struct {
+	db *sql.DB
+}
+
+func NewDestinationRepository(db *sql.DB) port.DestinationRepository {
+	return &destinationRepository{db: db}
+}
+
+const destinationColumns = `id, tenant_id, user_id, currency, network, address, label, created_at, usable_at`
+
+func scanDestination(row rowScanner) (*domain.Destination, error) {
+	var d domain.Destination
+	err := row.Scan(&d.ID, &d.TenantID, &d.UserID, &d.Currency, &d.Network, &d.Address, &d.Label, &d.CreatedAt, &d.UsableAt)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *destinationRepository) Create(ctx context.Context, tenantID string, d *domain.Destination) error {
+	const query = `INSERT INTO destinations (` + destinationColumns + `)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	if err := requireTenant(tenantID); err != nil {
+		return err
+	}
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query,
+		d.ID, tenantID, d.UserID, d.Currency, d.Network, d.Address, d.Label, d.CreatedAt, d.UsableAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueConstraint {
+		return domain.ErrDestinationExists
+	}
+	if err != nil {
+		return err
+	}
+
+	d.TenantID = tenantID
+	return nil
+}
+
+func (r *destinationRepository) GetByID(ctx context.Context, tenantID string, id uuid.UUID) (*domain.Destination, error) {
+	const query = `SELECT ` + destinationColumns + ` FROM destinations WHERE tenant_id = $1 AND id = $2`
+
+	if err := requireTenant(tenantID); err != nil {
+		return nil, err
+	}
+
+	d, err := scanDestination(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, id))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrDestinationNotFound
+	}
+	return d, err
+}
+
+func (r *destinationRepository) Find(ctx context.Context, tenantID string, userID string, currency string, network string, address string) (*domain.Destination, error) {
+	const query = `SELECT ` + destinationColumns + ` FROM destinations
+		WHERE tenant_id = $1 AND user_id = $2 AND currency = $3 AND network = $4 AND address = $5`
+
+	if err := requireTenant(tenantID); err != nil {
+		return nil, err
+	}
+
+	d, err := scanDestination(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, userID, currency, network, address))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return d, err
+}
+
+func (r *destinationRepository) ListByUser(ctx context.Context, tenantID string, userID string) ([]*domain.Destination, error) {
+	const query = `SELECT ` + destinationColumns + ` FROM destinations
+		WHERE tenant_id = $1 AND user_id = $2 ORDER BY created_at, id`
+
+	if err := requireTenant(tenantID); err != nil {
+		return nil, err
+	}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	destinations := []*domain.Destination{}
+	for rows.Next() {
+		d, err := scanDestination(rows)
+		if err != nil {
+			return nil, err
+		}
+		destinations = append(destinations, d)
+	}
+	return destinations, rows.Err()
+}
+
+func (r *destinationRepository) Delete(ctx context.Context, tenantID string, id uuid.UUID) error {
+	const query = `DELETE FROM destinations WHERE tenant_id = $1 AND id = $2`
+
+	if err := requireTenant(tenantID); err != nil {
+		return err
+	}
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, tenantID, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrDestinationNotFound
+	}
+	return nil
+}
+
+func (r *destinationRepository) GetSettings(ctx context.Context, tenantID string, userID string) (*domain.DestinationSettings, error) {
+	const query = `SELECT allow_list_only, enforced_until FROM destination_settings WHERE tenant_id = $1 AND user_id = $2`
+
+	if err := requireTenant(tenantID); err != nil {
+		return nil, err
+	}
+
+	settings := &domain.DestinationSettings{UserID: userID}
+	var until sql.NullTime
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, userID).Scan(&settings.AllowListOnly, &until)
+	if err == sql.ErrNoRows {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if until.Valid {
+		settings.EnforcedUntil = &until.Time
+	}
+	return settings, nil
+}
+
+func (r *destinationRepository) SetSettings(ctx context.Context, tenantID string, s *domain.DestinationSettings) error {
+	const query = `INSERT INTO destination_settings (tenant_id, user_id, allow_list_only, enforced_until, updated_at)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (tenant_id, user_id) DO UPDATE
+	SET allow_list_only = $3, enforced_until = $4, updated_at = $5`
+
+	if err := requireTenant(tenantID); err != nil {
+		return err
+	}
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, tenantID, s.UserID, s.AllowListOnly, s.EnforcedUntil, time.Now())
+	return err
+}
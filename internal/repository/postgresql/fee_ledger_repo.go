@@ -0,0 +1,28 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+)
+
+type feeLedger struct {
+	db *sql.DB
+}
+
+func NewFeeLedger(db *sql.DB) port.FeeLedger {
+	return &feeLedger{db: db}
+}
+
+func (r *feeLedger) Record(ctx context.Context, e *domain.FeeEntry) error {
+	const query = `INSERT INTO fee_ledger (id, tenant_id, withdrawal_id, currency, amount, kind, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	if err := requireTenant(e.TenantID); err != nil {
+		return err
+	}
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, e.ID, e.TenantID, e.WithdrawalID, e.Currency, e.Amount, e.Kind, e.CreatedAt)
+	return err
+}
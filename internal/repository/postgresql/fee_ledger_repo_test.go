@@ -0,0 +1,61 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"idempot/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Fee-bearing withdrawals of different users must not conflict: each books its
+// fee as a new ledger row instead of updating one shared balance.
+func TestFeeLedger_ConcurrentChargesDoNotConflict(t *testing.T) {
+	db := openTestDB(t)
+	ledger := NewFeeLedger(db)
+	balances := NewBalanceRepository(db)
+
+	tenantID := "fees-" + uuid.NewString()[:8]
+	ctx := context.Background()
+
+	const users = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, users)
+	for i := 0; i < users; i++ {
+		wg.Add(1)
+		go func(userID string) {
+			defer wg.Done()
+			errs <- balances.WithLock(ctx, tenantID, userID, func(txCtx context.Context) error {
+				return ledger.Record(txCtx, &domain.FeeEntry{
+					ID:           uuid.New(),
+					TenantID:     tenantID,
+					WithdrawalID: uuid.New(),
+					Currency:     "USDT",
+					Amount:       1.5,
+					Kind:         domain.FeeCharged,
+					CreatedAt:    time.Now(),
+				})
+			})
+		}(fmt.Sprintf("user-%d", i))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	var total float64
+	require.NoError(t, db.QueryRow(`SELECT SUM(amount) FROM fee_ledger WHERE tenant_id = $1`, tenantID).Scan(&total))
+	assert.Equal(t, users*1.5, total)
+
+	_, err := db.Exec(`UPDATE fee_ledger SET amount = 0 WHERE tenant_id = $1`, tenantID)
+	assert.ErrorContains(t, err, "append-only")
+	_, err = db.Exec(`DELETE FROM fee_ledger WHERE tenant_id = $1`, tenantID)
+	assert.ErrorContains(t, err, "append-only")
+}
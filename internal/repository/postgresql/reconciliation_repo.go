@@ -0,0 +1,33 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+)
+
+type reconciliationRepository struct {
+	db *sql.DB
+}
+
+func NewReconciliationRepository(db *sql.DB) port.ReconciliationRepository {
+	return &reconciliationRepository{db: db}
+}
+
+func (r *reconciliationRepository) SaveRun(ctx context.Context, run *domain.ReconciliationRun) error {
+	const query = `INSERT INTO reconciliation_runs (id, tenant_id, source_file, period_start, period_end, matched, missing, extra,
+		amount_mismatched, status_mismatched, fixed, items, started_at, finished_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+
+	items, err := json.Marshal(run.Discrepancies)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn(ctx, r.db).ExecContext(ctx, query, run.ID, run.TenantID, run.SourceFile, run.PeriodStart, run.PeriodEnd,
+		run.Matched, run.Missing, run.Extra, run.AmountMismatched, run.StatusMismatched, run.Fixed,
+		items, run.StartedAt, run.FinishedAt)
+	return err
+}
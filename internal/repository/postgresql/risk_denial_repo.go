@@ -0,0 +1,30 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+)
+
+type riskDenialRepository struct {
+	db *sql.DB
+}
+
+func NewRiskDenialRepository(db *sql.DB) port.RiskDenialRepository {
+	return &riskDenialRepository{db: db}
+}
+
+func (r *riskDenialRepository) Record(ctx context.Context, d *domain.RiskDenial) error {
+	const query = `INSERT INTO risk_denials (id, tenant_id, user_id, idempotency_key, amount, currency, network, destination,
+		rule, reason, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	if err := requireTenant(d.TenantID); err != nil {
+		return err
+	}
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, d.ID, d.TenantID, d.UserID, d.IdempotencyKey, d.Amount, d.Currency, d.Network,
+		d.Destination, d.Rule, d.Reason, d.CreatedAt)
+	return err
+}
@@ -0,0 +1,30 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+)
+
+type screeningHitRepository struct {
+	db *sql.DB
+}
+
+func NewScreeningHitRepository(db *sql.DB) port.ScreeningHitRepository {
+	return &screeningHitRepository{db: db}
+}
+
+func (r *screeningHitRepository) Record(ctx context.Context, h *domain.ScreeningHit) error {
+	const query = `INSERT INTO screening_hits (id, tenant_id, user_id, idempotency_key, currency, network, destination,
+		list_name, list_version, entry_type, entry_value, entity, reference, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+
+	if err := requireTenant(h.TenantID); err != nil {
+		return err
+	}
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, h.ID, h.TenantID, h.UserID, h.IdempotencyKey, h.Currency, h.Network, h.Destination,
+		h.List, h.ListVersion, h.Entry.Type, h.Entry.Value, h.Entry.Entity, h.Entry.Reference, h.CreatedAt)
+	return err
+}
@@ -0,0 +1,123 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"idempot/internal/domain"
+	"idempot/internal/service"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openTestDB connects to TEST_DATABASE_URL and applies the schema, skipping
+// the test when no database is configured.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", url)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	schema, err := os.ReadFile("../migration/init.sql")
+	require.NoError(t, err)
+	_, err = db.Exec(string(schema))
+	require.NoError(t, err)
+	return db
+}
+
+func TestTenantIsolation(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	withdrawals := NewWithdrawalRepository(db)
+	balances := NewBalanceRepository(db)
+
+	// Unique tenants keep reruns against the same database independent
+	suffix := uuid.NewString()[:8]
+	tenantA, tenantB := "brand-a-"+suffix, "brand-b-"+suffix
+	const userID, key = "user-1", "same-key"
+
+	newWithdrawal := func() *domain.Withdrawal {
+		now := time.Now()
+		return &domain.Withdrawal{
+			ID: uuid.New(), UserID: userID, Amount: 10, Currency: "USDT", Destination: "0x1",
+			IdempotencyKey: key, Status: domain.StatusPending, CreatedAt: now, UpdatedAt: now,
+		}
+	}
+
+	// The same user and idempotency key exist independently in both tenants
+	a, b := newWithdrawal(), newWithdrawal()
+	require.NoError(t, withdrawals.Create(ctx, tenantA, a))
+	require.NoError(t, withdrawals.Create(ctx, tenantB, b))
+	assert.Equal(t, domain.ErrDuplicateRequest, withdrawals.Create(ctx, tenantA, newWithdrawal()))
+
+	got, err := withdrawals.GetByIdempotencyKey(ctx, tenantA, key)
+	require.NoError(t, err)
+	assert.Equal(t, a.ID, got.ID)
+	assert.Equal(t, tenantA, got.TenantID)
+
+	_, err = withdrawals.GetByID(ctx, tenantB, a.ID)
+	assert.Equal(t, domain.ErrWithdrawalNotFound, err)
+	assert.Equal(t, domain.ErrWithdrawalNotFound, withdrawals.TransitionStatus(ctx, tenantB, a.ID, domain.StatusPending, domain.StatusConfirmed))
+	assert.Equal(t, domain.ErrWithdrawalNotFound, withdrawals.UpdateStatus(ctx, tenantB, a.ID, domain.StatusFailed))
+
+	got, err = withdrawals.GetByID(ctx, tenantA, a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusPending, got.Status)
+
+	listed, err := withdrawals.ListCreatedBetween(ctx, tenantB, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, b.ID, listed[0].ID)
+
+	tenants, err := withdrawals.ListTenants(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, tenants, tenantA)
+	assert.Contains(t, tenants, tenantB)
+
+	// Balances of the same user id are separate per tenant
+	require.NoError(t, balances.UpdateBalance(ctx, tenantA, userID, "USDT", 100))
+	require.NoError(t, balances.WithLock(ctx, tenantB, userID, func(txCtx context.Context) error {
+		return balances.UpdateBalance(txCtx, tenantB, userID, "USDT", 5)
+	}))
+
+	balA, err := balances.GetBalance(ctx, tenantA, userID, "USDT")
+	require.NoError(t, err)
+	balB, err := balances.GetBalance(ctx, tenantB, userID, "USDT")
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, balA.Amount)
+	assert.Equal(t, 5.0, balB.Amount)
+
+	_, err = withdrawals.GetByID(ctx, "", a.ID)
+	assert.Equal(t, domain.ErrMissingTenant, err)
+}
+
+// A context without a principal has no tenant, and no query runs without one.
+// The repositories reject it before touching the database, so none is needed.
+func TestTenantIsolation_ContextWithoutPrincipalIsRejected(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, domain.TenantFromContext(ctx))
+
+	withdrawals := service.NewWithdrawalService(NewWithdrawalRepository(nil), NewBalanceRepository(nil))
+	_, err := withdrawals.CreateWithdrawal(ctx, &domain.WithdrawalReq{
+		UserID: "user-1", Amount: 10, Currency: "USDT", Destination: "0x123", IdempotencyKey: "key-1",
+	})
+	assert.ErrorIs(t, err, domain.ErrMissingTenant)
+
+	_, err = withdrawals.GetWithdrawal(ctx, uuid.New())
+	assert.ErrorIs(t, err, domain.ErrMissingTenant)
+
+	destinations := service.NewDestinationService(NewDestinationRepository(nil), nil, 0)
+	_, err = destinations.ListDestinations(ctx, "user-1")
+	assert.ErrorIs(t, err, domain.ErrMissingTenant)
+}
@@ -0,0 +1,33 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+
+	"github.com/lib/pq"
+)
+
+type withdrawalActionRepository struct {
+	db *sql.DB
+}
+
+func NewWithdrawalActionRepository(db *sql.DB) port.WithdrawalActionRepository {
+	return &withdrawalActionRepository{db: db}
+}
+
+func (r *withdrawalActionRepository) Record(ctx context.Context, a *domain.WithdrawalActionRecord) error {
+	const query = `INSERT INTO withdrawal_actions (withdrawal_id, action, from_status, to_status,
+		actor_subject, actor_kind, actor_roles, reason, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	roles := make(pq.StringArray, 0, len(a.ActorRoles))
+	for _, role := range a.ActorRoles {
+		roles = append(roles, string(role))
+	}
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, a.WithdrawalID, a.Action, a.FromStatus, a.ToStatus,
+		a.ActorSubject, a.ActorKind, roles, a.Reason, a.CreatedAt)
+	return err
+}
@@ -0,0 +1,67 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"idempot/internal/port"
+	"time"
+)
+
+type withdrawalHistory struct {
+	db *sql.DB
+}
+
+func NewWithdrawalHistory(db *sql.DB) port.WithdrawalHistory {
+	return &withdrawalHistory{db: db}
+}
+
+func (r *withdrawalHistory) CountCreatedSince(ctx context.Context, tenantID string, userID string, since time.Time) (int, error) {
+	const query = `SELECT COUNT(*) FROM withdrawals WHERE tenant_id = $1 AND user_id = $2 AND created_at >= $3`
+
+	if err := requireTenant(tenantID); err != nil {
+		return 0, err
+	}
+
+	var n int
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, userID, since).Scan(&n)
+	return n, err
+}
+
+func (r *withdrawalHistory) RecentConfirmedAmounts(ctx context.Context, tenantID string, userID string, currency string, limit int) ([]float64, error) {
+	const query = `SELECT amount FROM withdrawals
+		WHERE tenant_id = $1 AND user_id = $2 AND currency = $3 AND status = 'confirmed'
+		ORDER BY created_at DESC LIMIT $4`
+
+	if err := requireTenant(tenantID); err != nil {
+		return nil, err
+	}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, userID, currency, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var amounts []float64
+	for rows.Next() {
+		var a float64
+		if err := rows.Scan(&a); err != nil {
+			return nil, err
+		}
+		amounts = append(amounts, a)
+	}
+	return amounts, rows.Err()
+}
+
+func (r *withdrawalHistory) HasConfirmedTo(ctx context.Context, tenantID string, userID string, currency string, network string, destination string) (bool, error) {
+	const query = `SELECT EXISTS(SELECT 1 FROM withdrawals
+		WHERE tenant_id = $1 AND user_id = $2 AND currency = $3 AND network = $4 AND destination = $5 AND status = 'confirmed')`
+
+	if err := requireTenant(tenantID); err != nil {
+		return false, err
+	}
+
+	var exists bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, userID, currency, network, destination).Scan(&exists)
+	return exists, err
+}
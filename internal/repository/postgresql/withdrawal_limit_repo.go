@@ -0,0 +1,53 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+	"time"
+)
+
+type withdrawalLimitRepository struct {
+	db *sql.DB
+}
+
+func NewWithdrawalLimitRepository(db *sql.DB) port.WithdrawalLimitRepository {
+	return &withdrawalLimitRepository{db: db}
+}
+
+func (r *withdrawalLimitRepository) GetUserLimits(ctx context.Context, tenantID string, userID string, currency string) (*domain.WithdrawalLimits, error) {
+	const query = `SELECT currency, min_per_transaction, max_per_transaction, daily_limit, monthly_limit
+		FROM withdrawal_limits WHERE tenant_id = $1 AND user_id = $2 AND currency = $3`
+
+	if err := requireTenant(tenantID); err != nil {
+		return nil, err
+	}
+
+	var l domain.WithdrawalLimits
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, userID, currency).
+		Scan(&l.Currency, &l.MinPerTransaction, &l.MaxPerTransaction, &l.Daily, &l.Monthly)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *withdrawalLimitRepository) SetUserLimits(ctx context.Context, tenantID string, userID string, l *domain.WithdrawalLimits) error {
+	const query = `INSERT INTO withdrawal_limits (tenant_id, user_id, currency,
+		min_per_transaction, max_per_transaction, daily_limit, monthly_limit, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (tenant_id, user_id, currency) DO UPDATE
+	SET min_per_transaction = $4, max_per_transaction = $5, daily_limit = $6, monthly_limit = $7, updated_at = $8`
+
+	if err := requireTenant(tenantID); err != nil {
+		return err
+	}
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, tenantID, userID, l.Currency,
+		l.MinPerTransaction, l.MaxPerTransaction, l.Daily, l.Monthly, time.Now())
+	return err
+}
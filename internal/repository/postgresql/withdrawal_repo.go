@@ -3,39 +3,80 @@ package postgresql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"idempot/internal/domain"
 	"idempot/internal/port"
+	"idempot/internal/tracing"
+	"log/slog"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ctxtype string
 
+const tracerName = "idempot/internal/repository/postgresql"
+
 const (
 	trKey ctxtype = "tx"
 )
 
 var (
-	uniqueConstraint   pq.ErrorCode = "23505"
-	lockNotAvailable   pq.ErrorCode = "55P03"
+	uniqueConstraint     pq.ErrorCode = "23505"
+	lockNotAvailable     pq.ErrorCode = "55P03"
+	serializationFailure pq.ErrorCode = "40001"
 )
 
+// maxSerializationRetries bounds how often WithLock reruns a transaction that
+// lost a serialization conflict, e.g. a false positive of page-level predicate locks.
+const maxSerializationRetries = 3
+
 type withdrawalRepository struct {
 	db *sql.DB
 }
 
 type balanceRepository struct {
-	db *sql.DB
+	db    *sql.DB
+	locks port.LockObserver
+}
+
+type BalanceOption func(*balanceRepository)
+
+// WithLockObserver reports how long WithLock waits for and holds balance locks.
+func WithLockObserver(locks port.LockObserver) BalanceOption {
+	return func(r *balanceRepository) {
+		r.locks = locks
+	}
 }
 
+type noopLockObserver struct{}
+
+func (noopLockObserver) LockWaited(time.Duration, error) {}
+func (noopLockObserver) LockHeld(time.Duration, error)   {}
+func (noopLockObserver) SerializationRetry()             {}
+
 func NewWithdrawalRepository(db *sql.DB) port.WithdrawalRepository {
 	return &withdrawalRepository{db: db}
 }
 
-func NewBalanceRepository(db *sql.DB) port.BalanceRepository {
-	return &balanceRepository{db: db}
+func NewBalanceRepository(db *sql.DB, opts ...BalanceOption) port.BalanceRepository {
+	r := &balanceRepository{db: db, locks: noopLockObserver{}}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// requireTenant guards against queries that would otherwise not be scoped.
+func requireTenant(tenantID string) error {
+	if tenantID == "" {
+		return domain.ErrMissingTenant
+	}
+	return nil
 }
 
 func getTr(ctx context.Context) (*sql.Tx, bool) {
@@ -43,87 +84,128 @@ func getTr(ctx context.Context) (*sql.Tx, bool) {
 	return tr, ok
 }
 
-func (wr *withdrawalRepository) Create(ctx context.Context, w *domain.Withdrawal) error {
-	const query = `INSERT INTO withdrawals (id, user_id, amount, currency, destination, idempotency_key, status, created_at, updated_at)
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// conn returns the transaction stored in ctx, falling back to the pool.
+func conn(ctx context.Context, db *sql.DB) querier {
+	if tr, ok := getTr(ctx); ok {
+		return tr
+	}
+	return db
+}
+
+// Withdrawals created before fees have no net_amount and paid no fee
+const withdrawalColumns = `id, tenant_id, user_id, amount, fee, COALESCE(net_amount, amount), currency, network, destination, idempotency_key, status,
+	requested_by, risk_decision, risk_rule, risk_reason, COALESCE(provider_reference, ''), created_at, updated_at, status_since`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWithdrawal(row rowScanner) (*domain.Withdrawal, error) {
+	var w domain.Withdrawal
+	err := row.Scan(&w.ID, &w.TenantID, &w.UserID, &w.Amount, &w.Fee, &w.NetAmount, &w.Currency, &w.Network, &w.Destination, &w.IdempotencyKey, &w.Status,
+		&w.RequestedBy, &w.RiskDecision, &w.RiskRule, &w.RiskReason, &w.ProviderReference, &w.CreatedAt, &w.UpdatedAt, &w.StatusSince)
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (wr *withdrawalRepository) Create(ctx context.Context, tenantID string, w *domain.Withdrawal) error {
+	const query = `INSERT INTO withdrawals (id, tenant_id, user_id, amount, fee, net_amount, currency, network, destination, idempotency_key, status, requested_by,
+		risk_decision, risk_rule, risk_reason, created_at, updated_at, status_since)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $16)`
+
+	if err := requireTenant(tenantID); err != nil {
+		return err
+	}
 
 	tr, ok := getTr(ctx)
 
 	var err error
 	if ok {
-		_, err = tr.ExecContext(ctx, query, w.ID, w.UserID, w.Amount, w.Currency, w.Destination, w.IdempotencyKey, w.Status, w.CreatedAt, w.UpdatedAt)
+		_, err = tr.ExecContext(ctx, query, w.ID, tenantID, w.UserID, w.Amount, w.Fee, w.NetAmount, w.Currency, w.Network, w.Destination, w.IdempotencyKey, w.Status, w.RequestedBy,
+			w.RiskDecision, w.RiskRule, w.RiskReason, w.CreatedAt, w.UpdatedAt)
 	} else {
-		_, err = wr.db.ExecContext(ctx, query, w.ID, w.UserID, w.Amount, w.Currency, w.Destination, w.IdempotencyKey, w.Status, w.CreatedAt, w.UpdatedAt)
+		_, err = wr.db.ExecContext(ctx, query, w.ID, tenantID, w.UserID, w.Amount, w.Fee, w.NetAmount, w.Currency, w.Network, w.Destination, w.IdempotencyKey, w.Status, w.RequestedBy,
+			w.RiskDecision, w.RiskRule, w.RiskReason, w.CreatedAt, w.UpdatedAt)
 	}
 
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueConstraint {
-			if pqErr.Constraint == "withdrawals_idempotency_key_key" {
+			if pqErr.Constraint == "idx_withdrawals_tenant_idempotency_key" {
 				return domain.ErrDuplicateRequest
 			}
 		}
 		return err
 	}
 
+	w.TenantID = tenantID
+	w.StatusSince = w.CreatedAt
 	return nil
 }
 
-func (r *withdrawalRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Withdrawal, error) {
-	var w domain.Withdrawal
-	const query = `SELECT id, user_id, amount, currency, destination, idempotency_key, status, created_at, updated_at 
-              FROM withdrawals WHERE id = $1`
+func (r *withdrawalRepository) GetByID(ctx context.Context, tenantID string, id uuid.UUID) (*domain.Withdrawal, error) {
+	const query = `SELECT ` + withdrawalColumns + ` FROM withdrawals WHERE tenant_id = $1 AND id = $2`
 
-	tx, ok := getTr(ctx)
-	var err error
-	if ok {
-		err = tx.QueryRowContext(ctx, query, id).Scan(
-			&w.ID, &w.UserID, &w.Amount, &w.Currency, &w.Destination, &w.IdempotencyKey, &w.Status, &w.CreatedAt, &w.UpdatedAt,
-		)
-	} else {
-		err = r.db.QueryRowContext(ctx, query, id).Scan(
-			&w.ID, &w.UserID, &w.Amount, &w.Currency, &w.Destination, &w.IdempotencyKey, &w.Status, &w.CreatedAt, &w.UpdatedAt,
-		)
+	if err := requireTenant(tenantID); err != nil {
+		return nil, err
 	}
 
+	w, err := scanWithdrawal(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, id))
 	if err == sql.ErrNoRows {
 		return nil, domain.ErrWithdrawalNotFound
 	}
-	return &w, err
+	return w, err
 }
 
-func (r *withdrawalRepository) GetByIdempotencyKey(ctx context.Context, key string) (*domain.Withdrawal, error) {
-	var w domain.Withdrawal
-	const query = `SELECT id, user_id, amount, currency, destination, idempotency_key, status, created_at, updated_at 
-              FROM withdrawals WHERE idempotency_key = $1`
+func (r *withdrawalRepository) GetByIdempotencyKey(ctx context.Context, tenantID string, key string) (*domain.Withdrawal, error) {
+	const query = `SELECT ` + withdrawalColumns + ` FROM withdrawals WHERE tenant_id = $1 AND idempotency_key = $2`
 
-	tx, ok := getTr(ctx)
-	var err error
-	if ok {
-		err = tx.QueryRowContext(ctx, query, key).Scan(
-			&w.ID, &w.UserID, &w.Amount, &w.Currency, &w.Destination, &w.IdempotencyKey, &w.Status, &w.CreatedAt, &w.UpdatedAt,
-		)
-	} else {
-		err = r.db.QueryRowContext(ctx, query, key).Scan(
-			&w.ID, &w.UserID, &w.Amount, &w.Currency, &w.Destination, &w.IdempotencyKey, &w.Status, &w.CreatedAt, &w.UpdatedAt,
-		)
+	if err := requireTenant(tenantID); err != nil {
+		return nil, err
 	}
 
+	w, err := scanWithdrawal(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, key))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
-	return &w, err
+	return w, err
+}
+
+func (r *withdrawalRepository) GetByProviderReference(ctx context.Context, tenantID string, reference string) (*domain.Withdrawal, error) {
+	const query = `SELECT ` + withdrawalColumns + ` FROM withdrawals WHERE tenant_id = $1 AND provider_reference = $2`
+
+	if err := requireTenant(tenantID); err != nil {
+		return nil, err
+	}
+
+	w, err := scanWithdrawal(conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, reference))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrWithdrawalNotFound
+	}
+	return w, err
 }
 
-func (r *withdrawalRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status domain.WithdrawalStatus) error {
-	const query = `UPDATE withdrawals SET status = $1, updated_at = $2 WHERE id = $3`
+func (r *withdrawalRepository) UpdateStatus(ctx context.Context, tenantID string, id uuid.UUID, status domain.WithdrawalStatus) error {
+	const query = `UPDATE withdrawals SET status = $1, updated_at = $2, status_since = $2 WHERE tenant_id = $3 AND id = $4`
+
+	if err := requireTenant(tenantID); err != nil {
+		return err
+	}
 
 	tx, ok := getTr(ctx)
 	var result sql.Result
 	var err error
 	if ok {
-		result, err = tx.ExecContext(ctx, query, status, time.Now(), id)
+		result, err = tx.ExecContext(ctx, query, status, time.Now(), tenantID, id)
 	} else {
-		result, err = r.db.ExecContext(ctx, query, status, time.Now(), id)
+		result, err = r.db.ExecContext(ctx, query, status, time.Now(), tenantID, id)
 	}
 
 	if err != nil {
@@ -137,27 +219,186 @@ func (r *withdrawalRepository) UpdateStatus(ctx context.Context, id uuid.UUID, s
 	return nil
 }
 
+func (r *withdrawalRepository) TransitionStatus(ctx context.Context, tenantID string, id uuid.UUID, from, status domain.WithdrawalStatus) error {
+	const query = `UPDATE withdrawals SET status = $1, updated_at = $2, status_since = $2 WHERE tenant_id = $3 AND id = $4 AND status = $5`
+
+	if err := requireTenant(tenantID); err != nil {
+		return err
+	}
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, status, time.Now(), tenantID, id, from)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		var exists bool
+		if err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM withdrawals WHERE tenant_id = $1 AND id = $2)`, tenantID, id).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return domain.ErrWithdrawalNotFound
+		}
+		return domain.ErrStatusConflict
+	}
+	return nil
+}
+
+func (r *withdrawalRepository) ListInStatusBefore(ctx context.Context, tenantID string, status domain.WithdrawalStatus, before time.Time, after domain.PageCursor, limit int) ([]*domain.Withdrawal, error) {
+	// Served by idx_withdrawals_tenant_status_since
+	const query = `SELECT ` + withdrawalColumns + `
+		FROM withdrawals
+		WHERE tenant_id = $1 AND status = $2 AND status_since < $3 AND (status_since, id) > ($4, $5)
+		ORDER BY status_since, id
+		LIMIT $6`
+
+	if err := requireTenant(tenantID); err != nil {
+		return nil, err
+	}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, status, before, after.At, after.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanWithdrawals(rows)
+}
+
+func (r *withdrawalRepository) ListCreatedBetween(ctx context.Context, tenantID string, from, to time.Time) ([]*domain.Withdrawal, error) {
+	const query = `SELECT ` + withdrawalColumns + `
+		FROM withdrawals
+		WHERE tenant_id = $1 AND created_at >= $2 AND created_at < $3
+		ORDER BY created_at, id`
+
+	if err := requireTenant(tenantID); err != nil {
+		return nil, err
+	}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, tenantID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return scanWithdrawals(rows)
+}
+
+func (r *withdrawalRepository) SumCreatedSince(ctx context.Context, tenantID string, userID string, currency string, since time.Time) (float64, error) {
+	// Served by idx_withdrawals_tenant_user_currency_created_at
+	const query = `SELECT COALESCE(SUM(amount), 0) FROM withdrawals
+		WHERE tenant_id = $1 AND user_id = $2 AND currency = $3 AND created_at >= $4
+		AND status NOT IN ('failed', 'expired', 'cancelled', 'rejected')`
+
+	if err := requireTenant(tenantID); err != nil {
+		return 0, err
+	}
+
+	var sum float64
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, tenantID, userID, currency, since).Scan(&sum)
+	return sum, err
+}
+
+func (r *withdrawalRepository) ListTenants(ctx context.Context) ([]string, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT DISTINCT tenant_id FROM withdrawals ORDER BY tenant_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tenants []string
+	for rows.Next() {
+		var tenantID string
+		if err := rows.Scan(&tenantID); err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, tenantID)
+	}
+	return tenants, rows.Err()
+}
+
+func (r *withdrawalRepository) CountPendingBeforeAllTenants(ctx context.Context, before time.Time) (int, error) {
+	// No tenant filter and no requireTenant: this is the readiness backlog of all tenants
+	const query = `SELECT COUNT(*) FROM withdrawals WHERE status = 'pending' AND status_since < $1`
+
+	var count int
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, before).Scan(&count)
+	return count, err
+}
+
+func scanWithdrawals(rows *sql.Rows) ([]*domain.Withdrawal, error) {
+	defer rows.Close()
+
+	var result []*domain.Withdrawal
+	for rows.Next() {
+		w, err := scanWithdrawal(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, w)
+	}
+	return result, rows.Err()
+}
+
 //--------------------Balance
 
-func (r *balanceRepository) GetBalance(ctx context.Context, userID string, currency string) (*domain.Balance, error) {
+func (r *balanceRepository) GetBalance(ctx context.Context, tenantID string, userID string, currency string) (*domain.Balance, error) {
 	var balance domain.Balance
-	const query = `SELECT user_id, amount, currency FROM balances WHERE user_id = $1 AND currency = $2`
+	const query = `SELECT tenant_id, user_id, amount, currency FROM balances WHERE tenant_id = $1 AND user_id = $2 AND currency = $3`
+
+	if err := requireTenant(tenantID); err != nil {
+		return nil, err
+	}
 
 	tr, ok := getTr(ctx)
 	var err error
 	if ok {
-		err = tr.QueryRowContext(ctx, query, userID, currency).Scan(&balance.UserID, &balance.Amount, &balance.Currency)
+		err = tr.QueryRowContext(ctx, query, tenantID, userID, currency).Scan(&balance.TenantID, &balance.UserID, &balance.Amount, &balance.Currency)
 	} else {
-		err = r.db.QueryRowContext(ctx, query, userID, currency).Scan(&balance.UserID, &balance.Amount, &balance.Currency)
+		err = r.db.QueryRowContext(ctx, query, tenantID, userID, currency).Scan(&balance.TenantID, &balance.UserID, &balance.Amount, &balance.Currency)
 	}
 
 	if err == sql.ErrNoRows {
-		return &domain.Balance{UserID: userID, Amount: 0, Currency: currency}, nil
+		return &domain.Balance{TenantID: tenantID, UserID: userID, Amount: 0, Currency: currency}, nil
 	}
 	return &balance, err
 }
 
-func (r *balanceRepository) WithLock(ctx context.Context, userID string, fn func(ctx context.Context) error) error {
+func (r *balanceRepository) WithLock(ctx context.Context, tenantID string, userID string, fn func(ctx context.Context) error) (err error) {
+	if err := requireTenant(tenantID); err != nil {
+		return err
+	}
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "BalanceRepository.WithLock",
+		trace.WithAttributes(attribute.String("tenant.id", tenantID)))
+	defer tracing.End(span, &err)
+
+	for attempt := 0; ; attempt++ {
+		span.SetAttributes(attribute.Int("lock.attempts", attempt+1))
+		err := r.withLockOnce(ctx, tenantID, userID, fn)
+		if !isSerializationFailure(err) || attempt >= maxSerializationRetries || ctx.Err() != nil {
+			if errors.Is(err, domain.ErrLockTimeout) {
+				slog.DebugContext(ctx, "balance row is locked", "tenant_id", tenantID)
+			}
+			return err
+		}
+		r.locks.SerializationRetry()
+		slog.WarnContext(ctx, "serialization failure, retrying transaction", "tenant_id", tenantID, "attempt", attempt+1)
+	}
+}
+
+func isSerializationFailure(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == serializationFailure
+}
+
+func (r *balanceRepository) withLockOnce(ctx context.Context, tenantID string, userID string, fn func(ctx context.Context) error) (err error) {
+	start := time.Now()
+	var lockedAt time.Time
+	defer func() {
+		if lockedAt.IsZero() {
+			r.locks.LockWaited(time.Since(start), err)
+			return
+		}
+		r.locks.LockHeld(time.Since(lockedAt), err)
+	}()
+
 	// Serializable
 	tr, err := r.db.BeginTx(ctx, &sql.TxOptions{
 		Isolation: sql.LevelSerializable,
@@ -175,7 +416,7 @@ func (r *balanceRepository) WithLock(ctx context.Context, userID string, fn func
 	}()
 
 	// блокировка строки баланса пользователя
-	_, err = tr.ExecContext(ctx, "SELECT id FROM balances WHERE user_id = $1 FOR UPDATE NOWAIT", userID)
+	_, err = tr.ExecContext(ctx, "SELECT id FROM balances WHERE tenant_id = $1 AND user_id = $2 FOR UPDATE NOWAIT", tenantID, userID)
 	if err != nil {
 		tr.Rollback()
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == lockNotAvailable {
@@ -184,14 +425,14 @@ func (r *balanceRepository) WithLock(ctx context.Context, userID string, fn func
 		if err == sql.ErrNoRows {
 			//Если нет записи, создаем
 			_, err = tr.ExecContext(ctx,
-				"INSERT INTO balances (user_id, currency, amount) VALUES ($1, 'USDT', 0) ON CONFLICT DO NOTHING",
-				userID)
+				"INSERT INTO balances (tenant_id, user_id, currency, amount) VALUES ($1, $2, 'USDT', 0) ON CONFLICT DO NOTHING",
+				tenantID, userID)
 			if err != nil {
 				return err
 			}
 
 			//Защита от идемпотентности(двойное списание)
-			_, err = tr.ExecContext(ctx, "SELECT id FROM balances WHERE user_id = $1 FOR UPDATE NOWAIT", userID)
+			_, err = tr.ExecContext(ctx, "SELECT id FROM balances WHERE tenant_id = $1 AND user_id = $2 FOR UPDATE NOWAIT", tenantID, userID)
 			if err != nil {
 				tr.Rollback()
 				if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == lockNotAvailable {
@@ -204,6 +445,11 @@ func (r *balanceRepository) WithLock(ctx context.Context, userID string, fn func
 		}
 	}
 
+	lockedAt = time.Now()
+	r.locks.LockWaited(lockedAt.Sub(start), nil)
+	trace.SpanFromContext(ctx).AddEvent("balance locked",
+		trace.WithAttributes(attribute.Int64("lock.wait_us", lockedAt.Sub(start).Microseconds())))
+
 	txCtx := context.WithValue(ctx, trKey, tr)
 
 	if err := fn(txCtx); err != nil {
@@ -218,20 +464,24 @@ func (r *balanceRepository) WithLock(ctx context.Context, userID string, fn func
 	return nil
 }
 
-func (r *balanceRepository) UpdateBalance(ctx context.Context, userID string, currency string, amount float64) error {
+func (r *balanceRepository) UpdateBalance(ctx context.Context, tenantID string, userID string, currency string, amount float64) error {
     query := `
-        INSERT INTO balances (user_id, currency, amount, updated_at)
-        VALUES ($1, $2, $3, $4)
-        ON CONFLICT (user_id, currency) DO UPDATE 
-        SET amount = balances.amount + $3, updated_at = $4
+        INSERT INTO balances (tenant_id, user_id, currency, amount, updated_at)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (tenant_id, user_id, currency) DO UPDATE 
+        SET amount = balances.amount + $4, updated_at = $5
     `
+
+    if err := requireTenant(tenantID); err != nil {
+        return err
+    }
     
     tr, ok := getTr(ctx)
     var err error
     if ok {
-        _, err = tr.ExecContext(ctx, query, userID, currency, amount, time.Now())
+        _, err = tr.ExecContext(ctx, query, tenantID, userID, currency, amount, time.Now())
     } else {
-        _, err = r.db.ExecContext(ctx, query, userID, currency, amount, time.Now())
+        _, err = r.db.ExecContext(ctx, query, tenantID, userID, currency, amount, time.Now())
     }
     return err
 }
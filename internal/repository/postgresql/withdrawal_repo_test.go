@@ -0,0 +1,45 @@
+package postgresql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"idempot/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A withdrawal approved after the expiry TTL is pending from its approval on,
+// so it is not listed for expiry until a full TTL later.
+func TestWithdrawalRepository_StatusSinceRestartsOnApproval(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	withdrawals := NewWithdrawalRepository(db)
+	tenantID := "expiry-" + uuid.NewString()[:8]
+
+	created := time.Now().Add(-48 * time.Hour)
+	held := &domain.Withdrawal{
+		ID: uuid.New(), UserID: "user-1", Amount: 10, Currency: "USDT", Destination: "0x1",
+		IdempotencyKey: "held", Status: domain.StatusAwaitingApproval, CreatedAt: created, UpdatedAt: created,
+	}
+	require.NoError(t, withdrawals.Create(ctx, tenantID, held))
+
+	cutoff := time.Now().Add(-24 * time.Hour)
+	listed, err := withdrawals.ListInStatusBefore(ctx, tenantID, domain.StatusAwaitingApproval, cutoff, domain.PageCursor{}, 10)
+	require.NoError(t, err)
+	assert.Len(t, listed, 1)
+
+	require.NoError(t, withdrawals.TransitionStatus(ctx, tenantID, held.ID, domain.StatusAwaitingApproval, domain.StatusPending))
+
+	listed, err = withdrawals.ListInStatusBefore(ctx, tenantID, domain.StatusPending, cutoff, domain.PageCursor{}, 10)
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+
+	got, err := withdrawals.GetByID(ctx, tenantID, held.ID)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), got.StatusSince, time.Minute)
+	assert.WithinDuration(t, created, got.CreatedAt, time.Second)
+}
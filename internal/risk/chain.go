@@ -0,0 +1,47 @@
+// Package risk runs configurable fraud and compliance rules against a
+// withdrawal before the user's balance is debited.
+package risk
+
+import (
+	"context"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+)
+
+// Rule is one check. It returns nil when it does not fire.
+type Rule interface {
+	Name() string
+	Check(ctx context.Context, in *domain.RiskInput) (*domain.RiskDecision, error)
+}
+
+// Chain implements port.RiskEvaluator. Rules run in order; the strictest
+// outcome wins, and among equally strict ones the first rule to fire. A deny
+// stops the chain.
+type Chain struct {
+	rules []Rule
+}
+
+var _ port.RiskEvaluator = (*Chain)(nil)
+
+func NewChain(rules ...Rule) *Chain {
+	return &Chain{rules: rules}
+}
+
+func (c *Chain) Evaluate(ctx context.Context, in *domain.RiskInput) (*domain.RiskDecision, error) {
+	decision := &domain.RiskDecision{Outcome: domain.RiskAllow}
+	for _, rule := range c.rules {
+		fired, err := rule.Check(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		if fired == nil || !fired.Outcome.StricterThan(decision.Outcome) {
+			continue
+		}
+		decision = fired
+		decision.Rule = rule.Name()
+		if decision.Outcome == domain.RiskDeny {
+			break
+		}
+	}
+	return decision, nil
+}
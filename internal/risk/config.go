@@ -0,0 +1,81 @@
+package risk
+
+import (
+	"fmt"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+	"time"
+)
+
+const (
+	TypeVelocity          = "velocity"
+	TypeNewDestination    = "new_destination"
+	TypeUnusualAmount     = "unusual_amount"
+	TypeSanctionedAddress = "sanctioned_address"
+)
+
+// RuleConfig describes one rule; only the fields of its Type are used.
+// Name defaults to the type.
+type RuleConfig struct {
+	Name    string `yaml:"name"`
+	Type    string `yaml:"type"`
+	Outcome string `yaml:"outcome"`
+
+	MaxCount int           `yaml:"maxCount"`
+	Window   time.Duration `yaml:"window"`
+
+	Multiplier float64 `yaml:"multiplier"`
+	Lookback   int     `yaml:"lookback"`
+	MinHistory int     `yaml:"minHistory"`
+
+	Addresses []string `yaml:"addresses"`
+}
+
+// Build turns configured rules into a chain, in the configured order.
+func Build(configs []RuleConfig, history port.WithdrawalHistory) (*Chain, error) {
+	rules := make([]Rule, 0, len(configs))
+	seen := make(map[string]bool, len(configs))
+	for i, c := range configs {
+		rule, err := build(c, history)
+		if err != nil {
+			return nil, fmt.Errorf("risk rule %d (%s): %w", i, c.Type, err)
+		}
+		if seen[rule.Name()] {
+			return nil, fmt.Errorf("risk rule %d: name %q is used twice", i, rule.Name())
+		}
+		seen[rule.Name()] = true
+		rules = append(rules, rule)
+	}
+	return NewChain(rules...), nil
+}
+
+func build(c RuleConfig, history port.WithdrawalHistory) (Rule, error) {
+	name := c.Name
+	if name == "" {
+		name = c.Type
+	}
+	outcome := domain.RiskOutcome(c.Outcome)
+	if !outcome.Valid() || outcome == domain.RiskAllow {
+		return nil, fmt.Errorf("outcome must be hold or deny, got %q", c.Outcome)
+	}
+
+	switch c.Type {
+	case TypeVelocity:
+		if c.MaxCount < 1 || c.Window <= 0 {
+			return nil, fmt.Errorf("maxCount and window must be positive")
+		}
+		return &Velocity{RuleName: name, Outcome: outcome, MaxCount: c.MaxCount, Window: c.Window, History: history}, nil
+	case TypeNewDestination:
+		return &NewDestination{RuleName: name, Outcome: outcome, History: history}, nil
+	case TypeUnusualAmount:
+		if c.Multiplier <= 1 || c.Lookback < 1 {
+			return nil, fmt.Errorf("multiplier must exceed 1 and lookback must be positive")
+		}
+		return &UnusualAmount{RuleName: name, Outcome: outcome, Multiplier: c.Multiplier,
+			Lookback: c.Lookback, MinHistory: c.MinHistory, History: history}, nil
+	case TypeSanctionedAddress:
+		return NewSanctionedAddress(name, outcome, c.Addresses), nil
+	default:
+		return nil, fmt.Errorf("unknown rule type")
+	}
+}
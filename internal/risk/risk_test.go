@@ -0,0 +1,136 @@
+package risk
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"idempot/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+type pastWithdrawal struct {
+	User        string                  `yaml:"user"`
+	Amount      float64                 `yaml:"amount"`
+	Currency    string                  `yaml:"currency"`
+	Destination string                  `yaml:"destination"`
+	Status      domain.WithdrawalStatus `yaml:"status"`
+	Age         time.Duration           `yaml:"age"`
+}
+
+// fixtureHistory answers history questions from testdata instead of a database.
+type fixtureHistory struct {
+	now  time.Time
+	past []pastWithdrawal
+}
+
+func (h *fixtureHistory) CountCreatedSince(_ context.Context, _ string, userID string, since time.Time) (int, error) {
+	n := 0
+	for _, w := range h.past {
+		if w.User == userID && !h.now.Add(-w.Age).Before(since) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (h *fixtureHistory) RecentConfirmedAmounts(_ context.Context, _ string, userID string, currency string, limit int) ([]float64, error) {
+	var amounts []float64
+	for _, w := range h.past {
+		if w.User == userID && w.Currency == currency && w.Status == domain.StatusConfirmed && len(amounts) < limit {
+			amounts = append(amounts, w.Amount)
+		}
+	}
+	return amounts, nil
+}
+
+func (h *fixtureHistory) HasConfirmedTo(_ context.Context, _ string, userID string, currency string, _ string, destination string) (bool, error) {
+	for _, w := range h.past {
+		if w.User == userID && w.Currency == currency && w.Destination == destination && w.Status == domain.StatusConfirmed {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func loadYAML(t *testing.T, path string, out any) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, yaml.Unmarshal(data, out))
+}
+
+func fixtureChain(t *testing.T, now time.Time) *Chain {
+	t.Helper()
+	var rules []RuleConfig
+	loadYAML(t, "testdata/rules.yaml", &rules)
+	history := &fixtureHistory{now: now}
+	loadYAML(t, "testdata/history.yaml", &history.past)
+
+	chain, err := Build(rules, history)
+	require.NoError(t, err)
+	return chain
+}
+
+func TestChain_Fixtures(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	chain := fixtureChain(t, now)
+
+	cases := []struct {
+		name        string
+		user        string
+		amount      float64
+		destination string
+		outcome     domain.RiskOutcome
+		rule        string
+	}{
+		{"usual payout to a known address", "regular", 150, "TKnown", domain.RiskAllow, ""},
+		{"new address", "regular", 150, "TFresh", domain.RiskHold, "first-payout-to-address"},
+		{"unusual amount wins over new address by order", "regular", 1000, "TFresh", domain.RiskHold, "large-for-user"},
+		{"too many withdrawals in the window", "busy", 10, "TBusy", domain.RiskHold, "burst"},
+		{"sanctioned address is denied whatever else fires", "busy", 10, "0x8589427373d6d84e98730d7795d8f6f8731fda16", domain.RiskDeny, "sanctions"},
+		{"no history is no baseline for amounts", "newcomer", 1e6, "TNew", domain.RiskHold, "first-payout-to-address"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			decision, err := chain.Evaluate(context.Background(), &domain.RiskInput{
+				TenantID:    domain.DefaultTenant,
+				UserID:      tc.user,
+				Amount:      tc.amount,
+				Currency:    "USDT",
+				Destination: tc.destination,
+				Now:         now,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.outcome, decision.Outcome)
+			assert.Equal(t, tc.rule, decision.Rule)
+			if tc.rule != "" {
+				assert.NotEmpty(t, decision.Reason)
+			}
+		})
+	}
+}
+
+func TestBuild_RejectsInvalidRules(t *testing.T) {
+	invalid := []RuleConfig{
+		{Type: TypeNewDestination, Outcome: "allow"},
+		{Type: TypeNewDestination, Outcome: "block"},
+		{Type: TypeVelocity, Outcome: "hold"},
+		{Type: TypeUnusualAmount, Outcome: "hold", Multiplier: 0.5, Lookback: 10},
+		{Type: "geo", Outcome: "deny"},
+	}
+	for _, c := range invalid {
+		_, err := Build([]RuleConfig{c}, &fixtureHistory{})
+		assert.Error(t, err, "%+v", c)
+	}
+
+	_, err := Build([]RuleConfig{
+		{Type: TypeNewDestination, Outcome: "hold"},
+		{Type: TypeNewDestination, Outcome: "deny"},
+	}, &fixtureHistory{})
+	assert.Error(t, err, "duplicate names")
+}
@@ -0,0 +1,114 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+	"strings"
+	"time"
+)
+
+// Velocity fires when the user has already created MaxCount withdrawals within Window.
+type Velocity struct {
+	RuleName string
+	Outcome  domain.RiskOutcome
+	MaxCount int
+	Window   time.Duration
+	History  port.WithdrawalHistory
+}
+
+func (r *Velocity) Name() string { return r.RuleName }
+
+func (r *Velocity) Check(ctx context.Context, in *domain.RiskInput) (*domain.RiskDecision, error) {
+	n, err := r.History.CountCreatedSince(ctx, in.TenantID, in.UserID, in.Now.Add(-r.Window))
+	if err != nil {
+		return nil, err
+	}
+	if n < r.MaxCount {
+		return nil, nil
+	}
+	return &domain.RiskDecision{
+		Outcome: r.Outcome,
+		Reason:  fmt.Sprintf("%d withdrawals in the last %s, at most %d allowed", n, r.Window, r.MaxCount),
+	}, nil
+}
+
+// NewDestination fires when no withdrawal to the destination was ever confirmed for the user.
+type NewDestination struct {
+	RuleName string
+	Outcome  domain.RiskOutcome
+	History  port.WithdrawalHistory
+}
+
+func (r *NewDestination) Name() string { return r.RuleName }
+
+func (r *NewDestination) Check(ctx context.Context, in *domain.RiskInput) (*domain.RiskDecision, error) {
+	known, err := r.History.HasConfirmedTo(ctx, in.TenantID, in.UserID, in.Currency, in.Network, in.Destination)
+	if err != nil || known {
+		return nil, err
+	}
+	return &domain.RiskDecision{Outcome: r.Outcome, Reason: "first withdrawal to this destination"}, nil
+}
+
+// UnusualAmount fires when the amount exceeds Multiplier times the average of
+// the user's last Lookback confirmed withdrawals. Users with fewer than
+// MinHistory of them have no baseline and are left alone.
+type UnusualAmount struct {
+	RuleName   string
+	Outcome    domain.RiskOutcome
+	Multiplier float64
+	Lookback   int
+	MinHistory int
+	History    port.WithdrawalHistory
+}
+
+func (r *UnusualAmount) Name() string { return r.RuleName }
+
+func (r *UnusualAmount) Check(ctx context.Context, in *domain.RiskInput) (*domain.RiskDecision, error) {
+	amounts, err := r.History.RecentConfirmedAmounts(ctx, in.TenantID, in.UserID, in.Currency, r.Lookback)
+	if err != nil {
+		return nil, err
+	}
+	if len(amounts) == 0 || len(amounts) < r.MinHistory {
+		return nil, nil
+	}
+
+	var sum float64
+	for _, a := range amounts {
+		sum += a
+	}
+	average := sum / float64(len(amounts))
+	if in.Amount <= average*r.Multiplier {
+		return nil, nil
+	}
+	return &domain.RiskDecision{
+		Outcome: r.Outcome,
+		Reason:  fmt.Sprintf("amount %g is more than %g times the average of %g", in.Amount, r.Multiplier, average),
+	}, nil
+}
+
+// SanctionedAddress fires when the destination is on a sanctions list.
+// Addresses are compared case-insensitively.
+type SanctionedAddress struct {
+	RuleName  string
+	Outcome   domain.RiskOutcome
+	addresses map[string]bool
+}
+
+func NewSanctionedAddress(name string, outcome domain.RiskOutcome, addresses []string) *SanctionedAddress {
+	set := make(map[string]bool, len(addresses))
+	for _, a := range addresses {
+		set[strings.ToLower(strings.TrimSpace(a))] = true
+	}
+	return &SanctionedAddress{RuleName: name, Outcome: outcome, addresses: set}
+}
+
+func (r *SanctionedAddress) Name() string { return r.RuleName }
+
+func (r *SanctionedAddress) Check(_ context.Context, in *domain.RiskInput) (*domain.RiskDecision, error) {
+	if !r.addresses[strings.ToLower(in.Destination)] {
+		return nil, nil
+	}
+	return &domain.RiskDecision{Outcome: r.Outcome, Reason: "destination is on the sanctions list"}, nil
+}
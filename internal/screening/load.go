@@ -0,0 +1,110 @@
+package screening
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"idempot/internal/domain"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// jsonList is the JSON list format. Version is optional and defaults to a
+// hash of the file.
+type jsonList struct {
+	Version string                  `json:"version"`
+	Entries []domain.ScreeningEntry `json:"entries"`
+}
+
+// readList parses a CSV or JSON list, by file extension. CSV files need a
+// header row with at least the type and value columns; entity and reference
+// are optional.
+func readList(path string) (string, []domain.ScreeningEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	sum := sha256.Sum256(data)
+	version := hex.EncodeToString(sum[:6])
+
+	var entries []domain.ScreeningEntry
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		var list jsonList
+		if err := json.Unmarshal(data, &list); err != nil {
+			return "", nil, err
+		}
+		if list.Version != "" {
+			version = list.Version
+		}
+		entries = list.Entries
+	case ".csv":
+		entries, err = readCSV(bytes.NewReader(data))
+		if err != nil {
+			return "", nil, err
+		}
+	default:
+		return "", nil, fmt.Errorf("unsupported list format %q", filepath.Ext(path))
+	}
+
+	for i, e := range entries {
+		if e.Type != domain.ScreeningAddress && e.Type != domain.ScreeningUser {
+			return "", nil, fmt.Errorf("entry %d: unknown type %q", i+1, e.Type)
+		}
+		if strings.TrimSpace(e.Value) == "" {
+			return "", nil, fmt.Errorf("entry %d: empty value", i+1)
+		}
+	}
+	return version, entries, nil
+}
+
+func readCSV(r io.Reader) ([]domain.ScreeningEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"type", "value"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("header has no %s column", required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var entries []domain.ScreeningEntry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, domain.ScreeningEntry{
+			Type:      domain.ScreeningEntryType(strings.ToLower(field(record, "type"))),
+			Value:     field(record, "value"),
+			Entity:    field(record, "entity"),
+			Reference: field(record, "reference"),
+		})
+	}
+}
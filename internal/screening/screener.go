@@ -0,0 +1,146 @@
+// Package screening blocks withdrawals to addresses, and optionally from
+// users, on compliance deny-lists kept as local files.
+package screening
+
+import (
+	"context"
+	"fmt"
+	"idempot/internal/domain"
+	"idempot/internal/filewatch"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ListFile is a deny-list on disk; Name identifies it in hits and on the admin endpoint.
+type ListFile struct {
+	Name string
+	Path string
+}
+
+type match struct {
+	list    string
+	version string
+	entry   domain.ScreeningEntry
+}
+
+type index struct {
+	addresses map[string]match
+	users     map[string]match
+	versions  []domain.ScreeningListVersion
+}
+
+// Screener holds every list in one in-memory index and swaps it as a whole on
+// reload, so a screening never sees half of an update. A failed reload keeps
+// the previous index.
+type Screener struct {
+	files       []ListFile
+	screenUsers bool
+
+	mu    sync.RWMutex
+	index *index
+
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+// NewScreener loads the lists. User entries are ignored unless screenUsers is set.
+func NewScreener(files []ListFile, screenUsers bool) (*Screener, error) {
+	s := &Screener{
+		files:       files,
+		screenUsers: screenUsers,
+		logger:      slog.Default(),
+		now:         time.Now,
+	}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Screener) WithLogger(logger *slog.Logger) *Screener {
+	s.logger = logger
+	return s
+}
+
+// normalize makes matching insensitive to case and surrounding space; hex and
+// bech32 addresses are case-insensitive, and no deny-list relies on case alone.
+func normalize(v string) string {
+	return strings.ToLower(strings.TrimSpace(v))
+}
+
+func (s *Screener) Reload() error {
+	idx := &index{
+		addresses: make(map[string]match),
+		users:     make(map[string]match),
+	}
+	loadedAt := s.now()
+	for _, f := range s.files {
+		version, entries, err := readList(f.Path)
+		if err != nil {
+			return fmt.Errorf("load screening list %s: %w", f.Name, err)
+		}
+		for _, e := range entries {
+			m := match{list: f.Name, version: version, entry: e}
+			if e.Type == domain.ScreeningAddress {
+				idx.addresses[normalize(e.Value)] = m
+			} else {
+				idx.users[normalize(e.Value)] = m
+			}
+		}
+		idx.versions = append(idx.versions, domain.ScreeningListVersion{
+			Name: f.Name, Version: version, Entries: len(entries), LoadedAt: loadedAt,
+		})
+	}
+	sort.Slice(idx.versions, func(i, j int) bool { return idx.versions[i].Name < idx.versions[j].Name })
+
+	s.mu.Lock()
+	s.index = idx
+	s.mu.Unlock()
+	return nil
+}
+
+// Watch reloads on file changes until ctx is cancelled.
+func (s *Screener) Watch(ctx context.Context) error {
+	paths := make([]string, 0, len(s.files))
+	for _, f := range s.files {
+		paths = append(paths, f.Path)
+	}
+
+	return filewatch.Watch(ctx, paths, filewatch.DefaultDebounce, func() {
+		if err := s.Reload(); err != nil {
+			s.logger.ErrorContext(ctx, "screening list reload failed, keeping previous lists", "error", err)
+			return
+		}
+		for _, v := range s.Versions() {
+			s.logger.InfoContext(ctx, "screening list reloaded", "list", v.Name, "version", v.Version, "entries", v.Entries)
+		}
+	})
+}
+
+func (s *Screener) current() *index {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index
+}
+
+// Screen returns the first list entry matching the destination, then the
+// user, or nil if neither is listed. Only the list fields of the hit are set.
+func (s *Screener) Screen(userID string, destination string) *domain.ScreeningHit {
+	idx := s.current()
+	m, ok := idx.addresses[normalize(destination)]
+	if !ok && s.screenUsers {
+		m, ok = idx.users[normalize(userID)]
+	}
+	if !ok {
+		return nil
+	}
+	return &domain.ScreeningHit{List: m.list, ListVersion: m.version, Entry: m.entry}
+}
+
+// Versions lists the loaded lists by name.
+func (s *Screener) Versions() []domain.ScreeningListVersion {
+	return append([]domain.ScreeningListVersion(nil), s.current().versions...)
+}
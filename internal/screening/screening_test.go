@@ -0,0 +1,117 @@
+package screening
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"idempot/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ofacCSV = `type,value,entity,reference
+# comment lines are skipped
+address,0x8589427373D6D84E98730D7795D8f6f8731FDA16,Tornado Cash,SDN-12345
+user,user-666,Sanctioned Person,SDN-67890
+`
+
+const internalJSON = `{
+  "version": "2026-03-01",
+  "entries": [
+    {"type": "address", "value": "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", "entity": "Known scam", "reference": "CASE-1"}
+  ]
+}`
+
+func writeLists(t *testing.T) (string, []ListFile) {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ofac.csv"), []byte(ofacCSV), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "internal.json"), []byte(internalJSON), 0o600))
+	return dir, []ListFile{
+		{Name: "ofac", Path: filepath.Join(dir, "ofac.csv")},
+		{Name: "internal", Path: filepath.Join(dir, "internal.json")},
+	}
+}
+
+func TestScreener_Screen(t *testing.T) {
+	_, files := writeLists(t)
+	screener, err := NewScreener(files, true)
+	require.NoError(t, err)
+
+	hit := screener.Screen("user-1", "0x8589427373d6d84e98730d7795d8f6f8731fda16")
+	require.NotNil(t, hit)
+	assert.Equal(t, "ofac", hit.List)
+	assert.Equal(t, "Tornado Cash", hit.Entry.Entity)
+	assert.Equal(t, "SDN-12345", hit.Entry.Reference)
+	assert.Len(t, hit.ListVersion, 12)
+
+	hit = screener.Screen("user-1", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t")
+	require.NotNil(t, hit)
+	assert.Equal(t, "internal", hit.List)
+	assert.Equal(t, "2026-03-01", hit.ListVersion)
+
+	hit = screener.Screen("user-666", "0xclean")
+	require.NotNil(t, hit)
+	assert.Equal(t, domain.ScreeningUser, hit.Entry.Type)
+
+	assert.Nil(t, screener.Screen("user-1", "0xclean"))
+
+	versions := screener.Versions()
+	require.Len(t, versions, 2)
+	assert.Equal(t, "internal", versions[0].Name)
+	assert.Equal(t, 1, versions[0].Entries)
+	assert.Equal(t, 2, versions[1].Entries)
+}
+
+func TestScreener_UsersOnlyWhenEnabled(t *testing.T) {
+	_, files := writeLists(t)
+	screener, err := NewScreener(files, false)
+	require.NoError(t, err)
+	assert.Nil(t, screener.Screen("user-666", "0xclean"))
+}
+
+func TestScreener_RejectsMalformedLists(t *testing.T) {
+	dir := t.TempDir()
+	cases := map[string]string{
+		"no-value.csv": "type,entity\naddress,x\n",
+		"bad-type.csv": "type,value\ncountry,KP\n",
+		"empty.csv":    "type,value\naddress, \n",
+		"broken.json":  `{"entries": [`,
+		"list.txt":     "0xabc\n",
+	}
+	for name, content := range cases {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		_, err := NewScreener([]ListFile{{Name: name, Path: path}}, true)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestScreener_WatchReloads(t *testing.T) {
+	dir, files := writeLists(t)
+	screener, err := NewScreener(files, true)
+	require.NoError(t, err)
+	before := screener.Versions()[1].Version
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = screener.Watch(ctx) }()
+	time.Sleep(100 * time.Millisecond)
+
+	// A broken file keeps the previous lists
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ofac.csv"), []byte("garbage"), 0o600))
+	time.Sleep(500 * time.Millisecond)
+	assert.Equal(t, before, screener.Versions()[1].Version)
+	assert.NotNil(t, screener.Screen("user-1", "0x8589427373D6D84E98730D7795D8f6f8731FDA16"))
+
+	updated := ofacCSV + "address,0xnewlylisted,Someone,SDN-1\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ofac.csv"), []byte(updated), 0o600))
+	assert.Eventually(t, func() bool {
+		return screener.Screen("user-1", "0xNEWLYLISTED") != nil
+	}, 3*time.Second, 50*time.Millisecond)
+	assert.NotEqual(t, before, screener.Versions()[1].Version)
+}
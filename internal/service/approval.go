@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"idempot/internal/domain"
+	"idempot/internal/tracing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func (s *withdrawalService) ApproveWithdrawal(ctx context.Context, id uuid.UUID, reason string) (_ *domain.ApprovalProgress, err error) {
+	ctx, span := startSpan(ctx, "ApproveWithdrawal", attribute.String("withdrawal.id", id.String()))
+	defer tracing.End(span, &err)
+
+	return s.decide(ctx, id, domain.DecisionApprove, reason)
+}
+
+func (s *withdrawalService) RejectWithdrawal(ctx context.Context, id uuid.UUID, reason string) (_ *domain.ApprovalProgress, err error) {
+	ctx, span := startSpan(ctx, "RejectWithdrawal", attribute.String("withdrawal.id", id.String()))
+	defer tracing.End(span, &err)
+
+	return s.decide(ctx, id, domain.DecisionReject, reason)
+}
+
+// decide records an operator's decision under the user's lock, so that
+// concurrent approvals are counted one after another. The last approval
+// required makes the withdrawal pending; any rejection refunds it. Only
+// operators and admins decide: the confirm scope alone is also held by
+// machine clients, and approvals are meant to be made by people.
+func (s *withdrawalService) decide(
+	ctx context.Context,
+	id uuid.UUID,
+	decision domain.ApprovalDecision,
+	reason string,
+) (*domain.ApprovalProgress, error) {
+	p, ok := domain.PrincipalFromContext(ctx)
+	if !ok || !(p.HasRole(domain.RoleOperator) || p.HasRole(domain.RoleAdmin)) {
+		return nil, domain.ErrForbidden
+	}
+
+	withdrawal, err := s.GetWithdrawal(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if withdrawal.Status != domain.StatusAwaitingApproval || s.approvals == nil {
+		return nil, domain.ErrStatusConflict
+	}
+	if p.Subject == withdrawal.RequestedBy || p.Subject == withdrawal.UserID {
+		return nil, domain.ErrSelfApproval
+	}
+
+	required := max(s.approvalPolicy.Required, 1)
+	progress := &domain.ApprovalProgress{
+		WithdrawalID: id,
+		Status:       domain.StatusAwaitingApproval,
+		Required:     required,
+	}
+	now := time.Now()
+	tenantID := domain.TenantFromContext(ctx)
+
+	err = s.balanceRepo.WithLock(ctx, tenantID, withdrawal.UserID, func(txCtx context.Context) error {
+		// The withdrawal may have been cancelled or decided while we waited for the lock
+		current, err := s.withdrawalRepo.GetByID(txCtx, tenantID, id)
+		if err != nil {
+			return err
+		}
+		if current.Status != domain.StatusAwaitingApproval {
+			return domain.ErrStatusConflict
+		}
+
+		if err := s.approvals.Record(txCtx, tenantID, &domain.Approval{
+			WithdrawalID: id,
+			Decision:     decision,
+			ActorSubject: p.Subject,
+			ActorKind:    p.Kind,
+			Reason:       reason,
+			CreatedAt:    now,
+		}); err != nil {
+			return err
+		}
+		count, err := s.approvals.CountApprovals(txCtx, tenantID, id)
+		if err != nil {
+			return err
+		}
+		progress.Approvals = count
+
+		action := domain.ActionApprove
+		switch {
+		case decision == domain.DecisionReject:
+			action, progress.Status = domain.ActionReject, domain.StatusRejected
+		case count >= required:
+			progress.Status = domain.StatusPending
+		default:
+			return s.auditDecision(txCtx, decision, current, progress.Status)
+		}
+
+		if err := s.withdrawalRepo.TransitionStatus(txCtx, tenantID, id, domain.StatusAwaitingApproval, progress.Status); err != nil {
+			return err
+		}
+		if decision == domain.DecisionReject {
+			if err := RefundWithdrawal(txCtx, s.balanceRepo, s.feeLedger, tenantID, current); err != nil {
+				return err
+			}
+		}
+		if err := s.actions.Record(txCtx, &domain.WithdrawalActionRecord{
+			WithdrawalID: id,
+			Action:       action,
+			FromStatus:   domain.StatusAwaitingApproval,
+			ToStatus:     progress.Status,
+			ActorSubject: p.Subject,
+			ActorKind:    p.Kind,
+			ActorRoles:   p.Roles,
+			Reason:       reason,
+			CreatedAt:    now,
+		}); err != nil {
+			return err
+		}
+		return s.auditDecision(txCtx, decision, current, progress.Status)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if progress.Status != domain.StatusAwaitingApproval {
+		s.events.Publish(ctx, domain.WithdrawalEvent{
+			WithdrawalID:   id,
+			TenantID:       tenantID,
+			UserID:         withdrawal.UserID,
+			Status:         progress.Status,
+			PreviousStatus: domain.StatusAwaitingApproval,
+			OccurredAt:     now,
+		})
+	}
+	return progress, nil
+}
+
+// auditDecision records every decision, including approvals that leave the
+// withdrawal awaiting further ones.
+func (s *withdrawalService) auditDecision(
+	ctx context.Context,
+	decision domain.ApprovalDecision,
+	current *domain.Withdrawal,
+	status domain.WithdrawalStatus,
+) error {
+	action := domain.AuditWithdrawalApproved
+	if decision == domain.DecisionReject {
+		action = domain.AuditWithdrawalRejected
+	}
+	after := *current
+	if status != current.Status {
+		after.Status = status
+		after.UpdatedAt = time.Now()
+	}
+	return RecordAudit(ctx, s.audit, action, domain.AuditEntityWithdrawal, current.ID.String(), current.UserID, current, &after)
+}
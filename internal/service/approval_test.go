@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"idempot/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockApprovalRepository struct {
+	mock.Mock
+}
+
+func (m *MockApprovalRepository) Record(ctx context.Context, tenantID string, approval *domain.Approval) error {
+	args := m.Called(ctx, tenantID, approval)
+	return args.Error(0)
+}
+
+func (m *MockApprovalRepository) CountApprovals(ctx context.Context, tenantID string, withdrawalID uuid.UUID) (int, error) {
+	args := m.Called(ctx, tenantID, withdrawalID)
+	return args.Int(0), args.Error(1)
+}
+
+var testApprovalPolicy = domain.ApprovalPolicy{Required: 2, Thresholds: map[string]float64{"USDT": 1000}}
+
+func operator(subject string) context.Context {
+	return domain.WithPrincipal(context.Background(), &domain.Principal{
+		Subject:  subject,
+		Kind:     domain.PrincipalUser,
+		TenantID: domain.DefaultTenant,
+		Roles:    []domain.Role{domain.RoleOperator},
+		Scopes:   domain.ScopesForRoles([]domain.Role{domain.RoleOperator}),
+	})
+}
+
+func awaitingWithdrawal() *domain.Withdrawal {
+	return &domain.Withdrawal{
+		ID:          uuid.New(),
+		TenantID:    domain.DefaultTenant,
+		UserID:      "user-123",
+		Amount:      5000,
+		Currency:    "USDT",
+		Status:      domain.StatusAwaitingApproval,
+		RequestedBy: "backoffice",
+		CreatedAt:   time.Now(),
+	}
+}
+
+func TestApproveWithdrawal_NeedsDistinctOperators(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	mockApprovals := new(MockApprovalRepository)
+	service := NewWithdrawalService(mockWithdrawalRepo, mockBalanceRepo, WithApprovals(testApprovalPolicy, mockApprovals))
+
+	withdrawal := awaitingWithdrawal()
+	mockWithdrawalRepo.On("GetByID", mock.Anything, domain.DefaultTenant, withdrawal.ID).Return(withdrawal, nil)
+	mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, withdrawal.UserID, mock.Anything).Return(nil)
+
+	// Neither the requester nor the owner may decide
+	_, err := service.ApproveWithdrawal(operator("backoffice"), withdrawal.ID, "")
+	assert.Equal(t, domain.ErrSelfApproval, err)
+	_, err = service.RejectWithdrawal(operator("user-123"), withdrawal.ID, "")
+	assert.Equal(t, domain.ErrSelfApproval, err)
+
+	mockApprovals.On("Record", mock.Anything, domain.DefaultTenant, mock.MatchedBy(func(a *domain.Approval) bool {
+		return a.ActorSubject == "alice"
+	})).Return(nil).Once()
+	mockApprovals.On("CountApprovals", mock.Anything, domain.DefaultTenant, withdrawal.ID).Return(1, nil).Once()
+
+	progress, err := service.ApproveWithdrawal(operator("alice"), withdrawal.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusAwaitingApproval, progress.Status)
+	assert.Equal(t, 1, progress.Approvals)
+	mockWithdrawalRepo.AssertNotCalled(t, "TransitionStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	// The same operator cannot count twice
+	mockApprovals.On("Record", mock.Anything, domain.DefaultTenant, mock.MatchedBy(func(a *domain.Approval) bool {
+		return a.ActorSubject == "alice"
+	})).Return(domain.ErrAlreadyDecided).Once()
+	_, err = service.ApproveWithdrawal(operator("alice"), withdrawal.ID, "")
+	assert.Equal(t, domain.ErrAlreadyDecided, err)
+
+	mockApprovals.On("Record", mock.Anything, domain.DefaultTenant, mock.MatchedBy(func(a *domain.Approval) bool {
+		return a.ActorSubject == "bob" && a.Decision == domain.DecisionApprove
+	})).Return(nil).Once()
+	mockApprovals.On("CountApprovals", mock.Anything, domain.DefaultTenant, withdrawal.ID).Return(2, nil).Once()
+	mockWithdrawalRepo.On("TransitionStatus", mock.Anything, domain.DefaultTenant, withdrawal.ID,
+		domain.StatusAwaitingApproval, domain.StatusPending).Return(nil)
+
+	progress, err = service.ApproveWithdrawal(operator("bob"), withdrawal.ID, "looks fine")
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusPending, progress.Status)
+	assert.Equal(t, 2, progress.Approvals)
+
+	mockApprovals.AssertExpectations(t)
+	mockWithdrawalRepo.AssertExpectations(t)
+	mockBalanceRepo.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestApproveWithdrawal_NeedsOperatorRole(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	service := NewWithdrawalService(mockWithdrawalRepo, new(MockBalanceRepository), WithApprovals(testApprovalPolicy, new(MockApprovalRepository)))
+
+	withdrawal := awaitingWithdrawal()
+	mockWithdrawalRepo.On("GetByID", mock.Anything, domain.DefaultTenant, withdrawal.ID).Return(withdrawal, nil)
+
+	// A machine client with the confirm scope, such as the legacy token, is not an operator
+	client := domain.WithPrincipal(context.Background(), &domain.Principal{
+		Subject:  "payments-backend",
+		Kind:     domain.PrincipalService,
+		TenantID: domain.DefaultTenant,
+		Scopes:   []domain.Scope{domain.ScopeWithdrawalsConfirm},
+	})
+	_, err := service.ApproveWithdrawal(client, withdrawal.ID, "")
+	assert.Equal(t, domain.ErrForbidden, err)
+	_, err = service.RejectWithdrawal(client, withdrawal.ID, "")
+	assert.Equal(t, domain.ErrForbidden, err)
+
+	mockWithdrawalRepo.AssertNotCalled(t, "TransitionStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRejectWithdrawal_Refunds(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	mockApprovals := new(MockApprovalRepository)
+	service := NewWithdrawalService(mockWithdrawalRepo, mockBalanceRepo, WithApprovals(testApprovalPolicy, mockApprovals))
+
+	withdrawal := awaitingWithdrawal()
+	mockWithdrawalRepo.On("GetByID", mock.Anything, domain.DefaultTenant, withdrawal.ID).Return(withdrawal, nil)
+	mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, withdrawal.UserID, mock.Anything).Return(nil)
+	mockApprovals.On("Record", mock.Anything, domain.DefaultTenant, mock.MatchedBy(func(a *domain.Approval) bool {
+		return a.Decision == domain.DecisionReject
+	})).Return(nil)
+	mockApprovals.On("CountApprovals", mock.Anything, domain.DefaultTenant, withdrawal.ID).Return(0, nil)
+	mockWithdrawalRepo.On("TransitionStatus", mock.Anything, domain.DefaultTenant, withdrawal.ID,
+		domain.StatusAwaitingApproval, domain.StatusRejected).Return(nil)
+	mockBalanceRepo.On("UpdateBalance", mock.Anything, domain.DefaultTenant, withdrawal.UserID, "USDT", 5000.0).Return(nil)
+
+	progress, err := service.RejectWithdrawal(operator("alice"), withdrawal.ID, "unknown destination")
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusRejected, progress.Status)
+
+	mockBalanceRepo.AssertExpectations(t)
+	mockWithdrawalRepo.AssertExpectations(t)
+}
+
+func TestAwaitingApproval_NotEligibleForPayout(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	service := NewWithdrawalService(mockWithdrawalRepo, new(MockBalanceRepository), WithApprovals(testApprovalPolicy, new(MockApprovalRepository)))
+
+	withdrawal := awaitingWithdrawal()
+	mockWithdrawalRepo.On("GetByID", mock.Anything, domain.DefaultTenant, withdrawal.ID).Return(withdrawal, nil)
+
+	assert.Equal(t, domain.ErrStatusConflict, service.ConfirmWithdrawal(operator("alice"), withdrawal.ID))
+	assert.Equal(t, domain.ErrStatusConflict, service.FailWithdrawal(operator("alice"), withdrawal.ID, ""))
+}
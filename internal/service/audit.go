@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+	"time"
+)
+
+type noopAuditLog struct{}
+
+func (noopAuditLog) Append(context.Context, *domain.AuditEvent) error { return nil }
+
+// WithAuditLog records every withdrawal the service creates or changes in
+// audit, in the same transaction as the change.
+func WithAuditLog(audit port.AuditLog) Option {
+	return func(s *withdrawalService) {
+		s.audit = audit
+	}
+}
+
+// RecordAudit appends a change to userID's chain of the audit log in the
+// transaction carried by ctx, attributed to the principal and request in ctx.
+// The transaction should hold userID's WithLock, so that appends to the chain
+// never wait on each other.
+func RecordAudit(
+	ctx context.Context,
+	audit port.AuditLog,
+	action domain.AuditAction,
+	entityType string,
+	entityID string,
+	userID string,
+	before any,
+	after any,
+) error {
+	event, err := domain.NewAuditEvent(ctx, action, entityType, entityID, userID, before, after)
+	if err != nil {
+		return err
+	}
+	return audit.Append(ctx, event)
+}
+
+var transitionAuditActions = map[domain.WithdrawalStatus]domain.AuditAction{
+	domain.StatusConfirmed: domain.AuditWithdrawalConfirmed,
+	domain.StatusFailed:    domain.AuditWithdrawalFailed,
+	domain.StatusCancelled: domain.AuditWithdrawalCancelled,
+	domain.StatusExpired:   domain.AuditWithdrawalExpired,
+}
+
+// AuditTransition records that w, as it was before, moved to status.
+func AuditTransition(ctx context.Context, audit port.AuditLog, w *domain.Withdrawal, status domain.WithdrawalStatus) error {
+	after := *w
+	after.Status = status
+	after.UpdatedAt = time.Now()
+	after.StatusSince = after.UpdatedAt
+	return RecordAudit(ctx, audit, transitionAuditActions[status], domain.AuditEntityWithdrawal, w.ID.String(), w.UserID, w, &after)
+}
+
+// NewActionRecord tells that w, as it was before, moved to status by action,
+// attributed to the principal in ctx.
+func NewActionRecord(
+	ctx context.Context,
+	w *domain.Withdrawal,
+	action domain.WithdrawalAction,
+	status domain.WithdrawalStatus,
+	reason string,
+) *domain.WithdrawalActionRecord {
+	record := &domain.WithdrawalActionRecord{
+		WithdrawalID: w.ID,
+		Action:       action,
+		FromStatus:   w.Status,
+		ToStatus:     status,
+		Reason:       reason,
+		CreatedAt:    time.Now(),
+	}
+	if p, ok := domain.PrincipalFromContext(ctx); ok {
+		record.ActorSubject = p.Subject
+		record.ActorKind = p.Kind
+		record.ActorRoles = p.Roles
+	}
+	return record
+}
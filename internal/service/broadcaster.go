@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"idempot/internal/domain"
+	"math/rand/v2"
+	"sync"
+)
+
+const (
+	defaultHistorySize   = 1024
+	subscriberBufferSize = 64
+)
+
+// Event IDs are a random stream tag in the upper bits and a sequence number in
+// the lower ones. The tag tells IDs handed out by another process, restarted
+// or another replica, from positions in this one's history. Together the two
+// stay below 2^53, so IDs survive JSON numbers.
+const (
+	streamTagBits = 21
+	seqBits       = 32
+	seqMask       = 1<<seqBits - 1
+)
+
+type subscription struct {
+	filter domain.WithdrawalEventFilter
+	ch     chan domain.WithdrawalEvent
+}
+
+// Broadcaster fans withdrawal status events out to in-process subscribers and
+// keeps a bounded history so reconnecting clients can resume by event id.
+// Neither the history nor the ids outlive the process: a client can only
+// resume against the process it was connected to.
+type Broadcaster struct {
+	mu      sync.Mutex
+	stream  uint64
+	seq     uint64
+	history []domain.WithdrawalEvent
+	size    int
+	subs    map[*subscription]struct{}
+	closed  bool
+}
+
+func NewBroadcaster(historySize int) *Broadcaster {
+	if historySize <= 0 {
+		historySize = defaultHistorySize
+	}
+	return &Broadcaster{
+		stream:  (rand.Uint64N(1<<streamTagBits-1) + 1) << seqBits,
+		history: make([]domain.WithdrawalEvent, 0, historySize),
+		size:    historySize,
+		subs:    make(map[*subscription]struct{}),
+	}
+}
+
+func (b *Broadcaster) Publish(_ context.Context, event domain.WithdrawalEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	b.seq++
+	event.ID = b.stream | b.seq
+
+	if len(b.history) == b.size {
+		copy(b.history, b.history[1:])
+		b.history = b.history[:b.size-1]
+	}
+	b.history = append(b.history, event)
+
+	for sub := range b.subs {
+		if !sub.filter.Match(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			// Slow consumer: drop it, the client resumes with Last-Event-ID
+			delete(b.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Subscribe replays the buffered events after lastEventID. When it cannot tell
+// what came after lastEventID, because the id is from another process or has
+// left the history, it replays nothing and returns the id of the latest event
+// as resetID instead; the caller must then reload the state it follows.
+func (b *Broadcaster) Subscribe(filter domain.WithdrawalEventFilter, lastEventID uint64) ([]domain.WithdrawalEvent, uint64, <-chan domain.WithdrawalEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var replay []domain.WithdrawalEvent
+	var resetID uint64
+	if lastEventID == 0 || b.resumable(lastEventID) {
+		for _, e := range b.history {
+			if e.ID > lastEventID && filter.Match(e) {
+				replay = append(replay, e)
+			}
+		}
+	} else {
+		resetID = b.stream | b.seq
+	}
+
+	sub := &subscription{filter: filter, ch: make(chan domain.WithdrawalEvent, subscriberBufferSize)}
+	if b.closed {
+		close(sub.ch)
+		return replay, resetID, sub.ch, func() {}
+	}
+	b.subs[sub] = struct{}{}
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[sub]; ok {
+			delete(b.subs, sub)
+			close(sub.ch)
+		}
+	}
+
+	return replay, resetID, sub.ch, cancel
+}
+
+// resumable tells whether every event after id is still in the history.
+func (b *Broadcaster) resumable(id uint64) bool {
+	if id&^seqMask != b.stream {
+		return false
+	}
+	seq := id & seqMask
+	return seq <= b.seq && seq >= b.seq-uint64(len(b.history))
+}
+
+// Close disconnects every subscriber. It is meant to be registered as a server
+// shutdown hook so open streams don't block graceful shutdown.
+func (b *Broadcaster) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subs {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
+
+type noopPublisher struct{}
+
+func (noopPublisher) Publish(context.Context, domain.WithdrawalEvent) {}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"idempot/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroadcaster_FilterAndResume(t *testing.T) {
+	b := NewBroadcaster(10)
+	id := uuid.New()
+
+	b.Publish(context.Background(), domain.WithdrawalEvent{WithdrawalID: id, UserID: "user-1", Status: domain.StatusConfirmed})
+	b.Publish(context.Background(), domain.WithdrawalEvent{WithdrawalID: uuid.New(), UserID: "user-2", Status: domain.StatusConfirmed})
+
+	replay, resetID, events, cancel := b.Subscribe(domain.WithdrawalEventFilter{UserID: "user-1"}, 0)
+	defer cancel()
+	require.Len(t, replay, 1)
+	assert.Zero(t, resetID)
+	first := replay[0].ID
+
+	// Resuming after the last seen id replays nothing
+	resumed, resetID, _, cancelResumed := b.Subscribe(domain.WithdrawalEventFilter{UserID: "user-1"}, first)
+	defer cancelResumed()
+	assert.Empty(t, resumed)
+	assert.Zero(t, resetID)
+
+	b.Publish(context.Background(), domain.WithdrawalEvent{WithdrawalID: uuid.New(), UserID: "user-2"})
+	b.Publish(context.Background(), domain.WithdrawalEvent{WithdrawalID: id, UserID: "user-1", Status: domain.StatusFailed})
+
+	e := <-events
+	assert.Equal(t, first+3, e.ID)
+	assert.Equal(t, domain.StatusFailed, e.Status)
+}
+
+func TestBroadcaster_HistoryIsBounded(t *testing.T) {
+	b := NewBroadcaster(2)
+	var ids []uint64
+	for i := 0; i < 5; i++ {
+		b.Publish(context.Background(), domain.WithdrawalEvent{WithdrawalID: uuid.New()})
+		ids = append(ids, b.stream|uint64(i+1))
+	}
+
+	replay, _, _, cancel := b.Subscribe(domain.WithdrawalEventFilter{}, 0)
+	defer cancel()
+	require.Len(t, replay, 2)
+	assert.Equal(t, ids[3], replay[0].ID)
+	assert.Equal(t, ids[4], replay[1].ID)
+
+	// Events after the second one are no longer all there
+	replay, resetID, _, cancelGap := b.Subscribe(domain.WithdrawalEventFilter{}, ids[1])
+	defer cancelGap()
+	assert.Empty(t, replay)
+	assert.Equal(t, ids[4], resetID)
+
+	replay, resetID, _, cancelKept := b.Subscribe(domain.WithdrawalEventFilter{}, ids[2])
+	defer cancelKept()
+	assert.Len(t, replay, 2)
+	assert.Zero(t, resetID)
+}
+
+// Ids do not outlive the process: a restarted server or another replica must
+// not take them for positions in its own history.
+func TestBroadcaster_ResetsForeignEventIDs(t *testing.T) {
+	before := NewBroadcaster(10)
+	before.Publish(context.Background(), domain.WithdrawalEvent{WithdrawalID: uuid.New()})
+	replay, _, _, cancel := before.Subscribe(domain.WithdrawalEventFilter{}, 0)
+	cancel()
+	lastSeen := replay[0].ID
+
+	after := NewBroadcaster(10)
+	// Tags are random; make sure these two differ like two processes' would
+	after.stream = before.stream ^ 1<<seqBits
+	for i := 0; i < 3; i++ {
+		after.Publish(context.Background(), domain.WithdrawalEvent{WithdrawalID: uuid.New()})
+	}
+
+	replay, resetID, _, cancel := after.Subscribe(domain.WithdrawalEventFilter{}, lastSeen)
+	defer cancel()
+	assert.Empty(t, replay)
+	assert.NotZero(t, resetID)
+	assert.NotEqual(t, lastSeen, resetID)
+
+	// The reset id is a valid position to resume from
+	replay, next, _, cancelNext := after.Subscribe(domain.WithdrawalEventFilter{}, resetID)
+	defer cancelNext()
+	assert.Empty(t, replay)
+	assert.Zero(t, next)
+}
+
+func TestBroadcaster_CloseEndsSubscriptions(t *testing.T) {
+	b := NewBroadcaster(0)
+	_, _, events, cancel := b.Subscribe(domain.WithdrawalEventFilter{}, 0)
+	defer cancel()
+
+	b.Close()
+	_, ok := <-events
+	assert.False(t, ok)
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type destinationService struct {
+	repo      port.DestinationRepository
+	addresses port.AddressValidator
+	cooldown  time.Duration
+	now       func() time.Time
+}
+
+// NewDestinationService manages address books. New addresses, and switching
+// allow-list mode off, take effect only after cooldown.
+func NewDestinationService(
+	repo port.DestinationRepository,
+	addresses port.AddressValidator,
+	cooldown time.Duration,
+) port.DestinationService {
+	if addresses == nil {
+		addresses = noopAddressValidator{}
+	}
+	return &destinationService{
+		repo:      repo,
+		addresses: addresses,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+func checkOwner(ctx context.Context, userID string) error {
+	if p, ok := domain.PrincipalFromContext(ctx); ok && !p.OwnsUser(userID) {
+		return domain.ErrForbidden
+	}
+	return nil
+}
+
+func (s *destinationService) AddDestination(ctx context.Context, req *domain.DestinationReq) (*domain.Destination, error) {
+	if err := checkOwner(ctx, req.UserID); err != nil {
+		return nil, err
+	}
+	if err := s.addresses.Validate(req.Currency, req.Network, req.Address); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	d := &domain.Destination{
+		ID:        uuid.New(),
+		TenantID:  domain.TenantFromContext(ctx),
+		UserID:    req.UserID,
+		Currency:  req.Currency,
+		Network:   req.Network,
+		Address:   req.Address,
+		Label:     req.Label,
+		CreatedAt: now,
+		UsableAt:  now.Add(s.cooldown),
+	}
+	if err := s.repo.Create(ctx, d.TenantID, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *destinationService) ListDestinations(ctx context.Context, userID string) ([]*domain.Destination, error) {
+	if err := checkOwner(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByUser(ctx, domain.TenantFromContext(ctx), userID)
+}
+
+func (s *destinationService) DeleteDestination(ctx context.Context, id uuid.UUID) error {
+	tenantID := domain.TenantFromContext(ctx)
+	d, err := s.repo.GetByID(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	// Someone else's destination looks exactly like a missing one
+	if checkOwner(ctx, d.UserID) != nil {
+		return domain.ErrDestinationNotFound
+	}
+	return s.repo.Delete(ctx, tenantID, id)
+}
+
+func (s *destinationService) GetSettings(ctx context.Context, userID string) (*domain.DestinationSettings, error) {
+	if err := checkOwner(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetSettings(ctx, domain.TenantFromContext(ctx), userID)
+}
+
+func (s *destinationService) SetAllowListOnly(ctx context.Context, userID string, enabled bool) (*domain.DestinationSettings, error) {
+	if err := checkOwner(ctx, userID); err != nil {
+		return nil, err
+	}
+	tenantID := domain.TenantFromContext(ctx)
+	settings, err := s.repo.GetSettings(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	switch {
+	case enabled:
+		settings.EnforcedUntil = nil
+	case settings.AllowListOnly:
+		// Otherwise whoever took over the account could switch the mode off and withdraw at once
+		until := now.Add(s.cooldown)
+		settings.EnforcedUntil = &until
+	}
+	settings.UserID = userID
+	settings.AllowListOnly = enabled
+
+	if err := s.repo.SetSettings(ctx, tenantID, settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (s *destinationService) Check(ctx context.Context, tenantID string, userID string, currency string, network string, address string) error {
+	settings, err := s.repo.GetSettings(ctx, tenantID, userID)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	if !settings.Enforced(now) {
+		return nil
+	}
+
+	d, err := s.repo.Find(ctx, tenantID, userID, currency, network, address)
+	if err != nil {
+		return err
+	}
+	if d == nil {
+		return &domain.DestinationNotAllowedError{Address: address}
+	}
+	if !d.Usable(now) {
+		usableAt := d.UsableAt
+		return &domain.DestinationNotAllowedError{Address: address, UsableAt: &usableAt}
+	}
+	return nil
+}
+
+type noopDestinationChecker struct{}
+
+func (noopDestinationChecker) Check(context.Context, string, string, string, string, string) error {
+	return nil
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"idempot/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockDestinationRepository struct {
+	mock.Mock
+}
+
+func (m *MockDestinationRepository) Create(ctx context.Context, tenantID string, d *domain.Destination) error {
+	args := m.Called(ctx, tenantID, d)
+	return args.Error(0)
+}
+
+func (m *MockDestinationRepository) GetByID(ctx context.Context, tenantID string, id uuid.UUID) (*domain.Destination, error) {
+	args := m.Called(ctx, tenantID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Destination), args.Error(1)
+}
+
+func (m *MockDestinationRepository) Find(ctx context.Context, tenantID string, userID string, currency string, network string, address string) (*domain.Destination, error) {
+	args := m.Called(ctx, tenantID, userID, currency, network, address)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Destination), args.Error(1)
+}
+
+func (m *MockDestinationRepository) ListByUser(ctx context.Context, tenantID string, userID string) ([]*domain.Destination, error) {
+	args := m.Called(ctx, tenantID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Destination), args.Error(1)
+}
+
+func (m *MockDestinationRepository) Delete(ctx context.Context, tenantID string, id uuid.UUID) error {
+	args := m.Called(ctx, tenantID, id)
+	return args.Error(0)
+}
+
+func (m *MockDestinationRepository) GetSettings(ctx context.Context, tenantID string, userID string) (*domain.DestinationSettings, error) {
+	args := m.Called(ctx, tenantID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.DestinationSettings), args.Error(1)
+}
+
+func (m *MockDestinationRepository) SetSettings(ctx context.Context, tenantID string, settings *domain.DestinationSettings) error {
+	args := m.Called(ctx, tenantID, settings)
+	return args.Error(0)
+}
+
+func TestDestinationService_AddStartsCooldown(t *testing.T) {
+	repo := new(MockDestinationRepository)
+	destinations := NewDestinationService(repo, nil, time.Hour).(*destinationService)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	destinations.now = func() time.Time { return now }
+
+	repo.On("Create", mock.Anything, domain.DefaultTenant, mock.Anything).Return(nil)
+
+	d, err := destinations.AddDestination(tenantCtx, &domain.DestinationReq{
+		UserID: "user-1", Currency: "USDT", Network: "tron", Address: "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Hour), d.UsableAt)
+	assert.False(t, d.Usable(now))
+
+	// An end user cannot fill someone else's address book
+	ctx := domain.WithPrincipal(tenantCtx, &domain.Principal{Subject: "user-2", Kind: domain.PrincipalUser})
+	_, err = destinations.AddDestination(ctx, &domain.DestinationReq{UserID: "user-1", Currency: "USDT", Address: "x"})
+	assert.Equal(t, domain.ErrForbidden, err)
+	repo.AssertNumberOfCalls(t, "Create", 1)
+}
+
+func TestDestinationService_CheckInAllowListMode(t *testing.T) {
+	repo := new(MockDestinationRepository)
+	destinations := NewDestinationService(repo, nil, time.Hour).(*destinationService)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	destinations.now = func() time.Time { return now }
+	ctx := tenantCtx
+
+	repo.On("GetSettings", mock.Anything, domain.DefaultTenant, "user-1").
+		Return(&domain.DestinationSettings{UserID: "user-1", AllowListOnly: true}, nil)
+	repo.On("Find", mock.Anything, domain.DefaultTenant, "user-1", "USDT", "tron", "unknown").Return(nil, nil)
+	repo.On("Find", mock.Anything, domain.DefaultTenant, "user-1", "USDT", "tron", "cooling").
+		Return(&domain.Destination{Address: "cooling", UsableAt: now.Add(time.Minute)}, nil)
+	repo.On("Find", mock.Anything, domain.DefaultTenant, "user-1", "USDT", "tron", "approved").
+		Return(&domain.Destination{Address: "approved", UsableAt: now.Add(-time.Minute)}, nil)
+
+	var allowErr *domain.DestinationNotAllowedError
+	err := destinations.Check(ctx, domain.DefaultTenant, "user-1", "USDT", "tron", "unknown")
+	require.ErrorAs(t, err, &allowErr)
+	assert.Nil(t, allowErr.UsableAt)
+
+	err = destinations.Check(ctx, domain.DefaultTenant, "user-1", "USDT", "tron", "cooling")
+	require.ErrorAs(t, err, &allowErr)
+	require.NotNil(t, allowErr.UsableAt)
+	assert.Equal(t, now.Add(time.Minute), *allowErr.UsableAt)
+	assert.ErrorIs(t, err, domain.ErrDestinationNotAllowed)
+
+	assert.NoError(t, destinations.Check(ctx, domain.DefaultTenant, "user-1", "USDT", "tron", "approved"))
+}
+
+func TestDestinationService_CheckWithoutAllowList(t *testing.T) {
+	repo := new(MockDestinationRepository)
+	destinations := NewDestinationService(repo, nil, time.Hour)
+
+	repo.On("GetSettings", mock.Anything, domain.DefaultTenant, "user-1").
+		Return(&domain.DestinationSettings{UserID: "user-1"}, nil)
+
+	assert.NoError(t, destinations.Check(tenantCtx, domain.DefaultTenant, "user-1", "USDT", "tron", "anything"))
+	repo.AssertNotCalled(t, "Find", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDestinationService_DisablingAllowListWaitsForCooldown(t *testing.T) {
+	repo := new(MockDestinationRepository)
+	destinations := NewDestinationService(repo, nil, time.Hour).(*destinationService)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	destinations.now = func() time.Time { return now }
+	ctx := tenantCtx
+
+	repo.On("GetSettings", mock.Anything, domain.DefaultTenant, "user-1").
+		Return(&domain.DestinationSettings{UserID: "user-1", AllowListOnly: true}, nil)
+	repo.On("SetSettings", mock.Anything, domain.DefaultTenant, mock.Anything).Return(nil)
+
+	settings, err := destinations.SetAllowListOnly(ctx, "user-1", false)
+	require.NoError(t, err)
+	assert.False(t, settings.AllowListOnly)
+	require.NotNil(t, settings.EnforcedUntil)
+	assert.True(t, settings.Enforced(now.Add(59*time.Minute)))
+	assert.False(t, settings.Enforced(now.Add(time.Hour)))
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+	"log/slog"
+	"time"
+)
+
+const (
+	defaultExpiryTTL       = 24 * time.Hour
+	defaultExpiryBatchSize = 100
+)
+
+// ExpiryWorker moves withdrawals pending for longer than ttl to expired and
+// refunds the debited amount. A withdrawal held for approval is pending from
+// its approval on, so operators can approve it late without it expiring at
+// once. Each withdrawal is handled in its own WithLock transaction with a
+// conditional status update, so several replicas can sweep concurrently
+// without refunding twice.
+type ExpiryWorker struct {
+	withdrawalRepo port.WithdrawalRepository
+	balanceRepo    port.BalanceRepository
+	feeLedger      port.FeeLedger
+	events         port.WithdrawalEventPublisher
+	audit          port.AuditLog
+	actions        port.WithdrawalActionRepository
+	heartbeat      func()
+	ttl            time.Duration
+	approvalTTL    time.Duration
+	batchSize      int
+	logger         *slog.Logger
+}
+
+func NewExpiryWorker(
+	withdrawalRepo port.WithdrawalRepository,
+	balanceRepo port.BalanceRepository,
+	feeLedger port.FeeLedger,
+	events port.WithdrawalEventPublisher,
+	ttl time.Duration,
+	batchSize int,
+) *ExpiryWorker {
+	if ttl <= 0 {
+		ttl = defaultExpiryTTL
+	}
+	if batchSize <= 0 {
+		batchSize = defaultExpiryBatchSize
+	}
+	if events == nil {
+		events = noopPublisher{}
+	}
+	return &ExpiryWorker{
+		withdrawalRepo: withdrawalRepo,
+		balanceRepo:    balanceRepo,
+		feeLedger:      feeLedger,
+		events:         events,
+		audit:          noopAuditLog{},
+		actions:        noopActionRepository{},
+		heartbeat:      func() {},
+		ttl:            ttl,
+		batchSize:      batchSize,
+		logger:         slog.Default(),
+	}
+}
+
+func (w *ExpiryWorker) WithLogger(logger *slog.Logger) *ExpiryWorker {
+	w.logger = logger
+	return w
+}
+
+// WithAuditLog records every expiry in audit, attributed to the "expiry-worker" system principal.
+func (w *ExpiryWorker) WithAuditLog(audit port.AuditLog) *ExpiryWorker {
+	w.audit = audit
+	return w
+}
+
+// WithActionRepository records every expiry in actions, taken by the
+// "expiry-worker" system principal.
+func (w *ExpiryWorker) WithActionRepository(actions port.WithdrawalActionRepository) *ExpiryWorker {
+	w.actions = actions
+	return w
+}
+
+// WithApprovalTTL also expires withdrawals that waited for approval longer than
+// ttl, so that an undecided hold does not keep the user's funds. Zero, the
+// default, leaves them held until operators decide.
+func (w *ExpiryWorker) WithApprovalTTL(ttl time.Duration) *ExpiryWorker {
+	w.approvalTTL = ttl
+	return w
+}
+
+// WithHeartbeat calls beat after every sweep, failed or not, so that a
+// health check can tell a stalled worker from an idle one.
+func (w *ExpiryWorker) WithHeartbeat(beat func()) *ExpiryWorker {
+	w.heartbeat = beat
+	return w
+}
+
+// Run sweeps every interval until ctx is cancelled.
+func (w *ExpiryWorker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := w.Sweep(ctx); err != nil {
+			w.logger.ErrorContext(ctx, "expiry sweep failed", "error", err)
+		} else if n > 0 {
+			w.logger.InfoContext(ctx, "expired stale withdrawals", "count", n)
+		}
+		w.heartbeat()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep expires every withdrawal that was pending for longer than ttl, and
+// with an approval TTL every one awaiting approval for longer than that, tenant
+// by tenant, walking them in batches of batchSize. It returns the number of
+// expired withdrawals.
+func (w *ExpiryWorker) Sweep(ctx context.Context) (int, error) {
+	tenants, err := w.withdrawalRepo.ListTenants(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	expired := 0
+	for _, tenantID := range tenants {
+		n, err := w.sweepTenant(ctx, tenantID, domain.StatusPending, now.Add(-w.ttl))
+		expired += n
+		if err != nil {
+			return expired, err
+		}
+		if w.approvalTTL <= 0 {
+			continue
+		}
+		n, err = w.sweepTenant(ctx, tenantID, domain.StatusAwaitingApproval, now.Add(-w.approvalTTL))
+		expired += n
+		if err != nil {
+			return expired, err
+		}
+	}
+	return expired, nil
+}
+
+func (w *ExpiryWorker) sweepTenant(ctx context.Context, tenantID string, status domain.WithdrawalStatus, before time.Time) (int, error) {
+	var cursor domain.PageCursor
+	expired := 0
+
+	for {
+		batch, err := w.withdrawalRepo.ListInStatusBefore(ctx, tenantID, status, before, cursor, w.batchSize)
+		if err != nil {
+			return expired, err
+		}
+
+		for _, wd := range batch {
+			if ctx.Err() != nil {
+				return expired, ctx.Err()
+			}
+			ok, err := w.expire(ctx, wd)
+			if err != nil {
+				w.logger.ErrorContext(ctx, "failed to expire withdrawal", "tenant_id", tenantID, "withdrawal_id", wd.ID, "error", err)
+				continue
+			}
+			if ok {
+				expired++
+			}
+		}
+
+		if len(batch) < w.batchSize {
+			return expired, nil
+		}
+		last := batch[len(batch)-1]
+		cursor = domain.PageCursor{At: last.StatusSince, ID: last.ID}
+	}
+}
+
+func (w *ExpiryWorker) ttlFor(status domain.WithdrawalStatus) time.Duration {
+	if status == domain.StatusAwaitingApproval {
+		return w.approvalTTL
+	}
+	return w.ttl
+}
+
+func (w *ExpiryWorker) expire(ctx context.Context, wd *domain.Withdrawal) (bool, error) {
+	jobCtx := domain.WithPrincipal(ctx, domain.SystemPrincipal("expiry-worker", wd.TenantID))
+	err := w.balanceRepo.WithLock(jobCtx, wd.TenantID, wd.UserID, func(txCtx context.Context) error {
+		if err := w.withdrawalRepo.TransitionStatus(txCtx, wd.TenantID, wd.ID, wd.Status, domain.StatusExpired); err != nil {
+			return err
+		}
+		if err := RefundWithdrawal(txCtx, w.balanceRepo, w.feeLedger, wd.TenantID, wd); err != nil {
+			return err
+		}
+		reason := fmt.Sprintf("%s for longer than %s", wd.Status, w.ttlFor(wd.Status))
+		if err := w.actions.Record(txCtx, NewActionRecord(txCtx, wd, domain.ActionExpire, domain.StatusExpired, reason)); err != nil {
+			return err
+		}
+		return AuditTransition(txCtx, w.audit, wd, domain.StatusExpired)
+	})
+
+	switch {
+	case err == nil:
+	case errors.Is(err, domain.ErrStatusConflict), errors.Is(err, domain.ErrLockTimeout):
+		// Confirmed, expired by another replica, or the user is busy: next sweep
+		return false, nil
+	default:
+		return false, err
+	}
+
+	w.events.Publish(ctx, domain.WithdrawalEvent{
+		WithdrawalID:   wd.ID,
+		TenantID:       wd.TenantID,
+		UserID:         wd.UserID,
+		Status:         domain.StatusExpired,
+		PreviousStatus: wd.Status,
+		OccurredAt:     time.Now(),
+	})
+	return true, nil
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"idempot/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func staleWithdrawal(userID string, amount float64) *domain.Withdrawal {
+	created := time.Now().Add(-48 * time.Hour)
+	return &domain.Withdrawal{
+		ID:          uuid.New(),
+		TenantID:    domain.DefaultTenant,
+		UserID:      userID,
+		Amount:      amount,
+		Currency:    "USDT",
+		Status:      domain.StatusPending,
+		CreatedAt:   created,
+		StatusSince: created,
+	}
+}
+
+func TestExpiryWorker_ExpiresAndRefunds(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	broadcaster := NewBroadcaster(10)
+	worker := NewExpiryWorker(mockWithdrawalRepo, mockBalanceRepo, nil, broadcaster, time.Hour, 2)
+
+	first := staleWithdrawal("user-1", 100)
+	second := staleWithdrawal("user-2", 50)
+	third := staleWithdrawal("user-1", 25)
+
+	mockWithdrawalRepo.On("ListTenants", mock.Anything).Return([]string{domain.DefaultTenant}, nil)
+	mockWithdrawalRepo.On("ListInStatusBefore", mock.Anything, domain.DefaultTenant, domain.StatusPending, mock.Anything, domain.PageCursor{}, 2).
+		Return([]*domain.Withdrawal{first, second}, nil).Once()
+	mockWithdrawalRepo.On("ListInStatusBefore", mock.Anything, domain.DefaultTenant, domain.StatusPending, mock.Anything,
+		domain.PageCursor{At: second.StatusSince, ID: second.ID}, 2).
+		Return([]*domain.Withdrawal{third}, nil).Once()
+
+	for _, wd := range []*domain.Withdrawal{first, second, third} {
+		mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, wd.UserID, mock.Anything).Return(nil)
+		mockWithdrawalRepo.On("TransitionStatus", mock.Anything, domain.DefaultTenant, wd.ID, domain.StatusPending, domain.StatusExpired).Return(nil).Once()
+		mockBalanceRepo.On("UpdateBalance", mock.Anything, domain.DefaultTenant, wd.UserID, "USDT", wd.Amount).Return(nil).Once()
+	}
+
+	n, err := worker.Sweep(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	replay, _, _, cancel := broadcaster.Subscribe(domain.WithdrawalEventFilter{}, 0)
+	defer cancel()
+	assert.Len(t, replay, 3)
+
+	mockWithdrawalRepo.AssertExpectations(t)
+	mockBalanceRepo.AssertExpectations(t)
+}
+
+func TestExpiryWorker_SkipsConcurrentlyProcessed(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	worker := NewExpiryWorker(mockWithdrawalRepo, mockBalanceRepo, nil, nil, time.Hour, 10)
+
+	wd := staleWithdrawal("user-1", 100)
+
+	mockWithdrawalRepo.On("ListTenants", mock.Anything).Return([]string{domain.DefaultTenant}, nil)
+	mockWithdrawalRepo.On("ListInStatusBefore", mock.Anything, domain.DefaultTenant, domain.StatusPending, mock.Anything, domain.PageCursor{}, 10).
+		Return([]*domain.Withdrawal{wd}, nil).Once()
+	mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, wd.UserID, mock.Anything).Return(nil)
+	mockWithdrawalRepo.On("TransitionStatus", mock.Anything, domain.DefaultTenant, wd.ID, domain.StatusPending, domain.StatusExpired).
+		Return(domain.ErrStatusConflict).Once()
+
+	n, err := worker.Sweep(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// No refund for a withdrawal another replica (or a confirm) already moved
+	mockBalanceRepo.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockWithdrawalRepo.AssertExpectations(t)
+}
+
+func TestExpiryWorker_CountsPendingFromApproval(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	worker := NewExpiryWorker(mockWithdrawalRepo, mockBalanceRepo, nil, nil, 24*time.Hour, 10)
+
+	// Held for two days, approved a minute ago
+	approved := staleWithdrawal("user-1", 100)
+	approved.StatusSince = time.Now().Add(-time.Minute)
+	stale := staleWithdrawal("user-2", 50)
+
+	mockWithdrawalRepo.On("ListTenants", mock.Anything).Return([]string{domain.DefaultTenant}, nil)
+	mockWithdrawalRepo.On("ListInStatusBefore", mock.Anything, domain.DefaultTenant, domain.StatusPending,
+		mock.MatchedBy(func(before time.Time) bool {
+			// The repository compares the cutoff with status_since, as here
+			return stale.StatusSince.Before(before) && !approved.StatusSince.Before(before)
+		}), domain.PageCursor{}, 10).
+		Return([]*domain.Withdrawal{stale}, nil).Once()
+	mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, stale.UserID, mock.Anything).Return(nil)
+	mockWithdrawalRepo.On("TransitionStatus", mock.Anything, domain.DefaultTenant, stale.ID, domain.StatusPending, domain.StatusExpired).Return(nil).Once()
+	mockBalanceRepo.On("UpdateBalance", mock.Anything, domain.DefaultTenant, stale.UserID, "USDT", stale.Amount).Return(nil).Once()
+
+	n, err := worker.Sweep(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	mockWithdrawalRepo.AssertNotCalled(t, "TransitionStatus", mock.Anything, mock.Anything, approved.ID, mock.Anything, mock.Anything)
+	mockWithdrawalRepo.AssertExpectations(t)
+	mockBalanceRepo.AssertExpectations(t)
+}
+
+func TestExpiryWorker_ExpiresUndecidedHolds(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	mockActions := new(MockWithdrawalActionRepository)
+	worker := NewExpiryWorker(mockWithdrawalRepo, mockBalanceRepo, nil, nil, 24*time.Hour, 10).
+		WithApprovalTTL(72 * time.Hour).
+		WithActionRepository(mockActions)
+
+	held := staleWithdrawal("user-1", 100)
+	held.Status = domain.StatusAwaitingApproval
+	held.StatusSince = time.Now().Add(-100 * time.Hour)
+
+	mockWithdrawalRepo.On("ListTenants", mock.Anything).Return([]string{domain.DefaultTenant}, nil)
+	mockWithdrawalRepo.On("ListInStatusBefore", mock.Anything, domain.DefaultTenant, domain.StatusPending, mock.Anything, domain.PageCursor{}, 10).
+		Return([]*domain.Withdrawal{}, nil).Once()
+	mockWithdrawalRepo.On("ListInStatusBefore", mock.Anything, domain.DefaultTenant, domain.StatusAwaitingApproval,
+		mock.MatchedBy(func(before time.Time) bool { return time.Since(before) > 71*time.Hour }), domain.PageCursor{}, 10).
+		Return([]*domain.Withdrawal{held}, nil).Once()
+	mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, held.UserID, mock.Anything).Return(nil)
+	mockWithdrawalRepo.On("TransitionStatus", mock.Anything, domain.DefaultTenant, held.ID, domain.StatusAwaitingApproval, domain.StatusExpired).Return(nil).Once()
+	mockBalanceRepo.On("UpdateBalance", mock.Anything, domain.DefaultTenant, held.UserID, "USDT", held.Amount).Return(nil).Once()
+	// The worker is on record as the one who changed the status
+	mockActions.On("Record", mock.Anything, mock.MatchedBy(func(a *domain.WithdrawalActionRecord) bool {
+		return a.WithdrawalID == held.ID && a.Action == domain.ActionExpire &&
+			a.FromStatus == domain.StatusAwaitingApproval && a.ToStatus == domain.StatusExpired &&
+			a.ActorSubject == "expiry-worker" && a.ActorKind == domain.PrincipalSystem
+	})).Return(nil).Once()
+
+	n, err := worker.Sweep(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	mockWithdrawalRepo.AssertExpectations(t)
+	mockBalanceRepo.AssertExpectations(t)
+	mockActions.AssertExpectations(t)
+}
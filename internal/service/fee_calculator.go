@@ -0,0 +1,116 @@
+package service
+
+import (
+	"fmt"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+	"math"
+)
+
+type feeKey struct {
+	currency string
+	network  string
+}
+
+type feeCalculator struct {
+	schedules map[feeKey]domain.FeeSchedule
+}
+
+// NewFeeCalculator validates schedules. Currencies without a schedule are free.
+func NewFeeCalculator(schedules []domain.FeeSchedule) (port.FeeCalculator, error) {
+	byKey := make(map[feeKey]domain.FeeSchedule, len(schedules))
+	for _, s := range schedules {
+		if err := validateSchedule(s); err != nil {
+			return nil, fmt.Errorf("fee schedule %s/%s: %w", s.Currency, s.Network, err)
+		}
+		key := feeKey{s.Currency, s.Network}
+		if _, dup := byKey[key]; dup {
+			return nil, fmt.Errorf("fee schedule %s/%s: defined twice", s.Currency, s.Network)
+		}
+		byKey[key] = s
+	}
+	return &feeCalculator{schedules: byKey}, nil
+}
+
+func validateSchedule(s domain.FeeSchedule) error {
+	if s.Currency == "" {
+		return fmt.Errorf("currency is required")
+	}
+	if s.Flat < 0 || s.Percent < 0 || s.Min < 0 || s.Max < 0 {
+		return fmt.Errorf("fees cannot be negative")
+	}
+	if s.Max > 0 && s.Min > s.Max {
+		return fmt.Errorf("min exceeds max")
+	}
+
+	switch s.Type {
+	case domain.FeeFlat, domain.FeePercentage:
+	case domain.FeeTiered:
+		if len(s.Tiers) == 0 {
+			return fmt.Errorf("tiered schedule without tiers")
+		}
+		for i, t := range s.Tiers {
+			if t.Flat < 0 || t.Percent < 0 {
+				return fmt.Errorf("tier %d: fees cannot be negative", i)
+			}
+			last := i == len(s.Tiers)-1
+			if !last && (t.UpTo <= 0 || (i > 0 && t.UpTo <= s.Tiers[i-1].UpTo)) {
+				return fmt.Errorf("tier %d: up_to must be ascending and only the last tier may be open", i)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown fee type %q", s.Type)
+	}
+	return nil
+}
+
+func (c *feeCalculator) Quote(currency string, network string, amount float64) (*domain.FeeQuote, error) {
+	quote := &domain.FeeQuote{Amount: amount, Currency: currency, Network: network, NetAmount: amount}
+
+	schedule, ok := c.schedules[feeKey{currency, network}]
+	if !ok {
+		schedule, ok = c.schedules[feeKey{currency, ""}]
+	}
+	if !ok {
+		return quote, nil
+	}
+
+	var fee float64
+	switch schedule.Type {
+	case domain.FeeFlat:
+		fee = schedule.Flat
+	case domain.FeePercentage:
+		fee = amount * schedule.Percent / 100
+	case domain.FeeTiered:
+		for _, t := range schedule.Tiers {
+			if t.UpTo == 0 || amount <= t.UpTo {
+				fee = t.Flat + amount*t.Percent/100
+				break
+			}
+		}
+	}
+
+	fee = math.Max(fee, schedule.Min)
+	if schedule.Max > 0 {
+		fee = math.Min(fee, schedule.Max)
+	}
+	fee = roundAmount(fee)
+
+	if fee >= amount {
+		return nil, domain.ErrFeeExceedsAmount
+	}
+	quote.Fee = fee
+	quote.NetAmount = roundAmount(amount - fee)
+	return quote, nil
+}
+
+// roundAmount rounds to the 8 decimals of the DECIMAL(20,8) amount columns.
+func roundAmount(v float64) float64 {
+	return math.Round(v*1e8) / 1e8
+}
+
+type zeroFees struct{}
+
+func (zeroFees) Quote(currency string, network string, amount float64) (*domain.FeeQuote, error) {
+	return &domain.FeeQuote{Amount: amount, Currency: currency, Network: network, NetAmount: amount}, nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"testing"
+
+	"idempot/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeeCalculator_Quote(t *testing.T) {
+	fees, err := NewFeeCalculator([]domain.FeeSchedule{
+		{Currency: "USDT", Type: domain.FeePercentage, Percent: 0.5, Min: 1, Max: 20},
+		{Currency: "USDT", Network: "tron", Type: domain.FeeFlat, Flat: 1.5},
+		{Currency: "BTC", Type: domain.FeeTiered, Tiers: []domain.FeeTier{
+			{UpTo: 0.1, Flat: 0.0001},
+			{UpTo: 1, Flat: 0.0001, Percent: 0.1},
+			{Percent: 0.05},
+		}},
+	})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name     string
+		currency string
+		network  string
+		amount   float64
+		fee      float64
+	}{
+		{"percentage", "USDT", "", 1000, 5},
+		{"percentage clamped to min", "USDT", "", 10, 1},
+		{"percentage clamped to max", "USDT", "", 100000, 20},
+		{"network specific schedule", "USDT", "tron", 1000, 1.5},
+		{"unknown network falls back", "USDT", "ethereum", 1000, 5},
+		{"first tier", "BTC", "", 0.05, 0.0001},
+		{"middle tier", "BTC", "", 0.5, 0.0006},
+		{"open tier", "BTC", "", 10, 0.005},
+		{"no schedule is free", "EUR", "", 100, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			quote, err := fees.Quote(tc.currency, tc.network, tc.amount)
+			require.NoError(t, err)
+			assert.InDelta(t, tc.fee, quote.Fee, 1e-9)
+			assert.InDelta(t, tc.amount-tc.fee, quote.NetAmount, 1e-9)
+		})
+	}
+
+	_, err = fees.Quote("USDT", "tron", 1)
+	assert.Equal(t, domain.ErrFeeExceedsAmount, err)
+}
+
+func TestFeeCalculator_RejectsInvalidSchedules(t *testing.T) {
+	invalid := []domain.FeeSchedule{
+		{Currency: "USDT", Type: "surprise"},
+		{Currency: "USDT", Type: domain.FeeFlat, Flat: -1},
+		{Currency: "USDT", Type: domain.FeeFlat, Min: 10, Max: 5},
+		{Currency: "USDT", Type: domain.FeeTiered},
+		{Currency: "USDT", Type: domain.FeeTiered, Tiers: []domain.FeeTier{{UpTo: 100}, {UpTo: 50}, {}}},
+	}
+	for _, s := range invalid {
+		_, err := NewFeeCalculator([]domain.FeeSchedule{s})
+		assert.Error(t, err, "%+v", s)
+	}
+
+	_, err := NewFeeCalculator([]domain.FeeSchedule{
+		{Currency: "USDT", Type: domain.FeeFlat, Flat: 1},
+		{Currency: "USDT", Type: domain.FeeFlat, Flat: 2},
+	})
+	assert.Error(t, err)
+}
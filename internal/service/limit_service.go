@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+	"time"
+)
+
+type limitService struct {
+	limitRepo      port.WithdrawalLimitRepository
+	withdrawalRepo port.WithdrawalRepository
+	defaults       map[string]domain.WithdrawalLimits
+	balanceRepo    port.BalanceRepository
+	audit          port.AuditLog
+	now            func() time.Time
+}
+
+type LimitOption func(*limitService)
+
+// WithLimitAudit records every change of a user's limits in audit. The change
+// and its audit entry are written in one transaction under the user's balance
+// lock, taken from balanceRepo.
+func WithLimitAudit(balanceRepo port.BalanceRepository, audit port.AuditLog) LimitOption {
+	return func(s *limitService) {
+		s.balanceRepo = balanceRepo
+		s.audit = audit
+	}
+}
+
+// NewLimitService enforces defaults per currency, field by field replaced by
+// the non-zero caps of a user's override in limitRepo. Currencies without
+// defaults are unlimited.
+func NewLimitService(
+	limitRepo port.WithdrawalLimitRepository,
+	withdrawalRepo port.WithdrawalRepository,
+	defaults []domain.WithdrawalLimits,
+	opts ...LimitOption,
+) port.LimitService {
+	byCurrency := make(map[string]domain.WithdrawalLimits, len(defaults))
+	for _, l := range defaults {
+		byCurrency[l.Currency] = l
+	}
+	s := &limitService{
+		limitRepo:      limitRepo,
+		withdrawalRepo: withdrawalRepo,
+		defaults:       byCurrency,
+		now:            time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *limitService) GetLimits(ctx context.Context, userID string, currency string) (*domain.WithdrawalLimits, error) {
+	return s.effective(ctx, domain.TenantFromContext(ctx), userID, currency)
+}
+
+// SetUserLimits rejects an override that, merged with the defaults, would
+// leave a minimum above the maximum.
+func (s *limitService) SetUserLimits(ctx context.Context, userID string, limits *domain.WithdrawalLimits) error {
+	merged := s.merge(limits.Currency, limits)
+	if merged.MinPerTransaction > 0 && merged.MaxPerTransaction > 0 && merged.MinPerTransaction > merged.MaxPerTransaction {
+		return domain.ErrInvertedLimits
+	}
+
+	tenantID := domain.TenantFromContext(ctx)
+	if s.audit == nil {
+		return s.limitRepo.SetUserLimits(ctx, tenantID, userID, limits)
+	}
+
+	return s.balanceRepo.WithLock(ctx, tenantID, userID, func(txCtx context.Context) error {
+		// nil if the user had no override and the defaults applied
+		before, err := s.limitRepo.GetUserLimits(txCtx, tenantID, userID, limits.Currency)
+		if err != nil {
+			return err
+		}
+		if err := s.limitRepo.SetUserLimits(txCtx, tenantID, userID, limits); err != nil {
+			return err
+		}
+		return RecordAudit(txCtx, s.audit, domain.AuditLimitsChanged, domain.AuditEntityLimits, userID, userID, before, limits)
+	})
+}
+
+func (s *limitService) effective(ctx context.Context, tenantID, userID, currency string) (*domain.WithdrawalLimits, error) {
+	override, err := s.limitRepo.GetUserLimits(ctx, tenantID, userID, currency)
+	if err != nil {
+		return nil, err
+	}
+	limits := s.merge(currency, override)
+	return &limits, nil
+}
+
+// merge applies override, which may be nil, to the defaults of currency. An
+// override replaces only the caps it sets; the rest keep their defaults.
+func (s *limitService) merge(currency string, override *domain.WithdrawalLimits) domain.WithdrawalLimits {
+	limits := s.defaults[currency]
+	limits.Currency = currency
+	if override == nil {
+		return limits
+	}
+
+	if override.MinPerTransaction > 0 {
+		limits.MinPerTransaction = override.MinPerTransaction
+	}
+	if override.MaxPerTransaction > 0 {
+		limits.MaxPerTransaction = override.MaxPerTransaction
+	}
+	if override.Daily > 0 {
+		limits.Daily = override.Daily
+	}
+	if override.Monthly > 0 {
+		limits.Monthly = override.Monthly
+	}
+	return limits
+}
+
+func (s *limitService) Check(ctx context.Context, tenantID string, userID string, currency string, amount float64) error {
+	limits, err := s.effective(ctx, tenantID, userID, currency)
+	if err != nil {
+		return err
+	}
+
+	if limits.MinPerTransaction > 0 && amount < limits.MinPerTransaction {
+		return &domain.LimitExceededError{Kind: domain.LimitMinPerTransaction, Currency: currency, Limit: limits.MinPerTransaction}
+	}
+	if limits.MaxPerTransaction > 0 && amount > limits.MaxPerTransaction {
+		return &domain.LimitExceededError{Kind: domain.LimitMaxPerTransaction, Currency: currency,
+			Limit: limits.MaxPerTransaction, Remaining: limits.MaxPerTransaction}
+	}
+
+	now := s.now()
+	windows := []struct {
+		kind   domain.LimitKind
+		limit  float64
+		window time.Duration
+	}{
+		{domain.LimitDaily, limits.Daily, domain.DailyLimitWindow},
+		{domain.LimitMonthly, limits.Monthly, domain.MonthlyLimitWindow},
+	}
+	for _, w := range windows {
+		if w.limit <= 0 {
+			continue
+		}
+		used, err := s.withdrawalRepo.SumCreatedSince(ctx, tenantID, userID, currency, now.Add(-w.window))
+		if err != nil {
+			return err
+		}
+		if used+amount > w.limit {
+			remaining := w.limit - used
+			if remaining < 0 {
+				remaining = 0
+			}
+			return &domain.LimitExceededError{Kind: w.kind, Currency: currency, Limit: w.limit, Remaining: remaining}
+		}
+	}
+	return nil
+}
+
+type noopLimitChecker struct{}
+
+func (noopLimitChecker) Check(context.Context, string, string, string, float64) error { return nil }
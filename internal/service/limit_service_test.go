@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"idempot/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockWithdrawalLimitRepository struct {
+	mock.Mock
+}
+
+func (m *MockWithdrawalLimitRepository) GetUserLimits(ctx context.Context, tenantID string, userID string, currency string) (*domain.WithdrawalLimits, error) {
+	args := m.Called(ctx, tenantID, userID, currency)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.WithdrawalLimits), args.Error(1)
+}
+
+func (m *MockWithdrawalLimitRepository) SetUserLimits(ctx context.Context, tenantID string, userID string, limits *domain.WithdrawalLimits) error {
+	args := m.Called(ctx, tenantID, userID, limits)
+	return args.Error(0)
+}
+
+func (m *MockWithdrawalLimitRepository) withNoOverrides() *MockWithdrawalLimitRepository {
+	m.On("GetUserLimits", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	return m
+}
+
+func TestLimitService_PerTransaction(t *testing.T) {
+	limits := NewLimitService(new(MockWithdrawalLimitRepository).withNoOverrides(), new(MockWithdrawalRepository),
+		[]domain.WithdrawalLimits{{Currency: "USDT", MinPerTransaction: 10, MaxPerTransaction: 1000}})
+	ctx := context.Background()
+
+	var limitErr *domain.LimitExceededError
+	err := limits.Check(ctx, domain.DefaultTenant, "user-1", "USDT", 5)
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, domain.LimitMinPerTransaction, limitErr.Kind)
+
+	err = limits.Check(ctx, domain.DefaultTenant, "user-1", "USDT", 1500)
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, domain.LimitMaxPerTransaction, limitErr.Kind)
+	assert.Equal(t, 1000.0, limitErr.Remaining)
+
+	assert.NoError(t, limits.Check(ctx, domain.DefaultTenant, "user-1", "USDT", 500))
+	// Currencies without configured limits are not capped
+	assert.NoError(t, limits.Check(ctx, domain.DefaultTenant, "user-1", "BTC", 1e6))
+}
+
+func TestLimitService_RollingWindows(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	limits := NewLimitService(new(MockWithdrawalLimitRepository).withNoOverrides(), mockWithdrawalRepo,
+		[]domain.WithdrawalLimits{{Currency: "USDT", Daily: 1000, Monthly: 5000}}).(*limitService)
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	limits.now = func() time.Time { return now }
+
+	mockWithdrawalRepo.On("SumCreatedSince", mock.Anything, domain.DefaultTenant, "user-1", "USDT", now.Add(-domain.DailyLimitWindow)).Return(200.0, nil)
+	mockWithdrawalRepo.On("SumCreatedSince", mock.Anything, domain.DefaultTenant, "user-1", "USDT", now.Add(-domain.MonthlyLimitWindow)).Return(4700.0, nil)
+
+	// 200 + 400 fits the day, but 4700 + 400 breaks the month
+	err := limits.Check(context.Background(), domain.DefaultTenant, "user-1", "USDT", 400)
+	var limitErr *domain.LimitExceededError
+	require.ErrorAs(t, err, &limitErr)
+	assert.ErrorIs(t, err, domain.ErrLimitExceeded)
+	assert.Equal(t, domain.LimitMonthly, limitErr.Kind)
+	assert.Equal(t, 300.0, limitErr.Remaining)
+
+	assert.NoError(t, limits.Check(context.Background(), domain.DefaultTenant, "user-1", "USDT", 300))
+}
+
+func TestLimitService_UserOverride(t *testing.T) {
+	mockLimitRepo := new(MockWithdrawalLimitRepository)
+	limits := NewLimitService(mockLimitRepo, new(MockWithdrawalRepository),
+		[]domain.WithdrawalLimits{{Currency: "USDT", MaxPerTransaction: 1000}})
+
+	vip := &domain.WithdrawalLimits{Currency: "USDT", MaxPerTransaction: 50000}
+	mockLimitRepo.On("GetUserLimits", mock.Anything, domain.DefaultTenant, "vip", "USDT").Return(vip, nil)
+	mockLimitRepo.On("GetUserLimits", mock.Anything, domain.DefaultTenant, "user-1", "USDT").Return(nil, nil)
+
+	assert.NoError(t, limits.Check(context.Background(), domain.DefaultTenant, "vip", "USDT", 20000))
+	assert.ErrorIs(t, limits.Check(context.Background(), domain.DefaultTenant, "user-1", "USDT", 20000), domain.ErrLimitExceeded)
+
+	got, err := limits.GetLimits(tenantCtx, "user-1", "USDT")
+	require.NoError(t, err)
+	assert.Equal(t, 1000.0, got.MaxPerTransaction)
+}
+
+func TestLimitService_OverrideKeepsOtherDefaults(t *testing.T) {
+	mockLimitRepo := new(MockWithdrawalLimitRepository)
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	limits := NewLimitService(mockLimitRepo, mockWithdrawalRepo,
+		[]domain.WithdrawalLimits{{Currency: "USDT", MinPerTransaction: 10, MaxPerTransaction: 1000, Daily: 2000}})
+
+	// Only the per-transaction cap is raised; the minimum and the daily cap still apply
+	vip := &domain.WithdrawalLimits{Currency: "USDT", MaxPerTransaction: 50000}
+	mockLimitRepo.On("GetUserLimits", mock.Anything, domain.DefaultTenant, "vip", "USDT").Return(vip, nil)
+	mockWithdrawalRepo.On("SumCreatedSince", mock.Anything, domain.DefaultTenant, "vip", "USDT", mock.Anything).Return(0.0, nil)
+
+	var limitErr *domain.LimitExceededError
+	err := limits.Check(context.Background(), domain.DefaultTenant, "vip", "USDT", 5)
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, domain.LimitMinPerTransaction, limitErr.Kind)
+
+	err = limits.Check(context.Background(), domain.DefaultTenant, "vip", "USDT", 20000)
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, domain.LimitDaily, limitErr.Kind)
+
+	assert.NoError(t, limits.Check(context.Background(), domain.DefaultTenant, "vip", "USDT", 1500))
+
+	got, err := limits.GetLimits(tenantCtx, "vip", "USDT")
+	require.NoError(t, err)
+	assert.Equal(t, domain.WithdrawalLimits{Currency: "USDT", MinPerTransaction: 10, MaxPerTransaction: 50000, Daily: 2000}, *got)
+}
+
+func TestLimitService_RejectsOverrideInvertedByDefaults(t *testing.T) {
+	mockLimitRepo := new(MockWithdrawalLimitRepository)
+	limits := NewLimitService(mockLimitRepo, new(MockWithdrawalRepository),
+		[]domain.WithdrawalLimits{{Currency: "USDT", MinPerTransaction: 10, MaxPerTransaction: 1000}})
+
+	// A maximum under the default minimum would block every withdrawal
+	err := limits.SetUserLimits(tenantCtx, "user-1", &domain.WithdrawalLimits{Currency: "USDT", MaxPerTransaction: 5})
+	assert.ErrorIs(t, err, domain.ErrInvertedLimits)
+	mockLimitRepo.AssertNotCalled(t, "SetUserLimits", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	lowered := &domain.WithdrawalLimits{Currency: "USDT", MinPerTransaction: 1, MaxPerTransaction: 5}
+	mockLimitRepo.On("SetUserLimits", mock.Anything, domain.DefaultTenant, "user-1", lowered).Return(nil).Once()
+	assert.NoError(t, limits.SetUserLimits(tenantCtx, "user-1", lowered))
+	mockLimitRepo.AssertExpectations(t)
+}
+
+func TestLimitService_SetUserLimitsAudited(t *testing.T) {
+	mockLimitRepo := new(MockWithdrawalLimitRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	audit := &recordingAuditLog{}
+	limits := NewLimitService(mockLimitRepo, new(MockWithdrawalRepository), nil, WithLimitAudit(mockBalanceRepo, audit))
+
+	admin := &domain.Principal{Subject: "admin-1", Kind: domain.PrincipalUser, Roles: []domain.Role{domain.RoleAdmin}}
+	ctx := domain.WithRequestMeta(domain.WithPrincipal(context.Background(), admin), domain.RequestMeta{ClientIP: "198.51.100.2"})
+	previous := &domain.WithdrawalLimits{Currency: "USDT", Daily: 1000}
+	updated := &domain.WithdrawalLimits{Currency: "USDT", Daily: 5000}
+
+	mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, "user-1", mock.Anything).Return(nil)
+	mockLimitRepo.On("GetUserLimits", mock.Anything, domain.DefaultTenant, "user-1", "USDT").Return(previous, nil)
+	mockLimitRepo.On("SetUserLimits", mock.Anything, domain.DefaultTenant, "user-1", updated).Return(nil)
+
+	require.NoError(t, limits.SetUserLimits(ctx, "user-1", updated))
+
+	require.Len(t, audit.events, 1)
+	e := audit.events[0]
+	assert.Equal(t, domain.AuditLimitsChanged, e.Action)
+	assert.Equal(t, domain.AuditEntityLimits, e.EntityType)
+	assert.Equal(t, "user-1", e.EntityID)
+	assert.Equal(t, "admin-1", e.ActorSubject)
+	assert.Equal(t, "198.51.100.2", e.ClientIP)
+	assert.Contains(t, string(e.Before), `"daily":1000`)
+	assert.Contains(t, string(e.After), `"daily":5000`)
+	mockBalanceRepo.AssertExpectations(t)
+	mockLimitRepo.AssertExpectations(t)
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"idempot/internal/domain"
+	"idempot/internal/port"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var errNoFeeLedger = errors.New("withdrawal carries a fee but no fee ledger is configured")
+
+// RefundWithdrawal gives the user back the full debited amount and books the
+// fee back out of the fee ledger. It must run inside the user's WithLock
+// transaction, together with the status change that justifies the refund.
+func RefundWithdrawal(ctx context.Context, balanceRepo port.BalanceRepository, fees port.FeeLedger, tenantID string, w *domain.Withdrawal) error {
+	if err := balanceRepo.UpdateBalance(ctx, tenantID, w.UserID, w.Currency, w.Amount); err != nil {
+		return err
+	}
+	return recordFee(ctx, fees, tenantID, w, domain.FeeRefunded)
+}
+
+// recordFee appends w's fee to the ledger, negated for a refund. Fee-free
+// withdrawals leave no entry.
+func recordFee(ctx context.Context, fees port.FeeLedger, tenantID string, w *domain.Withdrawal, kind domain.FeeEntryKind) error {
+	if w.Fee <= 0 {
+		return nil
+	}
+	if fees == nil {
+		return errNoFeeLedger
+	}
+	amount := w.Fee
+	if kind == domain.FeeRefunded {
+		amount = -w.Fee
+	}
+	return fees.Record(ctx, &domain.FeeEntry{
+		ID:           uuid.New(),
+		TenantID:     tenantID,
+		WithdrawalID: w.ID,
+		Currency:     w.Currency,
+		Amount:       amount,
+		Kind:         kind,
+		CreatedAt:    time.Now(),
+	})
+}
@@ -0,0 +1,16 @@
+package service
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "idempot/internal/service"
+
+// startSpan opens the span of a WithdrawalService method; end it with tracing.End.
+func startSpan(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "WithdrawalService."+method, trace.WithAttributes(attrs...))
+}
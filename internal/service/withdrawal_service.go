@@ -2,102 +2,468 @@ package service
 
 import (
 	"context"
+	"errors"
 	"idempot/internal/domain"
 	"idempot/internal/port"
+	"idempot/internal/tracing"
+	"log/slog"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type withdrawalService struct {
 	withdrawalRepo port.WithdrawalRepository
 	balanceRepo    port.BalanceRepository
+	events         port.WithdrawalEventPublisher
+	actions        port.WithdrawalActionRepository
+	limits         port.LimitChecker
+	fees           port.FeeCalculator
+	feeLedger      port.FeeLedger
+	addresses      port.AddressValidator
+	destinations   port.DestinationChecker
+	approvalPolicy domain.ApprovalPolicy
+	approvals      port.ApprovalRepository
+	risk           port.RiskEvaluator
+	screener       port.Screener
+	screeningHits  port.ScreeningHitRepository
+	riskDenials    port.RiskDenialRepository
+	metrics        port.WithdrawalMetrics
+	audit          port.AuditLog
+	logger         *slog.Logger
+}
+
+type Option func(*withdrawalService)
+
+// WithEventPublisher makes the service publish every successful status change.
+func WithEventPublisher(publisher port.WithdrawalEventPublisher) Option {
+	return func(s *withdrawalService) {
+		s.events = publisher
+	}
+}
+
+type noopActionRepository struct{}
+
+func (noopActionRepository) Record(context.Context, *domain.WithdrawalActionRecord) error { return nil }
+
+// WithActionRepository records who confirmed, failed or cancelled each withdrawal.
+func WithActionRepository(actions port.WithdrawalActionRepository) Option {
+	return func(s *withdrawalService) {
+		s.actions = actions
+	}
+}
+
+// WithLimitChecker rejects withdrawals over the per-transaction and rolling limits.
+func WithLimitChecker(limits port.LimitChecker) Option {
+	return func(s *withdrawalService) {
+		s.limits = limits
+	}
+}
+
+// WithFeeCalculator charges fees on new withdrawals and books them to ledger;
+// without it withdrawals are free.
+func WithFeeCalculator(fees port.FeeCalculator, ledger port.FeeLedger) Option {
+	return func(s *withdrawalService) {
+		s.fees = fees
+		s.feeLedger = ledger
+	}
+}
+
+type noopAddressValidator struct{}
+
+func (noopAddressValidator) Validate(string, string, string) error { return nil }
+
+// WithAddressValidator rejects destinations malformed for the currency and network.
+func WithAddressValidator(addresses port.AddressValidator) Option {
+	return func(s *withdrawalService) {
+		s.addresses = addresses
+	}
+}
+
+// WithDestinationChecker holds users in allow-list mode to their address books.
+func WithDestinationChecker(destinations port.DestinationChecker) Option {
+	return func(s *withdrawalService) {
+		s.destinations = destinations
+	}
+}
+
+// WithApprovals holds withdrawals above the policy's thresholds for approval
+// by distinct operators, recording their decisions in approvals.
+func WithApprovals(policy domain.ApprovalPolicy, approvals port.ApprovalRepository) Option {
+	return func(s *withdrawalService) {
+		s.approvalPolicy = policy
+		s.approvals = approvals
+	}
+}
+
+type allowAllRisk struct{}
+
+func (allowAllRisk) Evaluate(context.Context, *domain.RiskInput) (*domain.RiskDecision, error) {
+	return &domain.RiskDecision{Outcome: domain.RiskAllow}, nil
+}
+
+// WithRiskEvaluator runs risk checks before the balance is debited. A hold
+// sends the withdrawal to approval, a deny rejects it and is recorded in denials.
+func WithRiskEvaluator(risk port.RiskEvaluator, denials port.RiskDenialRepository) Option {
+	return func(s *withdrawalService) {
+		s.risk = risk
+		s.riskDenials = denials
+	}
+}
+
+type noopScreener struct{}
+
+func (noopScreener) Screen(string, string) *domain.ScreeningHit { return nil }
+func (noopScreener) Versions() []domain.ScreeningListVersion { return nil }
+
+// WithScreening blocks withdrawals that match a deny-list and records every hit.
+func WithScreening(screener port.Screener, hits port.ScreeningHitRepository) Option {
+	return func(s *withdrawalService) {
+		s.screener = screener
+		s.screeningHits = hits
+	}
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) WithdrawalCreated(error) {}
+func (noopMetrics) IdempotentReplay()       {}
+
+// WithMetrics counts creations by outcome and idempotent replays.
+func WithMetrics(metrics port.WithdrawalMetrics) Option {
+	return func(s *withdrawalService) {
+		s.metrics = metrics
+	}
+}
+
+// WithLogger replaces the default logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *withdrawalService) {
+		s.logger = logger
+	}
 }
 
 func NewWithdrawalService(
 	withdrawalRepo port.WithdrawalRepository,
 	balanceRepo port.BalanceRepository,
+	opts ...Option,
 ) port.WithdrawalService {
-	return &withdrawalService{
+	s := &withdrawalService{
 		withdrawalRepo: withdrawalRepo,
 		balanceRepo:    balanceRepo,
+		events:         noopPublisher{},
+		actions:        noopActionRepository{},
+		limits:         noopLimitChecker{},
+		fees:           zeroFees{},
+		addresses:      noopAddressValidator{},
+		destinations:   noopDestinationChecker{},
+		risk:           allowAllRisk{},
+		screener:       noopScreener{},
+		metrics:        noopMetrics{},
+		audit:          noopAuditLog{},
+		logger:         slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
-func (s *withdrawalService) CreateWithdrawal(ctx context.Context, req *domain.WithdrawalReq) (*domain.Withdrawal, error) {
+func (s *withdrawalService) CreateWithdrawal(ctx context.Context, req *domain.WithdrawalReq) (_ *domain.Withdrawal, err error) {
+    ctx, span := startSpan(ctx, "CreateWithdrawal",
+        attribute.String("withdrawal.currency", req.Currency), attribute.String("withdrawal.network", req.Network))
+    defer tracing.End(span, &err)
+
+    withdrawal, replayed, err := s.createWithdrawal(ctx, req)
+    span.SetAttributes(attribute.Bool("withdrawal.replayed", replayed))
+    if replayed {
+        s.metrics.IdempotentReplay()
+    } else {
+        s.metrics.WithdrawalCreated(err)
+    }
+    return withdrawal, err
+}
+
+// createWithdrawal reports whether the withdrawal is an idempotent replay of an earlier request.
+func (s *withdrawalService) createWithdrawal(ctx context.Context, req *domain.WithdrawalReq) (*domain.Withdrawal, bool, error) {
+    if domain.IsReservedUserID(req.UserID) {
+        return nil, false, domain.ErrReservedUserID
+    }
+    if p, ok := domain.PrincipalFromContext(ctx); ok && !p.OwnsUser(req.UserID) {
+        return nil, false, domain.ErrForbidden
+    }
+    tenantID := domain.TenantFromContext(ctx)
+
     // Сначала проверяем idempotency key без транзакции для производительности
-    existing, err := s.withdrawalRepo.GetByIdempotencyKey(ctx, req.IdempotencyKey)
+    existing, err := s.withdrawalRepo.GetByIdempotencyKey(ctx, tenantID, req.IdempotencyKey)
     if err != nil {
-        return nil, err
+        return nil, false, err
     }
     
     if existing != nil {
         // Verify payload matches
-        if existing.UserID != req.UserID || existing.Amount != req.Amount || 
-           existing.Currency != req.Currency || existing.Destination != req.Destination {
-            return nil, domain.ErrIdempotencyKeyMismatch
+        if fields := req.ConflictingFields(existing); len(fields) > 0 {
+            return nil, false, &domain.IdempotencyKeyMismatchError{Fields: fields}
         }
-        return existing, nil
+        s.logger.DebugContext(ctx, "idempotent replay", "withdrawal_id", existing.ID)
+        return existing, true, nil
+    }
+
+    if err := s.addresses.Validate(req.Currency, req.Network, req.Destination); err != nil {
+        return nil, false, err
+    }
+    if err := s.destinations.Check(ctx, tenantID, req.UserID, req.Currency, req.Network, req.Destination); err != nil {
+        return nil, false, err
+    }
+    if hit := s.screener.Screen(req.UserID, req.Destination); hit != nil {
+        return nil, false, s.recordScreeningHit(ctx, tenantID, req, hit)
+    }
+
+    quote, err := s.fees.Quote(req.Currency, req.Network, req.Amount)
+    if err != nil {
+        return nil, false, err
+    }
+
+    initialStatus := domain.StatusPending
+    if s.approvalPolicy.Requires(req.Currency, req.Amount) {
+        initialStatus = domain.StatusAwaitingApproval
+    }
+    var requestedBy string
+    if p, ok := domain.PrincipalFromContext(ctx); ok {
+        requestedBy = p.Subject
     }
 
     var withdrawal *domain.Withdrawal
     
     
-    err = s.balanceRepo.WithLock(ctx, req.UserID, func(txCtx context.Context) error {
+    err = s.balanceRepo.WithLock(ctx, tenantID, req.UserID, func(txCtx context.Context) error {
         // Проверяем баланс внутри транзакции
-        balance, err := s.balanceRepo.GetBalance(txCtx, req.UserID, req.Currency)
+        balance, err := s.balanceRepo.GetBalance(txCtx, tenantID, req.UserID, req.Currency)
         if err != nil {
             return err
         }
 
         if balance.Amount < req.Amount {
-            return domain.ErrInsufficientBalance
+            return &domain.InsufficientBalanceError{
+                Currency:  req.Currency,
+                Available: balance.Amount,
+                Requested: req.Amount,
+            }
+        }
+
+        // Under the lock, so concurrent withdrawals cannot both fit the same allowance
+        if err := s.limits.Check(txCtx, tenantID, req.UserID, req.Currency, req.Amount); err != nil {
+            return err
+        }
+
+        risk, err := s.risk.Evaluate(txCtx, &domain.RiskInput{
+            TenantID:    tenantID,
+            UserID:      req.UserID,
+            Amount:      req.Amount,
+            Currency:    req.Currency,
+            Network:     req.Network,
+            Destination: req.Destination,
+            Now:         time.Now(),
+        })
+        if err != nil {
+            return err
+        }
+        status := initialStatus
+        switch risk.Outcome {
+        case domain.RiskDeny:
+            return &domain.RiskDeniedError{Rule: risk.Rule, Reason: risk.Reason}
+        case domain.RiskHold:
+            s.logger.InfoContext(ctx, "withdrawal held by risk rule", "rule", risk.Rule, "reason", risk.Reason)
+            status = domain.StatusAwaitingApproval
         }
 
         withdrawal = &domain.Withdrawal{
             ID:             uuid.New(),
+            TenantID:       tenantID,
             UserID:         req.UserID,
             Amount:         req.Amount,
+            Fee:            quote.Fee,
+            NetAmount:      quote.NetAmount,
             Currency:       req.Currency,
+            Network:        req.Network,
             Destination:    req.Destination,
             IdempotencyKey: req.IdempotencyKey,
-            Status:         domain.StatusPending,
+            Status:         status,
+            RequestedBy:    requestedBy,
+            RiskDecision:   risk.Outcome,
+            RiskRule:       risk.Rule,
+            RiskReason:     risk.Reason,
             CreatedAt:      time.Now(),
             UpdatedAt:      time.Now(),
         }
 
-        if err := s.withdrawalRepo.Create(txCtx, withdrawal); err != nil {
+        if err := s.withdrawalRepo.Create(txCtx, tenantID, withdrawal); err != nil {
             return err
         }
 
-        if err := s.balanceRepo.UpdateBalance(txCtx, req.UserID, req.Currency, -req.Amount); err != nil {
+        // The user pays the full amount; the fee part goes to the fee ledger
+        if err := s.balanceRepo.UpdateBalance(txCtx, tenantID, req.UserID, req.Currency, -req.Amount); err != nil {
+            return err
+        }
+        if err := recordFee(txCtx, s.feeLedger, tenantID, withdrawal, domain.FeeCharged); err != nil {
             return err
         }
 
-        return nil
+        return RecordAudit(txCtx, s.audit, domain.AuditWithdrawalCreated, domain.AuditEntityWithdrawal, withdrawal.ID.String(), withdrawal.UserID, nil, withdrawal)
     })
 
+    var denied *domain.RiskDeniedError
+    if errors.As(err, &denied) {
+        return nil, false, s.recordRiskDenial(ctx, tenantID, req, denied)
+    }
+    if err != nil {
+        return nil, false, err
+    }
+
+    return withdrawal, false, nil
+}
+
+// recordScreeningHit stores the hit outside any transaction, so that it
+// survives the rejected withdrawal, and returns the error for the caller.
+func (s *withdrawalService) recordScreeningHit(ctx context.Context, tenantID string, req *domain.WithdrawalReq, hit *domain.ScreeningHit) error {
+    hit.ID = uuid.New()
+    hit.TenantID = tenantID
+    hit.UserID = req.UserID
+    hit.IdempotencyKey = req.IdempotencyKey
+    hit.Currency = req.Currency
+    hit.Network = req.Network
+    hit.Destination = req.Destination
+    hit.CreatedAt = time.Now()
+    if err := s.screeningHits.Record(ctx, hit); err != nil {
+        return err
+    }
+    s.logger.WarnContext(ctx, "screening hit recorded", "hit_id", hit.ID, "list", hit.List)
+    return &domain.ScreeningHitError{Hit: hit}
+}
+
+// recordRiskDenial stores the denial after the withdrawal's transaction has
+// rolled back, so that the decision survives it, and returns the error for the caller.
+func (s *withdrawalService) recordRiskDenial(ctx context.Context, tenantID string, req *domain.WithdrawalReq, denied *domain.RiskDeniedError) error {
+    denial := &domain.RiskDenial{
+        ID:             uuid.New(),
+        TenantID:       tenantID,
+        UserID:         req.UserID,
+        IdempotencyKey: req.IdempotencyKey,
+        Amount:         req.Amount,
+        Currency:       req.Currency,
+        Network:        req.Network,
+        Destination:    req.Destination,
+        Rule:           denied.Rule,
+        Reason:         denied.Reason,
+        CreatedAt:      time.Now(),
+    }
+    if err := s.riskDenials.Record(ctx, denial); err != nil {
+        return err
+    }
+    s.logger.WarnContext(ctx, "risk denial recorded", "denial_id", denial.ID, "rule", denied.Rule)
+    return denied
+}
+
+func (s *withdrawalService) GetWithdrawal(ctx context.Context, id uuid.UUID) (_ *domain.Withdrawal, err error) {
+    ctx, span := startSpan(ctx, "GetWithdrawal", attribute.String("withdrawal.id", id.String()))
+    defer tracing.End(span, &err)
+
+    // Other tenants' withdrawals are invisible to the repository
+    withdrawal, err := s.withdrawalRepo.GetByID(ctx, domain.TenantFromContext(ctx), id)
     if err != nil {
         return nil, err
     }
 
+    // Someone else's withdrawal looks exactly like a missing one
+    if p, ok := domain.PrincipalFromContext(ctx); ok && !p.OwnsUser(withdrawal.UserID) {
+        return nil, domain.ErrWithdrawalNotFound
+    }
     return withdrawal, nil
 }
 
-func (s *withdrawalService) GetWithdrawal(ctx context.Context, id uuid.UUID) (*domain.Withdrawal, error) {
-    return s.withdrawalRepo.GetByID(ctx, id)
+func (s *withdrawalService) QuoteWithdrawal(ctx context.Context, req *domain.QuoteReq) (_ *domain.FeeQuote, err error) {
+    _, span := startSpan(ctx, "QuoteWithdrawal", attribute.String("withdrawal.currency", req.Currency))
+    defer tracing.End(span, &err)
+
+    return s.fees.Quote(req.Currency, req.Network, req.Amount)
+}
+
+func (s *withdrawalService) ConfirmWithdrawal(ctx context.Context, id uuid.UUID) (err error) {
+    ctx, span := startSpan(ctx, "ConfirmWithdrawal", attribute.String("withdrawal.id", id.String()))
+    defer tracing.End(span, &err)
+
+    return s.transition(ctx, id, domain.ActionConfirm, domain.StatusConfirmed, "", false)
+}
+
+func (s *withdrawalService) FailWithdrawal(ctx context.Context, id uuid.UUID, reason string) (err error) {
+    ctx, span := startSpan(ctx, "FailWithdrawal", attribute.String("withdrawal.id", id.String()))
+    defer tracing.End(span, &err)
+
+    return s.transition(ctx, id, domain.ActionFail, domain.StatusFailed, reason, true)
+}
+
+func (s *withdrawalService) CancelWithdrawal(ctx context.Context, id uuid.UUID, reason string) (err error) {
+    ctx, span := startSpan(ctx, "CancelWithdrawal", attribute.String("withdrawal.id", id.String()))
+    defer tracing.End(span, &err)
+
+    return s.transition(ctx, id, domain.ActionCancel, domain.StatusCancelled, reason, true)
 }
 
-func (s *withdrawalService) ConfirmWithdrawal(ctx context.Context, id uuid.UUID) error {
-    withdrawal, err := s.withdrawalRepo.GetByID(ctx, id)
+// transition moves a pending withdrawal to status, refunding the user if asked,
+// and records the acting principal in the same transaction.
+func (s *withdrawalService) transition(
+    ctx context.Context,
+    id uuid.UUID,
+    action domain.WithdrawalAction,
+    status domain.WithdrawalStatus,
+    reason string,
+    refund bool,
+) error {
+    withdrawal, err := s.GetWithdrawal(ctx, id)
     if err != nil {
         return err
     }
 
-    if withdrawal.Status != domain.StatusPending {
+    if withdrawal.Status == status {
         return nil //Already processed
     }
+    // Nothing but a cancel may skip the approval step
+    from := withdrawal.Status
+    if from != domain.StatusPending && !(action == domain.ActionCancel && from == domain.StatusAwaitingApproval) {
+        return domain.ErrStatusConflict
+    }
 
-    return s.withdrawalRepo.UpdateStatus(ctx, id, domain.StatusConfirmed)
-}
+    record := NewActionRecord(ctx, withdrawal, action, status, reason)
+
+    tenantID := domain.TenantFromContext(ctx)
+    err = s.balanceRepo.WithLock(ctx, tenantID, withdrawal.UserID, func(txCtx context.Context) error {
+        // Conditional update: the expiry worker may have refunded it in the meantime
+        if err := s.withdrawalRepo.TransitionStatus(txCtx, tenantID, id, from, status); err != nil {
+            return err
+        }
+        if refund {
+            if err := RefundWithdrawal(txCtx, s.balanceRepo, s.feeLedger, tenantID, withdrawal); err != nil {
+                return err
+            }
+        }
+        if err := s.actions.Record(txCtx, record); err != nil {
+            return err
+        }
+        return AuditTransition(txCtx, s.audit, withdrawal, status)
+    })
+    if err != nil {
+        return err
+    }
+    s.logger.DebugContext(ctx, "withdrawal status changed", "withdrawal_id", withdrawal.ID, "from", from, "to", status)
 
+    s.events.Publish(ctx, domain.WithdrawalEvent{
+        WithdrawalID:   withdrawal.ID,
+        TenantID:       tenantID,
+        UserID:         withdrawal.UserID,
+        Status:         status,
+        PreviousStatus: withdrawal.Status,
+        OccurredAt:     record.CreatedAt,
+    })
+    return nil
+}
@@ -12,35 +12,101 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tenantCtx is how requests reach the services: with a principal that names
+// the tenant. A context without one has no tenant at all.
+var tenantCtx = domain.WithPrincipal(context.Background(), domain.SystemPrincipal("test", domain.DefaultTenant))
+
 type MockWithdrawalRepository struct {
 	mock.Mock
 }
 
-func (m *MockWithdrawalRepository) Create(ctx context.Context, w *domain.Withdrawal) error {
-	args := m.Called(ctx, w)
+func (m *MockWithdrawalRepository) Create(ctx context.Context, tenantID string, w *domain.Withdrawal) error {
+	args := m.Called(ctx, tenantID, w)
 	return args.Error(0)
 }
 
-func (m *MockWithdrawalRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Withdrawal, error) {
-	args := m.Called(ctx, id)
+func (m *MockWithdrawalRepository) GetByID(ctx context.Context, tenantID string, id uuid.UUID) (*domain.Withdrawal, error) {
+	args := m.Called(ctx, tenantID, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.Withdrawal), args.Error(1)
 }
 
-func (m *MockWithdrawalRepository) GetByIdempotencyKey(ctx context.Context, key string) (*domain.Withdrawal, error) {
-	args := m.Called(ctx, key)
+func (m *MockWithdrawalRepository) GetByIdempotencyKey(ctx context.Context, tenantID string, key string) (*domain.Withdrawal, error) {
+	args := m.Called(ctx, tenantID, key)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*domain.Withdrawal), args.Error(1)
 }
 
-func (m *MockWithdrawalRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status domain.WithdrawalStatus) error {
-	args := m.Called(ctx, id, status)
+func (m *MockWithdrawalRepository) UpdateStatus(ctx context.Context, tenantID string, id uuid.UUID, status domain.WithdrawalStatus) error {
+	args := m.Called(ctx, tenantID, id, status)
+	return args.Error(0)
+}
+
+func (m *MockWithdrawalRepository) TransitionStatus(ctx context.Context, tenantID string, id uuid.UUID, from, status domain.WithdrawalStatus) error {
+	args := m.Called(ctx, tenantID, id, from, status)
+	return args.Error(0)
+}
+
+func (m *MockWithdrawalRepository) ListInStatusBefore(ctx context.Context, tenantID string, status domain.WithdrawalStatus, before time.Time, after domain.PageCursor, limit int) ([]*domain.Withdrawal, error) {
+	args := m.Called(ctx, tenantID, status, before, after, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Withdrawal), args.Error(1)
+}
+
+func (m *MockWithdrawalRepository) ListCreatedBetween(ctx context.Context, tenantID string, from, to time.Time) ([]*domain.Withdrawal, error) {
+	args := m.Called(ctx, tenantID, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.Withdrawal), args.Error(1)
+}
+
+func (m *MockWithdrawalRepository) GetByProviderReference(ctx context.Context, tenantID string, reference string) (*domain.Withdrawal, error) {
+	args := m.Called(ctx, tenantID, reference)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Withdrawal), args.Error(1)
+}
+
+func (m *MockWithdrawalRepository) SumCreatedSince(ctx context.Context, tenantID string, userID string, currency string, since time.Time) (float64, error) {
+	args := m.Called(ctx, tenantID, userID, currency, since)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *MockWithdrawalRepository) ListTenants(ctx context.Context) ([]string, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockWithdrawalRepository) CountPendingBeforeAllTenants(ctx context.Context, before time.Time) (int, error) {
+	args := m.Called(ctx, before)
+	return args.Int(0), args.Error(1)
+}
+
+type MockWithdrawalActionRepository struct {
+	mock.Mock
+}
+
+func (m *MockWithdrawalActionRepository) Record(ctx context.Context, action *domain.WithdrawalActionRecord) error {
+	args := m.Called(ctx, action)
 	return args.Error(0)
 }
 
@@ -48,18 +114,18 @@ type MockBalanceRepository struct {
 	mock.Mock
 }
 
-func (m *MockBalanceRepository) GetBalance(ctx context.Context, userID string, currency string) (*domain.Balance, error) {
-	args := m.Called(ctx, userID, currency)
+func (m *MockBalanceRepository) GetBalance(ctx context.Context, tenantID string, userID string, currency string) (*domain.Balance, error) {
+	args := m.Called(ctx, tenantID, userID, currency)
 	return args.Get(0).(*domain.Balance), args.Error(1)
 }
 
-func (m *MockBalanceRepository) UpdateBalance(ctx context.Context, userID string, currency string, amount float64) error {
-	args := m.Called(ctx, userID, currency, amount)
+func (m *MockBalanceRepository) UpdateBalance(ctx context.Context, tenantID string, userID string, currency string, amount float64) error {
+	args := m.Called(ctx, tenantID, userID, currency, amount)
 	return args.Error(0)
 }
 
-func (m *MockBalanceRepository) WithLock(ctx context.Context, userID string, fn func(ctx context.Context) error) error {
-	_ = m.Called(ctx, userID, fn)
+func (m *MockBalanceRepository) WithLock(ctx context.Context, tenantID string, userID string, fn func(ctx context.Context) error) error {
+	_ = m.Called(ctx, tenantID, userID, fn)
 	return fn(ctx)
 }
 
@@ -77,17 +143,17 @@ func TestCreateWithdrawal_Success(t *testing.T) {
 		IdempotencyKey: "key-123",
 	}
 
-	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, req.IdempotencyKey).Return(nil, nil)
+	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, domain.DefaultTenant, req.IdempotencyKey).Return(nil, nil)
 
-	mockBalanceRepo.On("WithLock", mock.Anything, req.UserID, mock.Anything).Return(nil)
-	mockBalanceRepo.On("GetBalance", mock.Anything, req.UserID, req.Currency).Return(&domain.Balance{
+	mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, req.UserID, mock.Anything).Return(nil)
+	mockBalanceRepo.On("GetBalance", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency).Return(&domain.Balance{
 		UserID: req.UserID, Amount: 500.0, Currency: req.Currency,
 	}, nil)
 
-	mockWithdrawalRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Withdrawal")).Return(nil)
-	mockBalanceRepo.On("UpdateBalance", mock.Anything, req.UserID, req.Currency, -req.Amount).Return(nil)
+	mockWithdrawalRepo.On("Create", mock.Anything, domain.DefaultTenant, mock.AnythingOfType("*domain.Withdrawal")).Return(nil)
+	mockBalanceRepo.On("UpdateBalance", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency, -req.Amount).Return(nil)
 
-	withdrawal, err := service.CreateWithdrawal(context.Background(), req)
+	withdrawal, err := service.CreateWithdrawal(tenantCtx, req)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, withdrawal)
@@ -113,17 +179,21 @@ func TestCreateWithdrawal_InsufficientBalance(t *testing.T) {
 		IdempotencyKey: "key-123",
 	}
 
-	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, req.IdempotencyKey).Return(nil, nil)
+	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, domain.DefaultTenant, req.IdempotencyKey).Return(nil, nil)
 
-	mockBalanceRepo.On("WithLock", mock.Anything, req.UserID, mock.Anything).Return(nil)
-	mockBalanceRepo.On("GetBalance", mock.Anything, req.UserID, req.Currency).Return(&domain.Balance{
+	mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, req.UserID, mock.Anything).Return(nil)
+	mockBalanceRepo.On("GetBalance", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency).Return(&domain.Balance{
 		UserID: req.UserID, Amount: 500.0, Currency: req.Currency,
 	}, nil)
 
-	withdrawal, err := service.CreateWithdrawal(context.Background(), req)
+	withdrawal, err := service.CreateWithdrawal(tenantCtx, req)
 
-	assert.Error(t, err)
-	assert.Equal(t, domain.ErrInsufficientBalance, err)
+	assert.ErrorIs(t, err, domain.ErrInsufficientBalance)
+	var balanceErr *domain.InsufficientBalanceError
+	if assert.ErrorAs(t, err, &balanceErr) {
+		assert.Equal(t, 500.0, balanceErr.Available)
+		assert.Equal(t, req.Amount, balanceErr.Requested)
+	}
 	assert.Nil(t, withdrawal)
 
 	mockWithdrawalRepo.AssertExpectations(t)
@@ -157,24 +227,24 @@ func TestCreateWithdrawal_Idempotency(t *testing.T) {
 	}
 
 	// Первый вызов - создаем
-	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, req.IdempotencyKey).Return(nil, nil).Once()
-	mockBalanceRepo.On("WithLock", mock.Anything, req.UserID, mock.Anything).Return(nil).Once()
-	mockBalanceRepo.On("GetBalance", mock.Anything, req.UserID, req.Currency).Return(&domain.Balance{
+	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, domain.DefaultTenant, req.IdempotencyKey).Return(nil, nil).Once()
+	mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, req.UserID, mock.Anything).Return(nil).Once()
+	mockBalanceRepo.On("GetBalance", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency).Return(&domain.Balance{
 		UserID: req.UserID, Amount: 500.0, Currency: req.Currency,
 	}, nil).Once()
-	mockWithdrawalRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Withdrawal")).Return(nil).Once()
-	mockBalanceRepo.On("UpdateBalance", mock.Anything, req.UserID, req.Currency, -req.Amount).Return(nil).Once()
+	mockWithdrawalRepo.On("Create", mock.Anything, domain.DefaultTenant, mock.AnythingOfType("*domain.Withdrawal")).Return(nil).Once()
+	mockBalanceRepo.On("UpdateBalance", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency, -req.Amount).Return(nil).Once()
 
-	withdrawal1, err1 := service.CreateWithdrawal(context.Background(), req)
+	withdrawal1, err1 := service.CreateWithdrawal(tenantCtx, req)
 	assert.NoError(t, err1)
 	assert.NotNil(t, withdrawal1)
 	// Эмулируем поведение БД: повторный запрос по ключу вернёт уже созданный withdrawal.
 	existingWithdrawal.ID = withdrawal1.ID
 
 	// Второй вызов - возвращаем существующий
-	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, req.IdempotencyKey).Return(existingWithdrawal, nil).Once()
+	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, domain.DefaultTenant, req.IdempotencyKey).Return(existingWithdrawal, nil).Once()
 
-	withdrawal2, err2 := service.CreateWithdrawal(context.Background(), req)
+	withdrawal2, err2 := service.CreateWithdrawal(tenantCtx, req)
 	assert.NoError(t, err2)
 	assert.NotNil(t, withdrawal2)
 	assert.Equal(t, withdrawal1.ID, withdrawal2.ID)
@@ -208,13 +278,13 @@ func TestCreateWithdrawal_Concurrent(t *testing.T) {
 	for i := 0; i < numGoroutines; i++ {
 		idempotencyKey := keys[i]
 
-		mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, idempotencyKey).Return(nil, nil).Once()
-		mockBalanceRepo.On("WithLock", mock.Anything, userID, mock.Anything).Return(nil).Once()
-		mockBalanceRepo.On("GetBalance", mock.Anything, userID, "USDT").Return(&domain.Balance{
+		mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, domain.DefaultTenant, idempotencyKey).Return(nil, nil).Once()
+		mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, userID, mock.Anything).Return(nil).Once()
+		mockBalanceRepo.On("GetBalance", mock.Anything, domain.DefaultTenant, userID, "USDT").Return(&domain.Balance{
 			UserID: userID, Amount: initialBalance, Currency: "USDT",
 		}, nil).Maybe()
-		mockWithdrawalRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Withdrawal")).Return(nil).Maybe()
-		mockBalanceRepo.On("UpdateBalance", mock.Anything, userID, "USDT", -withdrawalAmount).Return(nil).Maybe()
+		mockWithdrawalRepo.On("Create", mock.Anything, domain.DefaultTenant, mock.AnythingOfType("*domain.Withdrawal")).Return(nil).Maybe()
+		mockBalanceRepo.On("UpdateBalance", mock.Anything, domain.DefaultTenant, userID, "USDT", -withdrawalAmount).Return(nil).Maybe()
 	}
 
 	// Запускаем конкурентные запросы
@@ -231,7 +301,7 @@ func TestCreateWithdrawal_Concurrent(t *testing.T) {
 				IdempotencyKey: key,
 			}
 
-			withdrawal, err := service.CreateWithdrawal(context.Background(), req)
+			withdrawal, err := service.CreateWithdrawal(tenantCtx, req)
 			if err != nil {
 				results <- err
 			} else {
@@ -252,7 +322,7 @@ func TestCreateWithdrawal_Concurrent(t *testing.T) {
 	for err := range results {
 		if err == nil {
 			successCount++
-		} else if err == domain.ErrInsufficientBalance {
+		} else if errors.Is(err, domain.ErrInsufficientBalance) {
 			failCount++
 		}
 	}
@@ -281,13 +351,13 @@ func TestCreateWithdrawal_SameIdempotencyKeyConcurrent(t *testing.T) {
 	results := make(chan error, 5)
 
 	// Первый вызов - ключа еще нет
-	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, idempotencyKey).Return(nil, nil).Once()
-	mockBalanceRepo.On("WithLock", mock.Anything, userID, mock.Anything).Return(nil).Once()
-	mockBalanceRepo.On("GetBalance", mock.Anything, userID, "USDT").Return(&domain.Balance{
+	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, domain.DefaultTenant, idempotencyKey).Return(nil, nil).Once()
+	mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, userID, mock.Anything).Return(nil).Once()
+	mockBalanceRepo.On("GetBalance", mock.Anything, domain.DefaultTenant, userID, "USDT").Return(&domain.Balance{
 		UserID: userID, Amount: 1000.0, Currency: "USDT",
 	}, nil).Once()
-	mockWithdrawalRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Withdrawal")).Return(nil).Once()
-	mockBalanceRepo.On("UpdateBalance", mock.Anything, userID, "USDT", -100.0).Return(nil).Once()
+	mockWithdrawalRepo.On("Create", mock.Anything, domain.DefaultTenant, mock.AnythingOfType("*domain.Withdrawal")).Return(nil).Once()
+	mockBalanceRepo.On("UpdateBalance", mock.Anything, domain.DefaultTenant, userID, "USDT", -100.0).Return(nil).Once()
 
 	// Остальные вызовы - ключ уже существует
 	existingWithdrawal := &domain.Withdrawal{
@@ -303,7 +373,7 @@ func TestCreateWithdrawal_SameIdempotencyKeyConcurrent(t *testing.T) {
 	}
 
 	for i := 0; i < 4; i++ {
-		mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, idempotencyKey).Return(existingWithdrawal, nil).Once()
+		mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, domain.DefaultTenant, idempotencyKey).Return(existingWithdrawal, nil).Once()
 	}
 
 	// Запускаем 5 конкурентных запросов
@@ -320,7 +390,7 @@ func TestCreateWithdrawal_SameIdempotencyKeyConcurrent(t *testing.T) {
 				IdempotencyKey: idempotencyKey,
 			}
 
-			_, err := service.CreateWithdrawal(context.Background(), req)
+			_, err := service.CreateWithdrawal(tenantCtx, req)
 			results <- err
 		}()
 	}
@@ -351,25 +421,691 @@ func TestCreateWithdrawal_TransactionAtomicity(t *testing.T) {
 		IdempotencyKey: "key-123",
 	}
 
-	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, req.IdempotencyKey).Return(nil, nil)
-	mockBalanceRepo.On("WithLock", mock.Anything, req.UserID, mock.Anything).Return(nil)
-	mockBalanceRepo.On("GetBalance", mock.Anything, req.UserID, req.Currency).Return(&domain.Balance{
+	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, domain.DefaultTenant, req.IdempotencyKey).Return(nil, nil)
+	mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, req.UserID, mock.Anything).Return(nil)
+	mockBalanceRepo.On("GetBalance", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency).Return(&domain.Balance{
 		UserID: req.UserID, Amount: 500.0, Currency: req.Currency,
 	}, nil)
-	mockWithdrawalRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Withdrawal")).Return(nil)
+	mockWithdrawalRepo.On("Create", mock.Anything, domain.DefaultTenant, mock.AnythingOfType("*domain.Withdrawal")).Return(nil)
 
 	// Ошибка при обновлении баланса
 	expectedErr := errors.New("database error")
-	mockBalanceRepo.On("UpdateBalance", mock.Anything, req.UserID, req.Currency, -req.Amount).Return(expectedErr)
+	mockBalanceRepo.On("UpdateBalance", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency, -req.Amount).Return(expectedErr)
 
-	withdrawal, err := service.CreateWithdrawal(context.Background(), req)
+	withdrawal, err := service.CreateWithdrawal(tenantCtx, req)
 
 	assert.Error(t, err)
 	assert.Equal(t, expectedErr, err)
 	assert.Nil(t, withdrawal)
 
 	// Проверяем что Create был вызван (но транзакция откатится)
-	mockWithdrawalRepo.AssertCalled(t, "Create", mock.Anything, mock.AnythingOfType("*domain.Withdrawal"))
+	mockWithdrawalRepo.AssertCalled(t, "Create", mock.Anything, domain.DefaultTenant, mock.AnythingOfType("*domain.Withdrawal"))
 	mockWithdrawalRepo.AssertExpectations(t)
 	mockBalanceRepo.AssertExpectations(t)
 }
+
+// Тест 7: Подтверждение публикует событие смены статуса
+func TestConfirmWithdrawal_PublishesEvent(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	broadcaster := NewBroadcaster(10)
+	service := NewWithdrawalService(mockWithdrawalRepo, mockBalanceRepo, WithEventPublisher(broadcaster))
+
+	withdrawal := &domain.Withdrawal{
+		ID:     uuid.New(),
+		UserID: "user-123",
+		Status: domain.StatusPending,
+	}
+
+	mockWithdrawalRepo.On("GetByID", mock.Anything, domain.DefaultTenant, withdrawal.ID).Return(withdrawal, nil)
+	mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, withdrawal.UserID, mock.Anything).Return(nil)
+	mockWithdrawalRepo.On("TransitionStatus", mock.Anything, domain.DefaultTenant, withdrawal.ID, domain.StatusPending, domain.StatusConfirmed).Return(nil)
+
+	err := service.ConfirmWithdrawal(tenantCtx, withdrawal.ID)
+	assert.NoError(t, err)
+
+	replay, _, _, cancel := broadcaster.Subscribe(domain.WithdrawalEventFilter{WithdrawalID: withdrawal.ID}, 0)
+	defer cancel()
+	assert.Len(t, replay, 1)
+	assert.Equal(t, domain.StatusConfirmed, replay[0].Status)
+	assert.Equal(t, domain.StatusPending, replay[0].PreviousStatus)
+
+	mockWithdrawalRepo.AssertExpectations(t)
+}
+
+// Тест 8: Пользователь не может создать вывод от имени другого пользователя
+func TestCreateWithdrawal_ForeignUserForbidden(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	service := NewWithdrawalService(mockWithdrawalRepo, mockBalanceRepo)
+
+	ctx := domain.WithPrincipal(tenantCtx, &domain.Principal{Subject: "user-456", Kind: domain.PrincipalUser})
+	req := &domain.WithdrawalReq{
+		UserID:         "user-123",
+		Amount:         100.0,
+		Currency:       "USDT",
+		Destination:    "0x123",
+		IdempotencyKey: "key-123",
+	}
+
+	withdrawal, err := service.CreateWithdrawal(ctx, req)
+
+	assert.Equal(t, domain.ErrForbidden, err)
+	assert.Nil(t, withdrawal)
+	mockWithdrawalRepo.AssertNotCalled(t, "GetByIdempotencyKey", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// Тест 9: Чужой withdrawal выглядит как несуществующий
+func TestGetWithdrawal_ForeignUserNotFound(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	service := NewWithdrawalService(mockWithdrawalRepo, mockBalanceRepo)
+
+	withdrawal := &domain.Withdrawal{ID: uuid.New(), UserID: "user-123", Status: domain.StatusPending}
+	mockWithdrawalRepo.On("GetByID", mock.Anything, domain.DefaultTenant, withdrawal.ID).Return(withdrawal, nil)
+
+	owner := domain.WithPrincipal(tenantCtx, &domain.Principal{Subject: "user-123", Kind: domain.PrincipalUser})
+	got, err := service.GetWithdrawal(owner, withdrawal.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, withdrawal.ID, got.ID)
+
+	stranger := domain.WithPrincipal(tenantCtx, &domain.Principal{Subject: "user-456", Kind: domain.PrincipalUser})
+	got, err = service.GetWithdrawal(stranger, withdrawal.ID)
+	assert.Equal(t, domain.ErrWithdrawalNotFound, err)
+	assert.Nil(t, got)
+}
+
+// Тест 10: Отмена возвращает средства и фиксирует, кто её выполнил
+func TestCancelWithdrawal_RefundsAndRecordsActor(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	mockActionRepo := new(MockWithdrawalActionRepository)
+	service := NewWithdrawalService(mockWithdrawalRepo, mockBalanceRepo, WithActionRepository(mockActionRepo))
+
+	withdrawal := &domain.Withdrawal{
+		ID:       uuid.New(),
+		UserID:   "user-123",
+		Amount:   100.0,
+		Currency: "USDT",
+		Status:   domain.StatusPending,
+	}
+	operator := &domain.Principal{Subject: "op-1", Kind: domain.PrincipalUser, Roles: []domain.Role{domain.RoleOperator}}
+	ctx := domain.WithPrincipal(tenantCtx, operator)
+
+	mockWithdrawalRepo.On("GetByID", mock.Anything, domain.DefaultTenant, withdrawal.ID).Return(withdrawal, nil)
+	mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, withdrawal.UserID, mock.Anything).Return(nil)
+	mockWithdrawalRepo.On("TransitionStatus", mock.Anything, domain.DefaultTenant, withdrawal.ID, domain.StatusPending, domain.StatusCancelled).Return(nil)
+	mockBalanceRepo.On("UpdateBalance", mock.Anything, domain.DefaultTenant, withdrawal.UserID, withdrawal.Currency, withdrawal.Amount).Return(nil)
+	mockActionRepo.On("Record", mock.Anything, mock.MatchedBy(func(a *domain.WithdrawalActionRecord) bool {
+		return a.Action == domain.ActionCancel && a.ActorSubject == "op-1" &&
+			a.FromStatus == domain.StatusPending && a.ToStatus == domain.StatusCancelled && a.Reason == "duplicate"
+	})).Return(nil)
+
+	err := service.CancelWithdrawal(ctx, withdrawal.ID, "duplicate")
+	assert.NoError(t, err)
+
+	mockWithdrawalRepo.AssertExpectations(t)
+	mockBalanceRepo.AssertExpectations(t)
+	mockActionRepo.AssertExpectations(t)
+}
+
+// Тест 11: Нельзя провалить уже подтверждённый withdrawal
+func TestFailWithdrawal_NotPending(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	service := NewWithdrawalService(mockWithdrawalRepo, mockBalanceRepo)
+
+	withdrawal := &domain.Withdrawal{ID: uuid.New(), UserID: "user-123", Status: domain.StatusConfirmed}
+	mockWithdrawalRepo.On("GetByID", mock.Anything, domain.DefaultTenant, withdrawal.ID).Return(withdrawal, nil)
+
+	err := service.FailWithdrawal(tenantCtx, withdrawal.ID, "")
+	assert.Equal(t, domain.ErrStatusConflict, err)
+	mockBalanceRepo.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// Тест 12: Операции выполняются только в рамках тенанта вызывающего
+func TestWithdrawal_ScopedToCallerTenant(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	broadcaster := NewBroadcaster(10)
+	service := NewWithdrawalService(mockWithdrawalRepo, mockBalanceRepo, WithEventPublisher(broadcaster))
+
+	brandB := &domain.Principal{Subject: "payouts", Kind: domain.PrincipalService, TenantID: "brand-b",
+		Scopes: []domain.Scope{domain.ScopeAdmin}}
+	ctx := domain.WithPrincipal(tenantCtx, brandB)
+
+	req := &domain.WithdrawalReq{
+		UserID:         "user-123",
+		Amount:         100.0,
+		Currency:       "USDT",
+		Destination:    "0x123",
+		IdempotencyKey: "key-123",
+	}
+
+	// The key is already used in the default tenant, which brand-b must not see
+	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, "brand-b", req.IdempotencyKey).Return(nil, nil)
+	mockBalanceRepo.On("WithLock", mock.Anything, "brand-b", req.UserID, mock.Anything).Return(nil)
+	mockBalanceRepo.On("GetBalance", mock.Anything, "brand-b", req.UserID, req.Currency).Return(&domain.Balance{
+		TenantID: "brand-b", UserID: req.UserID, Amount: 500.0, Currency: req.Currency,
+	}, nil)
+	mockWithdrawalRepo.On("Create", mock.Anything, "brand-b", mock.AnythingOfType("*domain.Withdrawal")).Return(nil)
+	mockBalanceRepo.On("UpdateBalance", mock.Anything, "brand-b", req.UserID, req.Currency, -req.Amount).Return(nil)
+
+	withdrawal, err := service.CreateWithdrawal(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, "brand-b", withdrawal.TenantID)
+
+	// A default-tenant withdrawal id is simply not found from brand-b
+	foreignID := uuid.New()
+	mockWithdrawalRepo.On("GetByID", mock.Anything, "brand-b", foreignID).Return(nil, domain.ErrWithdrawalNotFound)
+
+	err = service.ConfirmWithdrawal(ctx, foreignID)
+	assert.Equal(t, domain.ErrWithdrawalNotFound, err)
+
+	mockWithdrawalRepo.On("GetByID", mock.Anything, "brand-b", withdrawal.ID).Return(withdrawal, nil)
+	mockWithdrawalRepo.On("TransitionStatus", mock.Anything, "brand-b", withdrawal.ID, domain.StatusPending, domain.StatusConfirmed).Return(nil)
+
+	assert.NoError(t, service.ConfirmWithdrawal(ctx, withdrawal.ID))
+
+	// Subscribers of another tenant do not receive the event
+	replay, _, _, cancel := broadcaster.Subscribe(domain.WithdrawalEventFilter{TenantID: domain.DefaultTenant, UserID: req.UserID}, 0)
+	defer cancel()
+	assert.Empty(t, replay)
+
+	replay, _, _, cancelB := broadcaster.Subscribe(domain.WithdrawalEventFilter{TenantID: "brand-b", UserID: req.UserID}, 0)
+	defer cancelB()
+	assert.Len(t, replay, 1)
+
+	mockWithdrawalRepo.AssertExpectations(t)
+	mockBalanceRepo.AssertExpectations(t)
+	mockWithdrawalRepo.AssertNotCalled(t, "GetByID", mock.Anything, domain.DefaultTenant, mock.Anything)
+}
+
+// Тест 13: Превышение лимита внутри транзакции не создаёт withdrawal
+func TestCreateWithdrawal_LimitExceeded(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	limits := NewLimitService(new(MockWithdrawalLimitRepository).withNoOverrides(), mockWithdrawalRepo,
+		[]domain.WithdrawalLimits{{Currency: "USDT", Daily: 500}})
+	service := NewWithdrawalService(mockWithdrawalRepo, mockBalanceRepo, WithLimitChecker(limits))
+
+	req := &domain.WithdrawalReq{
+		UserID:         "user-123",
+		Amount:         200.0,
+		Currency:       "USDT",
+		Destination:    "0x123",
+		IdempotencyKey: "key-123",
+	}
+
+	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, domain.DefaultTenant, req.IdempotencyKey).Return(nil, nil)
+	mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, req.UserID, mock.Anything).Return(nil)
+	mockBalanceRepo.On("GetBalance", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency).Return(&domain.Balance{
+		UserID: req.UserID, Amount: 1000.0, Currency: req.Currency,
+	}, nil)
+	mockWithdrawalRepo.On("SumCreatedSince", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency, mock.Anything).Return(350.0, nil)
+
+	withdrawal, err := service.CreateWithdrawal(tenantCtx, req)
+
+	assert.Nil(t, withdrawal)
+	assert.ErrorIs(t, err, domain.ErrLimitExceeded)
+	var limitErr *domain.LimitExceededError
+	if assert.ErrorAs(t, err, &limitErr) {
+		assert.Equal(t, domain.LimitDaily, limitErr.Kind)
+		assert.Equal(t, 150.0, limitErr.Remaining)
+	}
+	mockWithdrawalRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	mockBalanceRepo.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+type MockFeeLedger struct {
+	mock.Mock
+}
+
+func (m *MockFeeLedger) Record(ctx context.Context, entry *domain.FeeEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func feeEntry(kind domain.FeeEntryKind, amount float64) any {
+	return mock.MatchedBy(func(e *domain.FeeEntry) bool {
+		return e.Kind == kind && e.Amount == amount && e.TenantID == domain.DefaultTenant && e.WithdrawalID != uuid.Nil
+	})
+}
+
+// Тест 14: Комиссия записывается в журнал комиссий и сторнируется при отмене
+func TestWithdrawalFee_BookedAndReversed(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	mockFeeLedger := new(MockFeeLedger)
+	fees, err := NewFeeCalculator([]domain.FeeSchedule{{Currency: "USDT", Type: domain.FeeFlat, Flat: 2}})
+	assert.NoError(t, err)
+	service := NewWithdrawalService(mockWithdrawalRepo, mockBalanceRepo, WithFeeCalculator(fees, mockFeeLedger))
+
+	req := &domain.WithdrawalReq{
+		UserID:         "user-123",
+		Amount:         100.0,
+		Currency:       "USDT",
+		Destination:    "0x123",
+		IdempotencyKey: "key-123",
+	}
+
+	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, domain.DefaultTenant, req.IdempotencyKey).Return(nil, nil)
+	mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, req.UserID, mock.Anything).Return(nil)
+	mockBalanceRepo.On("GetBalance", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency).Return(&domain.Balance{
+		UserID: req.UserID, Amount: 100.0, Currency: req.Currency,
+	}, nil)
+	mockWithdrawalRepo.On("Create", mock.Anything, domain.DefaultTenant, mock.MatchedBy(func(w *domain.Withdrawal) bool {
+		return w.Amount == 100 && w.Fee == 2 && w.NetAmount == 98
+	})).Return(nil)
+	mockBalanceRepo.On("UpdateBalance", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency, -100.0).Return(nil).Once()
+	mockFeeLedger.On("Record", mock.Anything, feeEntry(domain.FeeCharged, 2.0)).Return(nil).Once()
+
+	withdrawal, err := service.CreateWithdrawal(tenantCtx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, 98.0, withdrawal.NetAmount)
+
+	mockWithdrawalRepo.On("GetByID", mock.Anything, domain.DefaultTenant, withdrawal.ID).Return(withdrawal, nil)
+	mockWithdrawalRepo.On("TransitionStatus", mock.Anything, domain.DefaultTenant, withdrawal.ID, domain.StatusPending, domain.StatusCancelled).Return(nil)
+	mockBalanceRepo.On("UpdateBalance", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency, 100.0).Return(nil).Once()
+	mockFeeLedger.On("Record", mock.Anything, feeEntry(domain.FeeRefunded, -2.0)).Return(nil).Once()
+
+	assert.NoError(t, service.CancelWithdrawal(tenantCtx, withdrawal.ID, ""))
+
+	mockWithdrawalRepo.AssertExpectations(t)
+	mockBalanceRepo.AssertExpectations(t)
+	mockFeeLedger.AssertExpectations(t)
+}
+
+type rejectingAddressValidator struct{}
+
+func (rejectingAddressValidator) Validate(currency, network, destination string) error {
+	return &domain.InvalidDestinationError{Currency: currency, Network: network, Reason: "address must start with 0x"}
+}
+
+// Тест 15: Невалидный адрес отклоняется до списания баланса
+func TestCreateWithdrawal_InvalidDestination(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	service := NewWithdrawalService(mockWithdrawalRepo, mockBalanceRepo, WithAddressValidator(rejectingAddressValidator{}))
+
+	req := &domain.WithdrawalReq{
+		UserID:         "user-123",
+		Amount:         100.0,
+		Currency:       "USDT",
+		Network:        "ethereum",
+		Destination:    "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t",
+		IdempotencyKey: "key-123",
+	}
+	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, domain.DefaultTenant, req.IdempotencyKey).Return(nil, nil)
+
+	withdrawal, err := service.CreateWithdrawal(tenantCtx, req)
+	assert.Nil(t, withdrawal)
+	assert.ErrorIs(t, err, domain.ErrInvalidDestination)
+	assert.EqualError(t, err, "invalid destination for USDT on ethereum: address must start with 0x")
+	mockBalanceRepo.AssertNotCalled(t, "WithLock", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// Тест 16: В режиме allow-list адрес вне адресной книги отклоняется
+func TestCreateWithdrawal_DestinationNotAllowed(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	mockDestinationRepo := new(MockDestinationRepository)
+	destinations := NewDestinationService(mockDestinationRepo, nil, time.Hour)
+	service := NewWithdrawalService(mockWithdrawalRepo, mockBalanceRepo, WithDestinationChecker(destinations))
+
+	req := &domain.WithdrawalReq{
+		UserID:         "user-123",
+		Amount:         100.0,
+		Currency:       "USDT",
+		Destination:    "0x123",
+		IdempotencyKey: "key-123",
+	}
+	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, domain.DefaultTenant, req.IdempotencyKey).Return(nil, nil)
+	mockDestinationRepo.On("GetSettings", mock.Anything, domain.DefaultTenant, req.UserID).
+		Return(&domain.DestinationSettings{UserID: req.UserID, AllowListOnly: true}, nil)
+	mockDestinationRepo.On("Find", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency, "", req.Destination).Return(nil, nil)
+
+	withdrawal, err := service.CreateWithdrawal(tenantCtx, req)
+	assert.Nil(t, withdrawal)
+	var allowErr *domain.DestinationNotAllowedError
+	assert.ErrorAs(t, err, &allowErr)
+	assert.Equal(t, req.Destination, allowErr.Address)
+	mockBalanceRepo.AssertNotCalled(t, "WithLock", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// Тест 17: Крупный вывод ждёт одобрения, средства при этом уже списаны
+func TestCreateWithdrawal_AboveThresholdAwaitsApproval(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	service := NewWithdrawalService(mockWithdrawalRepo, mockBalanceRepo, WithApprovals(testApprovalPolicy, new(MockApprovalRepository)))
+
+	req := &domain.WithdrawalReq{
+		UserID:         "user-123",
+		Amount:         5000.0,
+		Currency:       "USDT",
+		Destination:    "0x123",
+		IdempotencyKey: "key-123",
+	}
+	ctx := domain.WithPrincipal(tenantCtx, &domain.Principal{
+		Subject: "backoffice", Kind: domain.PrincipalService, TenantID: domain.DefaultTenant,
+	})
+
+	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, domain.DefaultTenant, req.IdempotencyKey).Return(nil, nil)
+	mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, req.UserID, mock.Anything).Return(nil)
+	mockBalanceRepo.On("GetBalance", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency).Return(&domain.Balance{
+		UserID: req.UserID, Amount: 10000.0, Currency: req.Currency,
+	}, nil)
+	mockWithdrawalRepo.On("Create", mock.Anything, domain.DefaultTenant, mock.MatchedBy(func(w *domain.Withdrawal) bool {
+		return w.Status == domain.StatusAwaitingApproval && w.RequestedBy == "backoffice"
+	})).Return(nil)
+	mockBalanceRepo.On("UpdateBalance", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency, -req.Amount).Return(nil)
+
+	withdrawal, err := service.CreateWithdrawal(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.StatusAwaitingApproval, withdrawal.Status)
+	mockWithdrawalRepo.AssertExpectations(t)
+	mockBalanceRepo.AssertExpectations(t)
+}
+
+type fixedRisk domain.RiskDecision
+
+func (r fixedRisk) Evaluate(context.Context, *domain.RiskInput) (*domain.RiskDecision, error) {
+	decision := domain.RiskDecision(r)
+	return &decision, nil
+}
+
+// Тест 18: Решение риск-движка: hold отправляет на одобрение, deny не списывает баланс
+func TestCreateWithdrawal_RiskDecision(t *testing.T) {
+	req := &domain.WithdrawalReq{
+		UserID:         "user-123",
+		Amount:         100.0,
+		Currency:       "USDT",
+		Destination:    "0x123",
+		IdempotencyKey: "key-123",
+	}
+
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	service := NewWithdrawalService(mockWithdrawalRepo, mockBalanceRepo,
+		WithRiskEvaluator(fixedRisk{Outcome: domain.RiskHold, Rule: "first-payout-to-address", Reason: "first withdrawal to this destination"}, nil))
+
+	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, domain.DefaultTenant, req.IdempotencyKey).Return(nil, nil)
+	mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, req.UserID, mock.Anything).Return(nil)
+	mockBalanceRepo.On("GetBalance", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency).Return(&domain.Balance{
+		UserID: req.UserID, Amount: 1000.0, Currency: req.Currency,
+	}, nil)
+	mockWithdrawalRepo.On("Create", mock.Anything, domain.DefaultTenant, mock.MatchedBy(func(w *domain.Withdrawal) bool {
+		return w.Status == domain.StatusAwaitingApproval && w.RiskDecision == domain.RiskHold && w.RiskRule == "first-payout-to-address"
+	})).Return(nil)
+	mockBalanceRepo.On("UpdateBalance", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency, -req.Amount).Return(nil)
+
+	withdrawal, err := service.CreateWithdrawal(tenantCtx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.StatusAwaitingApproval, withdrawal.Status)
+	mockWithdrawalRepo.AssertExpectations(t)
+
+	mockWithdrawalRepo = new(MockWithdrawalRepository)
+	mockBalanceRepo = new(MockBalanceRepository)
+	mockDenials := new(MockRiskDenialRepository)
+	service = NewWithdrawalService(mockWithdrawalRepo, mockBalanceRepo,
+		WithRiskEvaluator(fixedRisk{Outcome: domain.RiskDeny, Rule: "sanctions", Reason: "destination is on the sanctions list"}, mockDenials))
+
+	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, domain.DefaultTenant, req.IdempotencyKey).Return(nil, nil)
+	mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, req.UserID, mock.Anything).Return(nil)
+	mockBalanceRepo.On("GetBalance", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency).Return(&domain.Balance{
+		UserID: req.UserID, Amount: 1000.0, Currency: req.Currency,
+	}, nil)
+	mockDenials.On("Record", mock.Anything, mock.MatchedBy(func(d *domain.RiskDenial) bool {
+		return d.TenantID == domain.DefaultTenant && d.UserID == req.UserID && d.IdempotencyKey == req.IdempotencyKey &&
+			d.Rule == "sanctions" && d.Reason == "destination is on the sanctions list"
+	})).Return(nil).Once()
+
+	withdrawal, err = service.CreateWithdrawal(tenantCtx, req)
+	assert.Nil(t, withdrawal)
+	var riskErr *domain.RiskDeniedError
+	assert.ErrorAs(t, err, &riskErr)
+	assert.Equal(t, "sanctions", riskErr.Rule)
+	mockWithdrawalRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	mockBalanceRepo.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	// The withdrawal was never created, but the decision is kept
+	mockDenials.AssertExpectations(t)
+}
+
+type MockRiskDenialRepository struct {
+	mock.Mock
+}
+
+func (m *MockRiskDenialRepository) Record(ctx context.Context, denial *domain.RiskDenial) error {
+	args := m.Called(ctx, denial)
+	return args.Error(0)
+}
+
+type MockScreeningHitRepository struct {
+	mock.Mock
+}
+
+func (m *MockScreeningHitRepository) Record(ctx context.Context, hit *domain.ScreeningHit) error {
+	args := m.Called(ctx, hit)
+	return args.Error(0)
+}
+
+type listedDestination string
+
+func (d listedDestination) Screen(_ string, destination string) *domain.ScreeningHit {
+	if destination != string(d) {
+		return nil
+	}
+	return &domain.ScreeningHit{List: "ofac", ListVersion: "v1", Entry: domain.ScreeningEntry{Type: domain.ScreeningAddress, Value: destination}}
+}
+
+func (listedDestination) Versions() []domain.ScreeningListVersion { return nil }
+
+// Тест 19: Адрес из санкционного списка блокируется, совпадение сохраняется
+func TestCreateWithdrawal_ScreeningHit(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	mockHits := new(MockScreeningHitRepository)
+	service := NewWithdrawalService(mockWithdrawalRepo, mockBalanceRepo, WithScreening(listedDestination("0xbad"), mockHits))
+
+	req := &domain.WithdrawalReq{
+		UserID:         "user-123",
+		Amount:         100.0,
+		Currency:       "USDT",
+		Destination:    "0xbad",
+		IdempotencyKey: "key-123",
+	}
+	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, domain.DefaultTenant, req.IdempotencyKey).Return(nil, nil)
+	mockHits.On("Record", mock.Anything, mock.MatchedBy(func(h *domain.ScreeningHit) bool {
+		return h.TenantID == domain.DefaultTenant && h.UserID == req.UserID && h.IdempotencyKey == req.IdempotencyKey &&
+			h.List == "ofac" && h.ListVersion == "v1" && h.Destination == req.Destination
+	})).Return(nil)
+
+	withdrawal, err := service.CreateWithdrawal(tenantCtx, req)
+	assert.Nil(t, withdrawal)
+	assert.ErrorIs(t, err, domain.ErrScreeningHit)
+	mockHits.AssertExpectations(t)
+	mockBalanceRepo.AssertNotCalled(t, "WithLock", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+type recordingMetrics struct {
+	created []error
+	replays int
+}
+
+func (m *recordingMetrics) WithdrawalCreated(err error) { m.created = append(m.created, err) }
+func (m *recordingMetrics) IdempotentReplay()           { m.replays++ }
+
+// Тест 20: Метрики различают создание, отказ и идемпотентный повтор
+func TestCreateWithdrawal_Metrics(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	metrics := &recordingMetrics{}
+	service := NewWithdrawalService(mockWithdrawalRepo, mockBalanceRepo, WithMetrics(metrics))
+
+	req := &domain.WithdrawalReq{
+		UserID:         "user-123",
+		Amount:         100.0,
+		Currency:       "USDT",
+		Destination:    "0x123",
+		IdempotencyKey: "key-123",
+	}
+
+	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, domain.DefaultTenant, req.IdempotencyKey).Return(nil, nil).Once()
+	mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, req.UserID, mock.Anything).Return(nil).Once()
+	mockBalanceRepo.On("GetBalance", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency).Return(&domain.Balance{
+		UserID: req.UserID, Amount: 500.0, Currency: req.Currency,
+	}, nil).Once()
+	mockWithdrawalRepo.On("Create", mock.Anything, domain.DefaultTenant, mock.AnythingOfType("*domain.Withdrawal")).Return(nil).Once()
+	mockBalanceRepo.On("UpdateBalance", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency, -req.Amount).Return(nil).Once()
+
+	created, err := service.CreateWithdrawal(tenantCtx, req)
+	assert.NoError(t, err)
+
+	// The same request again is a replay, not a second creation
+	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, domain.DefaultTenant, req.IdempotencyKey).Return(created, nil)
+	_, err = service.CreateWithdrawal(tenantCtx, req)
+	assert.NoError(t, err)
+
+	// A different payload under the same key is a failed creation
+	changed := *req
+	changed.Amount = 200
+	_, err = service.CreateWithdrawal(tenantCtx, &changed)
+	assert.ErrorIs(t, err, domain.ErrIdempotencyKeyMismatch)
+	var mismatchErr *domain.IdempotencyKeyMismatchError
+	if assert.ErrorAs(t, err, &mismatchErr) {
+		assert.Equal(t, []string{"amount"}, mismatchErr.Fields)
+	}
+
+	assert.Len(t, metrics.created, 2)
+	assert.NoError(t, metrics.created[0])
+	assert.ErrorIs(t, metrics.created[1], domain.ErrIdempotencyKeyMismatch)
+	assert.Equal(t, 1, metrics.replays)
+}
+
+// Тест 21: Каждый вызов сервиса оставляет спан, ошибка отмечается в нём
+func TestCreateWithdrawal_Span(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	service := NewWithdrawalService(mockWithdrawalRepo, mockBalanceRepo)
+
+	req := &domain.WithdrawalReq{
+		UserID:         "user-123",
+		Amount:         600.0,
+		Currency:       "USDT",
+		Destination:    "0x123",
+		IdempotencyKey: "key-123",
+	}
+	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, domain.DefaultTenant, req.IdempotencyKey).Return(nil, nil)
+	mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, req.UserID, mock.Anything).Return(nil)
+	mockBalanceRepo.On("GetBalance", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency).Return(&domain.Balance{
+		UserID: req.UserID, Amount: 500.0, Currency: req.Currency,
+	}, nil)
+
+	_, err := service.CreateWithdrawal(tenantCtx, req)
+	assert.ErrorIs(t, err, domain.ErrInsufficientBalance)
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "WithdrawalService.CreateWithdrawal", spans[0].Name())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.Contains(t, spans[0].Attributes(), attribute.String("withdrawal.currency", "USDT"))
+	}
+	// Repositories see the service span as their parent
+	lockCtx := mockBalanceRepo.Calls[0].Arguments.Get(0).(context.Context)
+	assert.Equal(t, spans[0].SpanContext().SpanID(), trace.SpanContextFromContext(lockCtx).SpanID())
+}
+
+type recordingAuditLog struct {
+	events []*domain.AuditEvent
+	err    error
+}
+
+func (l *recordingAuditLog) Append(_ context.Context, e *domain.AuditEvent) error {
+	if l.err != nil {
+		return l.err
+	}
+	l.events = append(l.events, e)
+	return nil
+}
+
+// Тест 22: Создание и отмена попадают в журнал аудита с автором, IP и ID запроса
+func TestWithdrawal_AuditLog(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	audit := &recordingAuditLog{}
+	service := NewWithdrawalService(mockWithdrawalRepo, mockBalanceRepo, WithAuditLog(audit))
+
+	customer := &domain.Principal{Subject: "user-123", Kind: domain.PrincipalUser, Roles: []domain.Role{domain.RoleCustomer}}
+	ctx := domain.WithPrincipal(tenantCtx, customer)
+	ctx = domain.WithRequestMeta(ctx, domain.RequestMeta{ClientIP: "203.0.113.7", RequestID: "host/abc-000001"})
+	req := &domain.WithdrawalReq{UserID: "user-123", Amount: 100.0, Currency: "USDT", Destination: "0x123", IdempotencyKey: "key-audit"}
+
+	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, domain.DefaultTenant, req.IdempotencyKey).Return(nil, nil)
+	mockBalanceRepo.On("WithLock", mock.Anything, domain.DefaultTenant, req.UserID, mock.Anything).Return(nil)
+	mockBalanceRepo.On("GetBalance", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency).Return(&domain.Balance{
+		UserID: req.UserID, Amount: 500.0, Currency: req.Currency,
+	}, nil)
+	mockWithdrawalRepo.On("Create", mock.Anything, domain.DefaultTenant, mock.AnythingOfType("*domain.Withdrawal")).Return(nil)
+	mockBalanceRepo.On("UpdateBalance", mock.Anything, domain.DefaultTenant, req.UserID, req.Currency, mock.Anything).Return(nil)
+
+	withdrawal, err := service.CreateWithdrawal(ctx, req)
+	assert.NoError(t, err)
+
+	mockWithdrawalRepo.On("GetByID", mock.Anything, domain.DefaultTenant, withdrawal.ID).Return(withdrawal, nil)
+	mockWithdrawalRepo.On("TransitionStatus", mock.Anything, domain.DefaultTenant, withdrawal.ID, domain.StatusPending, domain.StatusCancelled).Return(nil)
+	assert.NoError(t, service.CancelWithdrawal(ctx, withdrawal.ID, "changed my mind"))
+
+	if assert.Len(t, audit.events, 2) {
+		created, cancelled := audit.events[0], audit.events[1]
+		assert.Equal(t, domain.AuditWithdrawalCreated, created.Action)
+		assert.Equal(t, withdrawal.ID.String(), created.EntityID)
+		assert.JSONEq(t, "null", string(created.Before))
+		assert.Contains(t, string(created.After), `"Status":"pending"`)
+
+		assert.Equal(t, domain.AuditWithdrawalCancelled, cancelled.Action)
+		assert.Contains(t, string(cancelled.Before), `"Status":"pending"`)
+		assert.Contains(t, string(cancelled.After), `"Status":"cancelled"`)
+		for _, e := range audit.events {
+			assert.Equal(t, domain.DefaultTenant, e.TenantID)
+			assert.Equal(t, "user-123", e.ActorSubject)
+			assert.Equal(t, domain.PrincipalUser, e.ActorKind)
+			assert.Equal(t, "203.0.113.7", e.ClientIP)
+			assert.Equal(t, "host/abc-000001", e.RequestID)
+		}
+	}
+
+	// The audit entry is part of the transaction: if it cannot be written, the change fails
+	audit.err = errors.New("audit_events unavailable")
+	req.IdempotencyKey = "key-audit-2"
+	mockWithdrawalRepo.On("GetByIdempotencyKey", mock.Anything, domain.DefaultTenant, req.IdempotencyKey).Return(nil, nil)
+	_, err = service.CreateWithdrawal(ctx, req)
+	assert.ErrorIs(t, err, audit.err)
+}
+
+// Тест 23: Служебные user_id (как бывший house-счёт) нельзя использовать для вывода
+func TestCreateWithdrawal_RejectsReservedUserID(t *testing.T) {
+	mockWithdrawalRepo := new(MockWithdrawalRepository)
+	mockBalanceRepo := new(MockBalanceRepository)
+	service := NewWithdrawalService(mockWithdrawalRepo, mockBalanceRepo)
+
+	ctx := domain.WithPrincipal(tenantCtx, &domain.Principal{
+		Subject: "admin-1", Kind: domain.PrincipalUser, Roles: []domain.Role{domain.RoleAdmin}, TenantID: domain.DefaultTenant,
+	})
+	withdrawal, err := service.CreateWithdrawal(ctx, &domain.WithdrawalReq{
+		UserID:         "__house__",
+		Amount:         10.0,
+		Currency:       "USDT",
+		Destination:    "0x123",
+		IdempotencyKey: "key-house",
+	})
+
+	assert.Nil(t, withdrawal)
+	assert.ErrorIs(t, err, domain.ErrReservedUserID)
+	mockWithdrawalRepo.AssertNotCalled(t, "GetByIdempotencyKey", mock.Anything, mock.Anything, mock.Anything)
+	mockBalanceRepo.AssertNotCalled(t, "WithLock", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
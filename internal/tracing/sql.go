@@ -0,0 +1,37 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+
+	"github.com/XSAM/otelsql"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// statementTarget picks the verb and table out of a statement for the span name.
+var statementTarget = regexp.MustCompile(`(?is)^\s*(?:with\b.*?\)\s*)?(select\b.*?\bfrom|insert\s+into|update|delete\s+from)\s+([a-z_][a-z0-9_.]*)`)
+
+// OpenDB opens a database whose statements, transactions and connections
+// each get a span, named like "UPDATE balances" for statements. Only the
+// parameterized SQL is recorded, never the arguments.
+func OpenDB(driverName string, dataSourceName string) (*sql.DB, error) {
+	return otelsql.Open(driverName, dataSourceName,
+		otelsql.WithAttributes(attribute.String("db.system", driverName)),
+		otelsql.WithSpanNameFormatter(spanName),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+		}),
+	)
+}
+
+func spanName(_ context.Context, method otelsql.Method, query string) string {
+	m := statementTarget.FindStringSubmatch(query)
+	if m == nil {
+		return string(method)
+	}
+	verb := strings.ToUpper(strings.Fields(m[1])[0])
+	return verb + " " + strings.ToLower(m[2])
+}
@@ -0,0 +1,136 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/XSAM/otelsql"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans routes the global tracer provider to an in-memory recorder for the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	recorder := recordSpans(t)
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Post("/v1/withdrawals/{id}/confirm", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/withdrawals/123/confirm", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "POST /v1/withdrawals/{id}/confirm", span.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.True(t, span.Parent().IsRemote())
+	assert.Equal(t, codes.Error, span.Status().Code)
+	assert.Contains(t, span.Attributes(), attribute.Int("http.response.status_code", http.StatusInternalServerError))
+}
+
+func TestMiddleware_StartsNewTraceWithoutHeader(t *testing.T) {
+	recorder := recordSpans(t)
+
+	var inHandler bool
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, child := otel.Tracer("test").Start(r.Context(), "child")
+		child.End()
+		inHandler = true
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	require.True(t, inHandler)
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	child, server := spans[0], spans[1]
+	assert.False(t, server.Parent().IsValid())
+	assert.Equal(t, server.SpanContext().SpanID(), child.Parent().SpanID())
+}
+
+func TestEnd_RecordsError(t *testing.T) {
+	recorder := recordSpans(t)
+
+	run := func(fail bool) (err error) {
+		_, span := otel.Tracer("test").Start(context.Background(), "op")
+		defer End(span, &err)
+		if fail {
+			return errors.New("boom")
+		}
+		return nil
+	}
+	require.NoError(t, run(false))
+	require.Error(t, run(true))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "boom", spans[1].Status().Description)
+}
+
+func TestSpanName(t *testing.T) {
+	for query, want := range map[string]string{
+		"SELECT id FROM balances WHERE tenant_id = $1 AND user_id = $2 FOR UPDATE NOWAIT": "SELECT balances",
+		"\n        INSERT INTO balances (tenant_id, user_id) VALUES ($1, $2)":             "INSERT balances",
+		"UPDATE withdrawals SET status = $1 WHERE id = $2":                                "UPDATE withdrawals",
+		"DELETE FROM destinations WHERE id = $1":                                          "DELETE destinations",
+		"SELECT COALESCE(SUM(amount), 0)\nFROM withdrawals WHERE user_id = $1":            "SELECT withdrawals",
+		"": "sql.conn.exec",
+	} {
+		assert.Equal(t, want, spanName(context.Background(), otelsql.MethodConnExec, query), query)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	_, err := Setup(Options{Exporter: "jaeger"})
+	assert.Error(t, err)
+	_, err = Setup(Options{Exporter: ExporterFile})
+	assert.Error(t, err)
+
+	shutdown, err := Setup(Options{})
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	shutdown, err = Setup(Options{Exporter: ExporterFile, File: path, ServiceName: "idempot-test"})
+	require.NoError(t, err)
+	_, span := otel.Tracer("test").Start(context.Background(), "exported")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	out, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `"Name":"exported"`)
+	assert.Contains(t, string(out), "idempot-test")
+}
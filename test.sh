@@ -5,7 +5,7 @@ echo "Тестирование конкурентных запросов..."
 send_request() {
     local key=$1
     curl -s -X POST http://localhost:8080/v1/withdrawals \
-        -H "Authorization: Bearer test-token" \
+        -H "Authorization: Bearer ${API_KEY:?set API_KEY}" \
         -H "Content-Type: application/json" \
         -d "{
             \"user_id\": \"user-123\",